@@ -1,7 +1,9 @@
 package nexproto
 
 import (
+	"errors"
 	"log"
+	"time"
 
 	nex "github.com/jnackmclain/nex-go"
 )
@@ -12,43 +14,1525 @@ const (
 
 	SetStatus             = 0x11
 	NintendoCreateAccount = 0x1B
+
+	// AccountManagementMethodCreateAccount is the method ID for method CreateAccount
+	AccountManagementMethodCreateAccount = 0x1
+
+	// AccountManagementMethodDeleteAccount is the method ID for method DeleteAccount
+	AccountManagementMethodDeleteAccount = 0x2
+
+	// AccountManagementMethodDisableAccount is the method ID for method DisableAccount
+	AccountManagementMethodDisableAccount = 0x3
+
+	// AccountManagementMethodChangePassword is the method ID for method ChangePassword
+	AccountManagementMethodChangePassword = 0x4
+
+	// AccountManagementMethodTestCapability is the method ID for method TestCapability
+	AccountManagementMethodTestCapability = 0x5
+
+	// AccountManagementMethodGetName is the method ID for method GetName
+	AccountManagementMethodGetName = 0x6
+
+	// AccountManagementMethodGetAccountData is the method ID for method GetAccountData
+	AccountManagementMethodGetAccountData = 0x7
+
+	// AccountManagementMethodGetPrivateData is the method ID for method GetPrivateData
+	AccountManagementMethodGetPrivateData = 0x8
+
+	// AccountManagementMethodGetPublicData is the method ID for method GetPublicData
+	AccountManagementMethodGetPublicData = 0x9
+
+	// AccountManagementMethodUpdateAccountName is the method ID for method UpdateAccountName
+	AccountManagementMethodUpdateAccountName = 0xB
+
+	// AccountManagementMethodUpdateAccountEmail is the method ID for method UpdateAccountEmail
+	AccountManagementMethodUpdateAccountEmail = 0xC
+
+	// AccountManagementMethodUpdateCustomData is the method ID for method UpdateCustomData
+	AccountManagementMethodUpdateCustomData = 0xD
+
+	// AccountManagementMethodFindByNameRegex is the method ID for method FindByNameRegex
+	AccountManagementMethodFindByNameRegex = 0xE
+
+	// AccountManagementMethodUpdateAccountExpiryDate is the method ID for method UpdateAccountExpiryDate
+	AccountManagementMethodUpdateAccountExpiryDate = 0xF
+
+	// AccountManagementMethodUpdateAccountEffectiveDate is the method ID for method UpdateAccountEffectiveDate
+	AccountManagementMethodUpdateAccountEffectiveDate = 0x10
+
+	// AccountManagementMethodUpdateStatus is the method ID for method UpdateStatus, handled as SetStatus
+	AccountManagementMethodUpdateStatus = SetStatus
+
+	// AccountManagementMethodGetStatus is the method ID for method GetStatus
+	AccountManagementMethodGetStatus = 0x12
+
+	// AccountManagementMethodGetLastConnectionStats is the method ID for method GetLastConnectionStats
+	AccountManagementMethodGetLastConnectionStats = 0x13
+
+	// AccountManagementMethodResetPassword is the method ID for method ResetPassword
+	AccountManagementMethodResetPassword = 0x14
+
+	// AccountManagementMethodCreateAccountWithCustomData is the method ID for method CreateAccountWithCustomData
+	AccountManagementMethodCreateAccountWithCustomData = 0x15
+
+	// AccountManagementMethodRetrieveAccount is the method ID for method RetrieveAccount
+	AccountManagementMethodRetrieveAccount = 0x16
+
+	// AccountManagementMethodUpdateAccount is the method ID for method UpdateAccount
+	AccountManagementMethodUpdateAccount = 0x17
+
+	// AccountManagementMethodChangePasswordByGuest is the method ID for method ChangePasswordByGuest
+	AccountManagementMethodChangePasswordByGuest = 0x18
+
+	// AccountManagementMethodFindByNameLike is the method ID for method FindByNameLike
+	AccountManagementMethodFindByNameLike = 0x19
+
+	// AccountManagementMethodCustomCreateAccount is the method ID for method CustomCreateAccount
+	AccountManagementMethodCustomCreateAccount = 0x1A
+
+	// AccountManagementMethodNintendoCreateAccount is the method ID for method NintendoCreateAccount
+	AccountManagementMethodNintendoCreateAccount = NintendoCreateAccount
+
+	// AccountManagementMethodLookupOrCreateAccount is the method ID for method LookupOrCreateAccount
+	AccountManagementMethodLookupOrCreateAccount = 0x1C
+
+	// AccountManagementMethodDisconnectPrincipal is the method ID for method DisconnectPrincipal
+	AccountManagementMethodDisconnectPrincipal = 0x1D
+
+	// AccountManagementMethodDisconnectAllPrincipals is the method ID for method DisconnectAllPrincipals
+	AccountManagementMethodDisconnectAllPrincipals = 0x1E
 )
 
-// AccountManagementProtocol handles the Account Management nex protocol
-type AccountManagementProtocol struct {
-	server                       *nex.Server
-	NintendoCreateAccountHandler func(err error, client *nex.Client, callID uint32, username string, key string, groups uint32, email string)
-	SetStatusHandler             func(err error, client *nex.Client, callID uint32, status string)
+// AccountData is the account of a principal. An account can only be used from EffectiveDate until ExpiryDate,
+// so a ban is an EffectiveDate in the future, NotEffectiveMessage being shown to the principal until then.
+// ExpiredMessage is shown once the account has expired
+type AccountData struct {
+	PID                 uint32
+	Name                string
+	Groups              uint32
+	Email               string
+	CreationDate        *nex.DateTime
+	EffectiveDate       *nex.DateTime
+	NotEffectiveMessage string
+	ExpiryDate          *nex.DateTime
+	ExpiredMessage      string
+
+	nex.Structure
+}
+
+// Bytes encodes the AccountData and returns a byte array
+func (accountData *AccountData) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt32LE(accountData.PID)
+	write4ByteString(stream, accountData.Name)
+	stream.WriteUInt32LE(accountData.Groups)
+	write4ByteString(stream, accountData.Email)
+	stream.WriteUInt64LE(accountData.CreationDate.Value())
+	stream.WriteUInt64LE(accountData.EffectiveDate.Value())
+	write4ByteString(stream, accountData.NotEffectiveMessage)
+	stream.WriteUInt64LE(accountData.ExpiryDate.Value())
+	write4ByteString(stream, accountData.ExpiredMessage)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts an AccountData structure from a stream
+func (accountData *AccountData) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 4 {
+		return errors.New("[AccountData::ExtractFromStream] Data missing PID")
+	}
+
+	pid := stream.ReadUInt32LE()
+
+	name, err := stream.Read4ByteString()
+	if err != nil {
+		return err
+	}
+
+	if len(stream.Bytes()[stream.ByteOffset():]) < 4 {
+		return errors.New("[AccountData::ExtractFromStream] Data missing groups")
+	}
+
+	groups := stream.ReadUInt32LE()
+
+	email, err := stream.Read4ByteString()
+	if err != nil {
+		return err
+	}
+
+	if len(stream.Bytes()[stream.ByteOffset():]) < 16 {
+		// length check for the following fixed-size data
+		// creationDate + effectiveDate
+		return errors.New("[AccountData::ExtractFromStream] Data size too small")
+	}
+
+	creationDate := nex.NewDateTime(stream.ReadUInt64LE())
+	effectiveDate := nex.NewDateTime(stream.ReadUInt64LE())
+
+	notEffectiveMessage, err := stream.Read4ByteString()
+	if err != nil {
+		return err
+	}
+
+	if len(stream.Bytes()[stream.ByteOffset():]) < 8 {
+		return errors.New("[AccountData::ExtractFromStream] Data missing expiry date")
+	}
+
+	expiryDate := nex.NewDateTime(stream.ReadUInt64LE())
+
+	expiredMessage, err := stream.Read4ByteString()
+	if err != nil {
+		return err
+	}
+
+	accountData.PID = pid
+	accountData.Name = name
+	accountData.Groups = groups
+	accountData.Email = email
+	accountData.CreationDate = creationDate
+	accountData.EffectiveDate = effectiveDate
+	accountData.NotEffectiveMessage = notEffectiveMessage
+	accountData.ExpiryDate = expiryDate
+	accountData.ExpiredMessage = expiredMessage
+
+	return nil
+}
+
+// Usable returns whether the account can be used at now, returning the message to show the principal if not.
+// Zero dates do not restrict the account
+func (accountData *AccountData) Usable(now time.Time) (bool, string) {
+	if accountData.EffectiveDate.Value() != 0 && now.Before(dateTimeTime(accountData.EffectiveDate)) {
+		return false, accountData.NotEffectiveMessage
+	}
+
+	if accountData.ExpiryDate.Value() != 0 && !now.Before(dateTimeTime(accountData.ExpiryDate)) {
+		return false, accountData.ExpiredMessage
+	}
+
+	return true, ""
+}
+
+// Copy returns a copy of the AccountData
+func (accountData *AccountData) Copy() *AccountData {
+	copied := *accountData
+	copied.CreationDate = nex.NewDateTime(accountData.CreationDate.Value())
+	copied.EffectiveDate = nex.NewDateTime(accountData.EffectiveDate.Value())
+	copied.ExpiryDate = nex.NewDateTime(accountData.ExpiryDate.Value())
+
+	return &copied
+}
+
+// NewAccountData returns a new AccountData
+func NewAccountData() *AccountData {
+	return &AccountData{
+		CreationDate:  nex.NewDateTime(0),
+		EffectiveDate: nex.NewDateTime(0),
+		ExpiryDate:    nex.NewDateTime(0),
+	}
+}
+
+// BasicAccountInfo is the name of an account, as returned by FindByNameRegex and FindByNameLike
+type BasicAccountInfo struct {
+	PIDOwner uint32
+	Name     string
+
+	nex.Structure
+}
+
+// Bytes encodes the BasicAccountInfo and returns a byte array
+func (basicAccountInfo *BasicAccountInfo) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt32LE(basicAccountInfo.PIDOwner)
+	write4ByteString(stream, basicAccountInfo.Name)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a BasicAccountInfo structure from a stream
+func (basicAccountInfo *BasicAccountInfo) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 4 {
+		return errors.New("[BasicAccountInfo::ExtractFromStream] Data missing PID owner")
+	}
+
+	pidOwner := stream.ReadUInt32LE()
+
+	name, err := stream.Read4ByteString()
+	if err != nil {
+		return err
+	}
+
+	basicAccountInfo.PIDOwner = pidOwner
+	basicAccountInfo.Name = name
+
+	return nil
+}
+
+// NewBasicAccountInfo returns a new BasicAccountInfo
+func NewBasicAccountInfo() *BasicAccountInfo {
+	return &BasicAccountInfo{}
+}
+
+// AccountManagementProtocol handles the Account Management nex protocol
+type AccountManagementProtocol struct {
+	server                             *nex.Server
+	CreateAccountHandler               func(err error, client *nex.Client, callID uint32, principalName string, key string, groups uint32, email string)
+	DeleteAccountHandler               func(err error, client *nex.Client, callID uint32, principalID uint32)
+	DisableAccountHandler              func(err error, client *nex.Client, callID uint32, principalID uint32, disabledUntil *nex.DateTime, message string)
+	ChangePasswordHandler              func(err error, client *nex.Client, callID uint32, newKey string)
+	TestCapabilityHandler              func(err error, client *nex.Client, callID uint32, capability uint32)
+	GetNameHandler                     func(err error, client *nex.Client, callID uint32, principalID uint32)
+	GetAccountDataHandler              func(err error, client *nex.Client, callID uint32)
+	GetPrivateDataHandler              func(err error, client *nex.Client, callID uint32)
+	GetPublicDataHandler               func(err error, client *nex.Client, callID uint32, principalID uint32)
+	UpdateAccountNameHandler           func(err error, client *nex.Client, callID uint32, name string)
+	UpdateAccountEmailHandler          func(err error, client *nex.Client, callID uint32, email string)
+	UpdateCustomDataHandler            func(err error, client *nex.Client, callID uint32, publicData *DataHolder, privateData *DataHolder)
+	FindByNameRegexHandler             func(err error, client *nex.Client, callID uint32, groups uint32, regex string, resultRange *ResultRange)
+	UpdateAccountExpiryDateHandler     func(err error, client *nex.Client, callID uint32, principalID uint32, expiryDate *nex.DateTime, expiredMessage string)
+	UpdateAccountEffectiveDateHandler  func(err error, client *nex.Client, callID uint32, principalID uint32, effectiveFrom *nex.DateTime, notEffectiveMessage string)
+	SetStatusHandler                   func(err error, client *nex.Client, callID uint32, status string)
+	GetStatusHandler                   func(err error, client *nex.Client, callID uint32, principalID uint32)
+	GetLastConnectionStatsHandler      func(err error, client *nex.Client, callID uint32, principalID uint32)
+	ResetPasswordHandler               func(err error, client *nex.Client, callID uint32)
+	CreateAccountWithCustomDataHandler func(err error, client *nex.Client, callID uint32, principalName string, key string, groups uint32, email string, publicData *DataHolder, privateData *DataHolder)
+	RetrieveAccountHandler             func(err error, client *nex.Client, callID uint32)
+	UpdateAccountHandler               func(err error, client *nex.Client, callID uint32, key string, email string, publicData *DataHolder, privateData *DataHolder)
+	ChangePasswordByGuestHandler       func(err error, client *nex.Client, callID uint32, principalName string, key string, email string)
+	FindByNameLikeHandler              func(err error, client *nex.Client, callID uint32, groups uint32, like string, resultRange *ResultRange)
+	CustomCreateAccountHandler         func(err error, client *nex.Client, callID uint32, principalName string, key string, groups uint32, email string, authData *DataHolder)
+	NintendoCreateAccountHandler       func(err error, client *nex.Client, callID uint32, username string, key string, groups uint32, email string)
+	LookupOrCreateAccountHandler       func(err error, client *nex.Client, callID uint32, principalName string, key string, groups uint32, email string, authData *DataHolder)
+	DisconnectPrincipalHandler         func(err error, client *nex.Client, callID uint32, principalID uint32)
+	DisconnectAllPrincipalsHandler     func(err error, client *nex.Client, callID uint32)
+}
+
+// Setup initializes the protocol
+func (accountManagementProtocol *AccountManagementProtocol) Setup() {
+	nexServer := accountManagementProtocol.server
+
+	trackPacketRoutes(nexServer)
+
+	nexServer.On("Data", func(packet nex.PacketInterface) {
+		request := packet.RMCRequest()
+
+		if AccountManagementProtocolID == request.ProtocolID() {
+			rememberPacketRoute(packet)
+
+			switch request.MethodID() {
+			case AccountManagementMethodCreateAccount:
+				go accountManagementProtocol.handleCreateAccount(packet)
+			case AccountManagementMethodDeleteAccount:
+				go accountManagementProtocol.handleDeleteAccount(packet)
+			case AccountManagementMethodDisableAccount:
+				go accountManagementProtocol.handleDisableAccount(packet)
+			case AccountManagementMethodChangePassword:
+				go accountManagementProtocol.handleChangePassword(packet)
+			case AccountManagementMethodTestCapability:
+				go accountManagementProtocol.handleTestCapability(packet)
+			case AccountManagementMethodGetName:
+				go accountManagementProtocol.handleGetName(packet)
+			case AccountManagementMethodGetAccountData:
+				go accountManagementProtocol.handleGetAccountData(packet)
+			case AccountManagementMethodGetPrivateData:
+				go accountManagementProtocol.handleGetPrivateData(packet)
+			case AccountManagementMethodGetPublicData:
+				go accountManagementProtocol.handleGetPublicData(packet)
+			case AccountManagementMethodUpdateAccountName:
+				go accountManagementProtocol.handleUpdateAccountName(packet)
+			case AccountManagementMethodUpdateAccountEmail:
+				go accountManagementProtocol.handleUpdateAccountEmail(packet)
+			case AccountManagementMethodUpdateCustomData:
+				go accountManagementProtocol.handleUpdateCustomData(packet)
+			case AccountManagementMethodFindByNameRegex:
+				go accountManagementProtocol.handleFindByNameRegex(packet)
+			case AccountManagementMethodUpdateAccountExpiryDate:
+				go accountManagementProtocol.handleUpdateAccountExpiryDate(packet)
+			case AccountManagementMethodUpdateAccountEffectiveDate:
+				go accountManagementProtocol.handleUpdateAccountEffectiveDate(packet)
+			case SetStatus:
+				go accountManagementProtocol.handleSetStatus(packet)
+			case AccountManagementMethodGetStatus:
+				go accountManagementProtocol.handleGetStatus(packet)
+			case AccountManagementMethodGetLastConnectionStats:
+				go accountManagementProtocol.handleGetLastConnectionStats(packet)
+			case AccountManagementMethodResetPassword:
+				go accountManagementProtocol.handleResetPassword(packet)
+			case AccountManagementMethodCreateAccountWithCustomData:
+				go accountManagementProtocol.handleCreateAccountWithCustomData(packet)
+			case AccountManagementMethodRetrieveAccount:
+				go accountManagementProtocol.handleRetrieveAccount(packet)
+			case AccountManagementMethodUpdateAccount:
+				go accountManagementProtocol.handleUpdateAccount(packet)
+			case AccountManagementMethodChangePasswordByGuest:
+				go accountManagementProtocol.handleChangePasswordByGuest(packet)
+			case AccountManagementMethodFindByNameLike:
+				go accountManagementProtocol.handleFindByNameLike(packet)
+			case AccountManagementMethodCustomCreateAccount:
+				go accountManagementProtocol.handleCustomCreateAccount(packet)
+			case NintendoCreateAccount:
+				go accountManagementProtocol.handleNintendoCreateAccount(packet)
+			case AccountManagementMethodLookupOrCreateAccount:
+				go accountManagementProtocol.handleLookupOrCreateAccount(packet)
+			case AccountManagementMethodDisconnectPrincipal:
+				go accountManagementProtocol.handleDisconnectPrincipal(packet)
+			case AccountManagementMethodDisconnectAllPrincipals:
+				go accountManagementProtocol.handleDisconnectAllPrincipals(packet)
+			default:
+				log.Printf("Unsupported AccountManagement method ID: %#v\n", request.MethodID())
+			}
+		}
+	})
+}
+
+// CreateAccount sets the CreateAccount handler function
+func (accountManagementProtocol *AccountManagementProtocol) CreateAccount(handler func(err error, client *nex.Client, callID uint32, principalName string, key string, groups uint32, email string)) {
+	accountManagementProtocol.CreateAccountHandler = handler
+}
+
+// DeleteAccount sets the DeleteAccount handler function
+func (accountManagementProtocol *AccountManagementProtocol) DeleteAccount(handler func(err error, client *nex.Client, callID uint32, principalID uint32)) {
+	accountManagementProtocol.DeleteAccountHandler = handler
+}
+
+// DisableAccount sets the DisableAccount handler function
+func (accountManagementProtocol *AccountManagementProtocol) DisableAccount(handler func(err error, client *nex.Client, callID uint32, principalID uint32, disabledUntil *nex.DateTime, message string)) {
+	accountManagementProtocol.DisableAccountHandler = handler
+}
+
+// ChangePassword sets the ChangePassword handler function
+func (accountManagementProtocol *AccountManagementProtocol) ChangePassword(handler func(err error, client *nex.Client, callID uint32, newKey string)) {
+	accountManagementProtocol.ChangePasswordHandler = handler
+}
+
+// TestCapability sets the TestCapability handler function
+func (accountManagementProtocol *AccountManagementProtocol) TestCapability(handler func(err error, client *nex.Client, callID uint32, capability uint32)) {
+	accountManagementProtocol.TestCapabilityHandler = handler
+}
+
+// GetName sets the GetName handler function
+func (accountManagementProtocol *AccountManagementProtocol) GetName(handler func(err error, client *nex.Client, callID uint32, principalID uint32)) {
+	accountManagementProtocol.GetNameHandler = handler
+}
+
+// GetAccountData sets the GetAccountData handler function
+func (accountManagementProtocol *AccountManagementProtocol) GetAccountData(handler func(err error, client *nex.Client, callID uint32)) {
+	accountManagementProtocol.GetAccountDataHandler = handler
+}
+
+// GetPrivateData sets the GetPrivateData handler function
+func (accountManagementProtocol *AccountManagementProtocol) GetPrivateData(handler func(err error, client *nex.Client, callID uint32)) {
+	accountManagementProtocol.GetPrivateDataHandler = handler
+}
+
+// GetPublicData sets the GetPublicData handler function
+func (accountManagementProtocol *AccountManagementProtocol) GetPublicData(handler func(err error, client *nex.Client, callID uint32, principalID uint32)) {
+	accountManagementProtocol.GetPublicDataHandler = handler
+}
+
+// UpdateAccountName sets the UpdateAccountName handler function
+func (accountManagementProtocol *AccountManagementProtocol) UpdateAccountName(handler func(err error, client *nex.Client, callID uint32, name string)) {
+	accountManagementProtocol.UpdateAccountNameHandler = handler
+}
+
+// UpdateAccountEmail sets the UpdateAccountEmail handler function
+func (accountManagementProtocol *AccountManagementProtocol) UpdateAccountEmail(handler func(err error, client *nex.Client, callID uint32, email string)) {
+	accountManagementProtocol.UpdateAccountEmailHandler = handler
+}
+
+// UpdateCustomData sets the UpdateCustomData handler function
+func (accountManagementProtocol *AccountManagementProtocol) UpdateCustomData(handler func(err error, client *nex.Client, callID uint32, publicData *DataHolder, privateData *DataHolder)) {
+	accountManagementProtocol.UpdateCustomDataHandler = handler
+}
+
+// FindByNameRegex sets the FindByNameRegex handler function
+func (accountManagementProtocol *AccountManagementProtocol) FindByNameRegex(handler func(err error, client *nex.Client, callID uint32, groups uint32, regex string, resultRange *ResultRange)) {
+	accountManagementProtocol.FindByNameRegexHandler = handler
+}
+
+// UpdateAccountExpiryDate sets the UpdateAccountExpiryDate handler function
+func (accountManagementProtocol *AccountManagementProtocol) UpdateAccountExpiryDate(handler func(err error, client *nex.Client, callID uint32, principalID uint32, expiryDate *nex.DateTime, expiredMessage string)) {
+	accountManagementProtocol.UpdateAccountExpiryDateHandler = handler
+}
+
+// UpdateAccountEffectiveDate sets the UpdateAccountEffectiveDate handler function
+func (accountManagementProtocol *AccountManagementProtocol) UpdateAccountEffectiveDate(handler func(err error, client *nex.Client, callID uint32, principalID uint32, effectiveFrom *nex.DateTime, notEffectiveMessage string)) {
+	accountManagementProtocol.UpdateAccountEffectiveDateHandler = handler
+}
+
+// SetStatus sets the SetStatus handler function
+func (accountManagementProtocol *AccountManagementProtocol) SetStatus(handler func(err error, client *nex.Client, callID uint32, status string)) {
+	accountManagementProtocol.SetStatusHandler = handler
+}
+
+// GetStatus sets the GetStatus handler function
+func (accountManagementProtocol *AccountManagementProtocol) GetStatus(handler func(err error, client *nex.Client, callID uint32, principalID uint32)) {
+	accountManagementProtocol.GetStatusHandler = handler
+}
+
+// GetLastConnectionStats sets the GetLastConnectionStats handler function
+func (accountManagementProtocol *AccountManagementProtocol) GetLastConnectionStats(handler func(err error, client *nex.Client, callID uint32, principalID uint32)) {
+	accountManagementProtocol.GetLastConnectionStatsHandler = handler
+}
+
+// ResetPassword sets the ResetPassword handler function
+func (accountManagementProtocol *AccountManagementProtocol) ResetPassword(handler func(err error, client *nex.Client, callID uint32)) {
+	accountManagementProtocol.ResetPasswordHandler = handler
+}
+
+// CreateAccountWithCustomData sets the CreateAccountWithCustomData handler function
+func (accountManagementProtocol *AccountManagementProtocol) CreateAccountWithCustomData(handler func(err error, client *nex.Client, callID uint32, principalName string, key string, groups uint32, email string, publicData *DataHolder, privateData *DataHolder)) {
+	accountManagementProtocol.CreateAccountWithCustomDataHandler = handler
+}
+
+// RetrieveAccount sets the RetrieveAccount handler function
+func (accountManagementProtocol *AccountManagementProtocol) RetrieveAccount(handler func(err error, client *nex.Client, callID uint32)) {
+	accountManagementProtocol.RetrieveAccountHandler = handler
+}
+
+// UpdateAccount sets the UpdateAccount handler function
+func (accountManagementProtocol *AccountManagementProtocol) UpdateAccount(handler func(err error, client *nex.Client, callID uint32, key string, email string, publicData *DataHolder, privateData *DataHolder)) {
+	accountManagementProtocol.UpdateAccountHandler = handler
+}
+
+// ChangePasswordByGuest sets the ChangePasswordByGuest handler function
+func (accountManagementProtocol *AccountManagementProtocol) ChangePasswordByGuest(handler func(err error, client *nex.Client, callID uint32, principalName string, key string, email string)) {
+	accountManagementProtocol.ChangePasswordByGuestHandler = handler
+}
+
+// FindByNameLike sets the FindByNameLike handler function
+func (accountManagementProtocol *AccountManagementProtocol) FindByNameLike(handler func(err error, client *nex.Client, callID uint32, groups uint32, like string, resultRange *ResultRange)) {
+	accountManagementProtocol.FindByNameLikeHandler = handler
+}
+
+// CustomCreateAccount sets the CustomCreateAccount handler function
+func (accountManagementProtocol *AccountManagementProtocol) CustomCreateAccount(handler func(err error, client *nex.Client, callID uint32, principalName string, key string, groups uint32, email string, authData *DataHolder)) {
+	accountManagementProtocol.CustomCreateAccountHandler = handler
+}
+
+// NintendoCreateAccount sets the NintendoCreateAccount handler function
+func (accountManagementProtocol *AccountManagementProtocol) NintendoCreateAccount(handler func(err error, client *nex.Client, callID uint32, username string, key string, groups uint32, email string)) {
+	accountManagementProtocol.NintendoCreateAccountHandler = handler
+}
+
+// LookupOrCreateAccount sets the LookupOrCreateAccount handler function
+func (accountManagementProtocol *AccountManagementProtocol) LookupOrCreateAccount(handler func(err error, client *nex.Client, callID uint32, principalName string, key string, groups uint32, email string, authData *DataHolder)) {
+	accountManagementProtocol.LookupOrCreateAccountHandler = handler
+}
+
+// DisconnectPrincipal sets the DisconnectPrincipal handler function
+func (accountManagementProtocol *AccountManagementProtocol) DisconnectPrincipal(handler func(err error, client *nex.Client, callID uint32, principalID uint32)) {
+	accountManagementProtocol.DisconnectPrincipalHandler = handler
+}
+
+// DisconnectAllPrincipals sets the DisconnectAllPrincipals handler function
+func (accountManagementProtocol *AccountManagementProtocol) DisconnectAllPrincipals(handler func(err error, client *nex.Client, callID uint32)) {
+	accountManagementProtocol.DisconnectAllPrincipalsHandler = handler
+}
+
+// RespondCreateAccount answers a CreateAccount call with the result of the creation
+func (accountManagementProtocol *AccountManagementProtocol) RespondCreateAccount(client *nex.Client, callID uint32, result uint32) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt32LE(result)
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodCreateAccount, callID, responseStream.Bytes())
+}
+
+// RespondDeleteAccount answers a DeleteAccount call
+func (accountManagementProtocol *AccountManagementProtocol) RespondDeleteAccount(client *nex.Client, callID uint32) {
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodDeleteAccount, callID, make([]byte, 0))
+}
+
+// RespondDisableAccount answers a DisableAccount call with the result of the change
+func (accountManagementProtocol *AccountManagementProtocol) RespondDisableAccount(client *nex.Client, callID uint32, result uint32) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt32LE(result)
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodDisableAccount, callID, responseStream.Bytes())
+}
+
+// RespondChangePassword answers a ChangePassword call with whether the password was changed
+func (accountManagementProtocol *AccountManagementProtocol) RespondChangePassword(client *nex.Client, callID uint32, changed bool) {
+	responseStream := nex.NewStreamOut(client.Server())
+	writeBool(responseStream, changed)
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodChangePassword, callID, responseStream.Bytes())
+}
+
+// RespondTestCapability answers a TestCapability call with whether the caller has the capability
+func (accountManagementProtocol *AccountManagementProtocol) RespondTestCapability(client *nex.Client, callID uint32, capable bool) {
+	responseStream := nex.NewStreamOut(client.Server())
+	writeBool(responseStream, capable)
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodTestCapability, callID, responseStream.Bytes())
+}
+
+// RespondGetName answers a GetName call with the name of the principal
+func (accountManagementProtocol *AccountManagementProtocol) RespondGetName(client *nex.Client, callID uint32, name string) {
+	responseStream := nex.NewStreamOut(client.Server())
+	write4ByteString(responseStream, name)
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodGetName, callID, responseStream.Bytes())
+}
+
+// RespondGetAccountData answers a GetAccountData call with the account of the caller
+func (accountManagementProtocol *AccountManagementProtocol) RespondGetAccountData(client *nex.Client, callID uint32, result uint32, accountData *AccountData) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt32LE(result)
+	responseStream.WriteStructure(accountData)
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodGetAccountData, callID, responseStream.Bytes())
+}
+
+// RespondGetPrivateData answers a GetPrivateData call with the private custom data of the caller
+func (accountManagementProtocol *AccountManagementProtocol) RespondGetPrivateData(client *nex.Client, callID uint32, hasData bool, privateData *DataHolder) {
+	responseStream := nex.NewStreamOut(client.Server())
+	writeBool(responseStream, hasData)
+	responseStream.WriteStructure(privateData)
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodGetPrivateData, callID, responseStream.Bytes())
+}
+
+// RespondGetPublicData answers a GetPublicData call with the public custom data of the principal
+func (accountManagementProtocol *AccountManagementProtocol) RespondGetPublicData(client *nex.Client, callID uint32, hasData bool, publicData *DataHolder) {
+	responseStream := nex.NewStreamOut(client.Server())
+	writeBool(responseStream, hasData)
+	responseStream.WriteStructure(publicData)
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodGetPublicData, callID, responseStream.Bytes())
+}
+
+// RespondUpdateAccountName answers a UpdateAccountName call with the result of the change
+func (accountManagementProtocol *AccountManagementProtocol) RespondUpdateAccountName(client *nex.Client, callID uint32, result uint32) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt32LE(result)
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodUpdateAccountName, callID, responseStream.Bytes())
+}
+
+// RespondUpdateAccountEmail answers a UpdateAccountEmail call with the result of the change
+func (accountManagementProtocol *AccountManagementProtocol) RespondUpdateAccountEmail(client *nex.Client, callID uint32, result uint32) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt32LE(result)
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodUpdateAccountEmail, callID, responseStream.Bytes())
+}
+
+// RespondUpdateCustomData answers a UpdateCustomData call with the result of the change
+func (accountManagementProtocol *AccountManagementProtocol) RespondUpdateCustomData(client *nex.Client, callID uint32, result uint32) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt32LE(result)
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodUpdateCustomData, callID, responseStream.Bytes())
+}
+
+// RespondFindByNameRegex answers a FindByNameRegex call with the matching accounts
+func (accountManagementProtocol *AccountManagementProtocol) RespondFindByNameRegex(client *nex.Client, callID uint32, accounts []*BasicAccountInfo) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt32LE(uint32(len(accounts)))
+
+	for _, account := range accounts {
+		responseStream.WriteStructure(account)
+	}
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodFindByNameRegex, callID, responseStream.Bytes())
+}
+
+// RespondUpdateAccountExpiryDate answers a UpdateAccountExpiryDate call
+func (accountManagementProtocol *AccountManagementProtocol) RespondUpdateAccountExpiryDate(client *nex.Client, callID uint32) {
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodUpdateAccountExpiryDate, callID, make([]byte, 0))
+}
+
+// RespondUpdateAccountEffectiveDate answers a UpdateAccountEffectiveDate call
+func (accountManagementProtocol *AccountManagementProtocol) RespondUpdateAccountEffectiveDate(client *nex.Client, callID uint32) {
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodUpdateAccountEffectiveDate, callID, make([]byte, 0))
+}
+
+// RespondSetStatus answers a SetStatus call
+func (accountManagementProtocol *AccountManagementProtocol) RespondSetStatus(client *nex.Client, callID uint32) {
+	respondSuccess(client, AccountManagementProtocolID, SetStatus, callID, make([]byte, 0))
+}
+
+// RespondGetStatus answers a GetStatus call with the status of the principal
+func (accountManagementProtocol *AccountManagementProtocol) RespondGetStatus(client *nex.Client, callID uint32, status string) {
+	responseStream := nex.NewStreamOut(client.Server())
+	write4ByteString(responseStream, status)
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodGetStatus, callID, responseStream.Bytes())
+}
+
+// RespondGetLastConnectionStats answers a GetLastConnectionStats call with the connection times of the principal
+func (accountManagementProtocol *AccountManagementProtocol) RespondGetLastConnectionStats(client *nex.Client, callID uint32, lastSessionLogin *nex.DateTime, lastSessionLogout *nex.DateTime, currentSessionLogin *nex.DateTime) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt64LE(lastSessionLogin.Value())
+	responseStream.WriteUInt64LE(lastSessionLogout.Value())
+	responseStream.WriteUInt64LE(currentSessionLogin.Value())
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodGetLastConnectionStats, callID, responseStream.Bytes())
+}
+
+// RespondResetPassword answers a ResetPassword call with whether the password was reset
+func (accountManagementProtocol *AccountManagementProtocol) RespondResetPassword(client *nex.Client, callID uint32, reset bool) {
+	responseStream := nex.NewStreamOut(client.Server())
+	writeBool(responseStream, reset)
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodResetPassword, callID, responseStream.Bytes())
+}
+
+// RespondCreateAccountWithCustomData answers a CreateAccountWithCustomData call
+func (accountManagementProtocol *AccountManagementProtocol) RespondCreateAccountWithCustomData(client *nex.Client, callID uint32) {
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodCreateAccountWithCustomData, callID, make([]byte, 0))
+}
+
+// RespondRetrieveAccount answers a RetrieveAccount call with the account and custom data of the caller
+func (accountManagementProtocol *AccountManagementProtocol) RespondRetrieveAccount(client *nex.Client, callID uint32, accountData *AccountData, publicData *DataHolder, privateData *DataHolder) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteStructure(accountData)
+	responseStream.WriteStructure(publicData)
+	responseStream.WriteStructure(privateData)
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodRetrieveAccount, callID, responseStream.Bytes())
+}
+
+// RespondUpdateAccount answers a UpdateAccount call
+func (accountManagementProtocol *AccountManagementProtocol) RespondUpdateAccount(client *nex.Client, callID uint32) {
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodUpdateAccount, callID, make([]byte, 0))
+}
+
+// RespondChangePasswordByGuest answers a ChangePasswordByGuest call
+func (accountManagementProtocol *AccountManagementProtocol) RespondChangePasswordByGuest(client *nex.Client, callID uint32) {
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodChangePasswordByGuest, callID, make([]byte, 0))
+}
+
+// RespondFindByNameLike answers a FindByNameLike call with the matching accounts
+func (accountManagementProtocol *AccountManagementProtocol) RespondFindByNameLike(client *nex.Client, callID uint32, accounts []*BasicAccountInfo) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt32LE(uint32(len(accounts)))
+
+	for _, account := range accounts {
+		responseStream.WriteStructure(account)
+	}
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodFindByNameLike, callID, responseStream.Bytes())
+}
+
+// RespondCustomCreateAccount answers a CustomCreateAccount call with the PID of the created account
+func (accountManagementProtocol *AccountManagementProtocol) RespondCustomCreateAccount(client *nex.Client, callID uint32, pid uint32) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt32LE(pid)
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodCustomCreateAccount, callID, responseStream.Bytes())
+}
+
+// RespondNintendoCreateAccount answers a NintendoCreateAccount call with the PID of the created account and its HMAC
+func (accountManagementProtocol *AccountManagementProtocol) RespondNintendoCreateAccount(client *nex.Client, callID uint32, pid uint32, pidHMAC string) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt32LE(pid)
+	write4ByteString(responseStream, pidHMAC)
+
+	respondSuccess(client, AccountManagementProtocolID, NintendoCreateAccount, callID, responseStream.Bytes())
+}
+
+// RespondLookupOrCreateAccount answers a LookupOrCreateAccount call with the PID of the found or created account
+func (accountManagementProtocol *AccountManagementProtocol) RespondLookupOrCreateAccount(client *nex.Client, callID uint32, pid uint32) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt32LE(pid)
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodLookupOrCreateAccount, callID, responseStream.Bytes())
+}
+
+// RespondDisconnectPrincipal answers a DisconnectPrincipal call with whether the principal was disconnected
+func (accountManagementProtocol *AccountManagementProtocol) RespondDisconnectPrincipal(client *nex.Client, callID uint32, disconnected bool) {
+	responseStream := nex.NewStreamOut(client.Server())
+	writeBool(responseStream, disconnected)
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodDisconnectPrincipal, callID, responseStream.Bytes())
+}
+
+// RespondDisconnectAllPrincipals answers a DisconnectAllPrincipals call with whether the principals were disconnected
+func (accountManagementProtocol *AccountManagementProtocol) RespondDisconnectAllPrincipals(client *nex.Client, callID uint32, disconnected bool) {
+	responseStream := nex.NewStreamOut(client.Server())
+	writeBool(responseStream, disconnected)
+
+	respondSuccess(client, AccountManagementProtocolID, AccountManagementMethodDisconnectAllPrincipals, callID, responseStream.Bytes())
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleCreateAccount(packet nex.PacketInterface) {
+	if accountManagementProtocol.CreateAccountHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::CreateAccount not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	principalName, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.CreateAccountHandler(err, client, callID, "", "", 0, "")
+		return
+	}
+
+	key, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.CreateAccountHandler(err, client, callID, "", "", 0, "")
+		return
+	}
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[AccountManagementProtocol::CreateAccount] Data missing groups")
+		go accountManagementProtocol.CreateAccountHandler(err, client, callID, "", "", 0, "")
+		return
+	}
+
+	groups := parametersStream.ReadUInt32LE()
+
+	email, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.CreateAccountHandler(err, client, callID, "", "", 0, "")
+		return
+	}
+
+	go accountManagementProtocol.CreateAccountHandler(nil, client, callID, principalName, key, groups, email)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleDeleteAccount(packet nex.PacketInterface) {
+	if accountManagementProtocol.DeleteAccountHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::DeleteAccount not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[AccountManagementProtocol::DeleteAccount] Data missing principal ID")
+		go accountManagementProtocol.DeleteAccountHandler(err, client, callID, 0)
+		return
+	}
+
+	principalID := parametersStream.ReadUInt32LE()
+
+	go accountManagementProtocol.DeleteAccountHandler(nil, client, callID, principalID)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleDisableAccount(packet nex.PacketInterface) {
+	if accountManagementProtocol.DisableAccountHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::DisableAccount not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[AccountManagementProtocol::DisableAccount] Data missing principal ID")
+		go accountManagementProtocol.DisableAccountHandler(err, client, callID, 0, nil, "")
+		return
+	}
+
+	principalID := parametersStream.ReadUInt32LE()
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 8 {
+		err := errors.New("[AccountManagementProtocol::DisableAccount] Data missing disabled until")
+		go accountManagementProtocol.DisableAccountHandler(err, client, callID, 0, nil, "")
+		return
+	}
+
+	disabledUntil := nex.NewDateTime(parametersStream.ReadUInt64LE())
+
+	message, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.DisableAccountHandler(err, client, callID, 0, nil, "")
+		return
+	}
+
+	go accountManagementProtocol.DisableAccountHandler(nil, client, callID, principalID, disabledUntil, message)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleChangePassword(packet nex.PacketInterface) {
+	if accountManagementProtocol.ChangePasswordHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::ChangePassword not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	newKey, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.ChangePasswordHandler(err, client, callID, "")
+		return
+	}
+
+	go accountManagementProtocol.ChangePasswordHandler(nil, client, callID, newKey)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleTestCapability(packet nex.PacketInterface) {
+	if accountManagementProtocol.TestCapabilityHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::TestCapability not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[AccountManagementProtocol::TestCapability] Data missing capability")
+		go accountManagementProtocol.TestCapabilityHandler(err, client, callID, 0)
+		return
+	}
+
+	capability := parametersStream.ReadUInt32LE()
+
+	go accountManagementProtocol.TestCapabilityHandler(nil, client, callID, capability)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleGetName(packet nex.PacketInterface) {
+	if accountManagementProtocol.GetNameHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::GetName not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[AccountManagementProtocol::GetName] Data missing principal ID")
+		go accountManagementProtocol.GetNameHandler(err, client, callID, 0)
+		return
+	}
+
+	principalID := parametersStream.ReadUInt32LE()
+
+	go accountManagementProtocol.GetNameHandler(nil, client, callID, principalID)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleGetAccountData(packet nex.PacketInterface) {
+	if accountManagementProtocol.GetAccountDataHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::GetAccountData not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+
+	go accountManagementProtocol.GetAccountDataHandler(nil, client, callID)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleGetPrivateData(packet nex.PacketInterface) {
+	if accountManagementProtocol.GetPrivateDataHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::GetPrivateData not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+
+	go accountManagementProtocol.GetPrivateDataHandler(nil, client, callID)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleGetPublicData(packet nex.PacketInterface) {
+	if accountManagementProtocol.GetPublicDataHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::GetPublicData not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[AccountManagementProtocol::GetPublicData] Data missing principal ID")
+		go accountManagementProtocol.GetPublicDataHandler(err, client, callID, 0)
+		return
+	}
+
+	principalID := parametersStream.ReadUInt32LE()
+
+	go accountManagementProtocol.GetPublicDataHandler(nil, client, callID, principalID)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleUpdateAccountName(packet nex.PacketInterface) {
+	if accountManagementProtocol.UpdateAccountNameHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::UpdateAccountName not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	name, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.UpdateAccountNameHandler(err, client, callID, "")
+		return
+	}
+
+	go accountManagementProtocol.UpdateAccountNameHandler(nil, client, callID, name)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleUpdateAccountEmail(packet nex.PacketInterface) {
+	if accountManagementProtocol.UpdateAccountEmailHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::UpdateAccountEmail not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	email, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.UpdateAccountEmailHandler(err, client, callID, "")
+		return
+	}
+
+	go accountManagementProtocol.UpdateAccountEmailHandler(nil, client, callID, email)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleUpdateCustomData(packet nex.PacketInterface) {
+	if accountManagementProtocol.UpdateCustomDataHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::UpdateCustomData not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	publicDataStructureInterface, err := parametersStream.ReadStructure(NewDataHolder())
+	if err != nil {
+		go accountManagementProtocol.UpdateCustomDataHandler(err, client, callID, nil, nil)
+		return
+	}
+
+	privateDataStructureInterface, err := parametersStream.ReadStructure(NewDataHolder())
+	if err != nil {
+		go accountManagementProtocol.UpdateCustomDataHandler(err, client, callID, nil, nil)
+		return
+	}
+
+	publicData := publicDataStructureInterface.(*DataHolder)
+	privateData := privateDataStructureInterface.(*DataHolder)
+
+	go accountManagementProtocol.UpdateCustomDataHandler(nil, client, callID, publicData, privateData)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleFindByNameRegex(packet nex.PacketInterface) {
+	if accountManagementProtocol.FindByNameRegexHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::FindByNameRegex not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[AccountManagementProtocol::FindByNameRegex] Data missing groups")
+		go accountManagementProtocol.FindByNameRegexHandler(err, client, callID, 0, "", nil)
+		return
+	}
+
+	groups := parametersStream.ReadUInt32LE()
+
+	regex, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.FindByNameRegexHandler(err, client, callID, 0, "", nil)
+		return
+	}
+
+	resultRangeStructureInterface, err := parametersStream.ReadStructure(NewResultRange())
+	if err != nil {
+		go accountManagementProtocol.FindByNameRegexHandler(err, client, callID, 0, "", nil)
+		return
+	}
+
+	resultRange := resultRangeStructureInterface.(*ResultRange)
+
+	go accountManagementProtocol.FindByNameRegexHandler(nil, client, callID, groups, regex, resultRange)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleUpdateAccountExpiryDate(packet nex.PacketInterface) {
+	if accountManagementProtocol.UpdateAccountExpiryDateHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::UpdateAccountExpiryDate not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[AccountManagementProtocol::UpdateAccountExpiryDate] Data missing principal ID")
+		go accountManagementProtocol.UpdateAccountExpiryDateHandler(err, client, callID, 0, nil, "")
+		return
+	}
+
+	principalID := parametersStream.ReadUInt32LE()
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 8 {
+		err := errors.New("[AccountManagementProtocol::UpdateAccountExpiryDate] Data missing expiry date")
+		go accountManagementProtocol.UpdateAccountExpiryDateHandler(err, client, callID, 0, nil, "")
+		return
+	}
+
+	expiryDate := nex.NewDateTime(parametersStream.ReadUInt64LE())
+
+	expiredMessage, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.UpdateAccountExpiryDateHandler(err, client, callID, 0, nil, "")
+		return
+	}
+
+	go accountManagementProtocol.UpdateAccountExpiryDateHandler(nil, client, callID, principalID, expiryDate, expiredMessage)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleUpdateAccountEffectiveDate(packet nex.PacketInterface) {
+	if accountManagementProtocol.UpdateAccountEffectiveDateHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::UpdateAccountEffectiveDate not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[AccountManagementProtocol::UpdateAccountEffectiveDate] Data missing principal ID")
+		go accountManagementProtocol.UpdateAccountEffectiveDateHandler(err, client, callID, 0, nil, "")
+		return
+	}
+
+	principalID := parametersStream.ReadUInt32LE()
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 8 {
+		err := errors.New("[AccountManagementProtocol::UpdateAccountEffectiveDate] Data missing effective from")
+		go accountManagementProtocol.UpdateAccountEffectiveDateHandler(err, client, callID, 0, nil, "")
+		return
+	}
+
+	effectiveFrom := nex.NewDateTime(parametersStream.ReadUInt64LE())
+
+	notEffectiveMessage, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.UpdateAccountEffectiveDateHandler(err, client, callID, 0, nil, "")
+		return
+	}
+
+	go accountManagementProtocol.UpdateAccountEffectiveDateHandler(nil, client, callID, principalID, effectiveFrom, notEffectiveMessage)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleSetStatus(packet nex.PacketInterface) {
+	if accountManagementProtocol.SetStatusHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::SetStatus not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := nex.NewStreamIn(parameters, accountManagementProtocol.server)
+
+	status, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.SetStatusHandler(err, client, callID, "")
+		return
+	}
+
+	go accountManagementProtocol.SetStatusHandler(nil, client, callID, status)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleGetStatus(packet nex.PacketInterface) {
+	if accountManagementProtocol.GetStatusHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::GetStatus not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[AccountManagementProtocol::GetStatus] Data missing principal ID")
+		go accountManagementProtocol.GetStatusHandler(err, client, callID, 0)
+		return
+	}
+
+	principalID := parametersStream.ReadUInt32LE()
+
+	go accountManagementProtocol.GetStatusHandler(nil, client, callID, principalID)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleGetLastConnectionStats(packet nex.PacketInterface) {
+	if accountManagementProtocol.GetLastConnectionStatsHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::GetLastConnectionStats not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[AccountManagementProtocol::GetLastConnectionStats] Data missing principal ID")
+		go accountManagementProtocol.GetLastConnectionStatsHandler(err, client, callID, 0)
+		return
+	}
+
+	principalID := parametersStream.ReadUInt32LE()
+
+	go accountManagementProtocol.GetLastConnectionStatsHandler(nil, client, callID, principalID)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleResetPassword(packet nex.PacketInterface) {
+	if accountManagementProtocol.ResetPasswordHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::ResetPassword not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+
+	go accountManagementProtocol.ResetPasswordHandler(nil, client, callID)
 }
 
-// Setup initializes the protocol
-func (accountManagementProtocol *AccountManagementProtocol) Setup() {
-	nexServer := accountManagementProtocol.server
+func (accountManagementProtocol *AccountManagementProtocol) handleCreateAccountWithCustomData(packet nex.PacketInterface) {
+	if accountManagementProtocol.CreateAccountWithCustomDataHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::CreateAccountWithCustomData not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
 
-	nexServer.On("Data", func(packet nex.PacketInterface) {
-		request := packet.RMCRequest()
+	client := packet.Sender()
+	request := packet.RMCRequest()
 
-		if AccountManagementProtocolID == request.ProtocolID() {
-			switch request.MethodID() {
-			case NintendoCreateAccount:
-				go accountManagementProtocol.handleNintendoCreateAccount(packet)
-			case SetStatus:
-				go accountManagementProtocol.handleSetStatus(packet)
-			default:
-				log.Printf("Unsupported AccountManagement method ID: %#v\n", request.MethodID())
-			}
-		}
-	})
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	principalName, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.CreateAccountWithCustomDataHandler(err, client, callID, "", "", 0, "", nil, nil)
+		return
+	}
+
+	key, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.CreateAccountWithCustomDataHandler(err, client, callID, "", "", 0, "", nil, nil)
+		return
+	}
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[AccountManagementProtocol::CreateAccountWithCustomData] Data missing groups")
+		go accountManagementProtocol.CreateAccountWithCustomDataHandler(err, client, callID, "", "", 0, "", nil, nil)
+		return
+	}
+
+	groups := parametersStream.ReadUInt32LE()
+
+	email, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.CreateAccountWithCustomDataHandler(err, client, callID, "", "", 0, "", nil, nil)
+		return
+	}
+
+	publicDataStructureInterface, err := parametersStream.ReadStructure(NewDataHolder())
+	if err != nil {
+		go accountManagementProtocol.CreateAccountWithCustomDataHandler(err, client, callID, "", "", 0, "", nil, nil)
+		return
+	}
+
+	privateDataStructureInterface, err := parametersStream.ReadStructure(NewDataHolder())
+	if err != nil {
+		go accountManagementProtocol.CreateAccountWithCustomDataHandler(err, client, callID, "", "", 0, "", nil, nil)
+		return
+	}
+
+	publicData := publicDataStructureInterface.(*DataHolder)
+	privateData := privateDataStructureInterface.(*DataHolder)
+
+	go accountManagementProtocol.CreateAccountWithCustomDataHandler(nil, client, callID, principalName, key, groups, email, publicData, privateData)
 }
 
-// NintendoCreateAccount sets the NintendoCreateAccount handler function
-func (accountManagementProtocol *AccountManagementProtocol) NintendoCreateAccount(handler func(err error, client *nex.Client, callID uint32, username string, key string, groups uint32, email string)) {
-	accountManagementProtocol.NintendoCreateAccountHandler = handler
+func (accountManagementProtocol *AccountManagementProtocol) handleRetrieveAccount(packet nex.PacketInterface) {
+	if accountManagementProtocol.RetrieveAccountHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::RetrieveAccount not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+
+	go accountManagementProtocol.RetrieveAccountHandler(nil, client, callID)
 }
 
-// SetStatus sets the SetStatus handler function
-func (accountManagementProtocol *AccountManagementProtocol) SetStatus(handler func(err error, client *nex.Client, callID uint32, status string)) {
-	accountManagementProtocol.SetStatusHandler = handler
+func (accountManagementProtocol *AccountManagementProtocol) handleUpdateAccount(packet nex.PacketInterface) {
+	if accountManagementProtocol.UpdateAccountHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::UpdateAccount not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	key, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.UpdateAccountHandler(err, client, callID, "", "", nil, nil)
+		return
+	}
+
+	email, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.UpdateAccountHandler(err, client, callID, "", "", nil, nil)
+		return
+	}
+
+	publicDataStructureInterface, err := parametersStream.ReadStructure(NewDataHolder())
+	if err != nil {
+		go accountManagementProtocol.UpdateAccountHandler(err, client, callID, "", "", nil, nil)
+		return
+	}
+
+	privateDataStructureInterface, err := parametersStream.ReadStructure(NewDataHolder())
+	if err != nil {
+		go accountManagementProtocol.UpdateAccountHandler(err, client, callID, "", "", nil, nil)
+		return
+	}
+
+	publicData := publicDataStructureInterface.(*DataHolder)
+	privateData := privateDataStructureInterface.(*DataHolder)
+
+	go accountManagementProtocol.UpdateAccountHandler(nil, client, callID, key, email, publicData, privateData)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleChangePasswordByGuest(packet nex.PacketInterface) {
+	if accountManagementProtocol.ChangePasswordByGuestHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::ChangePasswordByGuest not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	principalName, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.ChangePasswordByGuestHandler(err, client, callID, "", "", "")
+		return
+	}
+
+	key, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.ChangePasswordByGuestHandler(err, client, callID, "", "", "")
+		return
+	}
+
+	email, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.ChangePasswordByGuestHandler(err, client, callID, "", "", "")
+		return
+	}
+
+	go accountManagementProtocol.ChangePasswordByGuestHandler(nil, client, callID, principalName, key, email)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleFindByNameLike(packet nex.PacketInterface) {
+	if accountManagementProtocol.FindByNameLikeHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::FindByNameLike not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[AccountManagementProtocol::FindByNameLike] Data missing groups")
+		go accountManagementProtocol.FindByNameLikeHandler(err, client, callID, 0, "", nil)
+		return
+	}
+
+	groups := parametersStream.ReadUInt32LE()
+
+	like, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.FindByNameLikeHandler(err, client, callID, 0, "", nil)
+		return
+	}
+
+	resultRangeStructureInterface, err := parametersStream.ReadStructure(NewResultRange())
+	if err != nil {
+		go accountManagementProtocol.FindByNameLikeHandler(err, client, callID, 0, "", nil)
+		return
+	}
+
+	resultRange := resultRangeStructureInterface.(*ResultRange)
+
+	go accountManagementProtocol.FindByNameLikeHandler(nil, client, callID, groups, like, resultRange)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleCustomCreateAccount(packet nex.PacketInterface) {
+	if accountManagementProtocol.CustomCreateAccountHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::CustomCreateAccount not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	principalName, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.CustomCreateAccountHandler(err, client, callID, "", "", 0, "", nil)
+		return
+	}
+
+	key, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.CustomCreateAccountHandler(err, client, callID, "", "", 0, "", nil)
+		return
+	}
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[AccountManagementProtocol::CustomCreateAccount] Data missing groups")
+		go accountManagementProtocol.CustomCreateAccountHandler(err, client, callID, "", "", 0, "", nil)
+		return
+	}
+
+	groups := parametersStream.ReadUInt32LE()
+
+	email, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.CustomCreateAccountHandler(err, client, callID, "", "", 0, "", nil)
+		return
+	}
+
+	authDataStructureInterface, err := parametersStream.ReadStructure(NewDataHolder())
+	if err != nil {
+		go accountManagementProtocol.CustomCreateAccountHandler(err, client, callID, "", "", 0, "", nil)
+		return
+	}
+
+	authData := authDataStructureInterface.(*DataHolder)
+
+	go accountManagementProtocol.CustomCreateAccountHandler(nil, client, callID, principalName, key, groups, email, authData)
 }
 
 func (accountManagementProtocol *AccountManagementProtocol) handleNintendoCreateAccount(packet nex.PacketInterface) {
@@ -88,9 +1572,9 @@ func (accountManagementProtocol *AccountManagementProtocol) handleNintendoCreate
 	go accountManagementProtocol.NintendoCreateAccountHandler(nil, client, callID, username, key, groups, email)
 }
 
-func (accountManagementProtocol *AccountManagementProtocol) handleSetStatus(packet nex.PacketInterface) {
-	if accountManagementProtocol.NintendoCreateAccountHandler == nil {
-		log.Println("[Warning] AccountManagementProtocol::SetStatus not implemented")
+func (accountManagementProtocol *AccountManagementProtocol) handleLookupOrCreateAccount(packet nex.PacketInterface) {
+	if accountManagementProtocol.LookupOrCreateAccountHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::LookupOrCreateAccount not implemented")
 		go respondNotImplemented(packet, AccountManagementProtocolID)
 		return
 	}
@@ -101,15 +1585,84 @@ func (accountManagementProtocol *AccountManagementProtocol) handleSetStatus(pack
 	callID := request.CallID()
 	parameters := request.Parameters()
 
-	parametersStream := nex.NewStreamIn(parameters, accountManagementProtocol.server)
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
 
-	status, err := parametersStream.Read4ByteString()
+	principalName, err := parametersStream.Read4ByteString()
 	if err != nil {
-		go accountManagementProtocol.SetStatusHandler(err, client, callID, "")
+		go accountManagementProtocol.LookupOrCreateAccountHandler(err, client, callID, "", "", 0, "", nil)
 		return
 	}
 
-	go accountManagementProtocol.SetStatusHandler(nil, client, callID, status)
+	key, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.LookupOrCreateAccountHandler(err, client, callID, "", "", 0, "", nil)
+		return
+	}
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[AccountManagementProtocol::LookupOrCreateAccount] Data missing groups")
+		go accountManagementProtocol.LookupOrCreateAccountHandler(err, client, callID, "", "", 0, "", nil)
+		return
+	}
+
+	groups := parametersStream.ReadUInt32LE()
+
+	email, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go accountManagementProtocol.LookupOrCreateAccountHandler(err, client, callID, "", "", 0, "", nil)
+		return
+	}
+
+	authDataStructureInterface, err := parametersStream.ReadStructure(NewDataHolder())
+	if err != nil {
+		go accountManagementProtocol.LookupOrCreateAccountHandler(err, client, callID, "", "", 0, "", nil)
+		return
+	}
+
+	authData := authDataStructureInterface.(*DataHolder)
+
+	go accountManagementProtocol.LookupOrCreateAccountHandler(nil, client, callID, principalName, key, groups, email, authData)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleDisconnectPrincipal(packet nex.PacketInterface) {
+	if accountManagementProtocol.DisconnectPrincipalHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::DisconnectPrincipal not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, accountManagementProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[AccountManagementProtocol::DisconnectPrincipal] Data missing principal ID")
+		go accountManagementProtocol.DisconnectPrincipalHandler(err, client, callID, 0)
+		return
+	}
+
+	principalID := parametersStream.ReadUInt32LE()
+
+	go accountManagementProtocol.DisconnectPrincipalHandler(nil, client, callID, principalID)
+}
+
+func (accountManagementProtocol *AccountManagementProtocol) handleDisconnectAllPrincipals(packet nex.PacketInterface) {
+	if accountManagementProtocol.DisconnectAllPrincipalsHandler == nil {
+		log.Println("[Warning] AccountManagementProtocol::DisconnectAllPrincipals not implemented")
+		go respondNotImplemented(packet, AccountManagementProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+
+	go accountManagementProtocol.DisconnectAllPrincipalsHandler(nil, client, callID)
 }
 
 // NewAccountManagementProtocol returns a new AccountManagementProtocol
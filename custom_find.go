@@ -0,0 +1,52 @@
+package nexproto
+
+import (
+	"sort"
+)
+
+// CustomFindScorer rates how well a gathering matches the raw parameters of a CustomFind call.
+// Gatherings for which ok is false are left out, the rest are returned highest score first
+type CustomFindScorer func(data []byte, gathering *Gathering, participants []uint32) (score int, ok bool)
+
+// DefaultCustomFindScorer keeps every gathering which is not full, preferring the ones with the most participants.
+// It does not look at data, whose layout is not known
+func DefaultCustomFindScorer(data []byte, gathering *Gathering, participants []uint32) (int, bool) {
+	if gathering.MaxParticipants != 0 && len(participants) >= int(gathering.MaxParticipants) {
+		return 0, false
+	}
+
+	return len(participants), true
+}
+
+// CustomFind returns the gatherings registered as GatheringClassName which scorer accepts for the CustomFind
+// parameters data, best first. MatchmakeSessions and other classes are left out
+func (manager *GatheringManager) CustomFind(data []byte, scorer CustomFindScorer) []*Gathering {
+	type scoredGathering struct {
+		gathering *Gathering
+		score     int
+	}
+
+	gatherings := manager.filter(func(managed *managedGathering) bool {
+		return managed.gathering.className == manager.GatheringClassName
+	})
+
+	scored := make([]scoredGathering, 0, len(gatherings))
+
+	for _, gathering := range gatherings {
+		score, ok := scorer(data, gathering, manager.Participants(gathering.ID))
+		if ok {
+			scored = append(scored, scoredGathering{gathering, score})
+		}
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+
+	gatherings = make([]*Gathering, 0, len(scored))
+	for _, match := range scored {
+		gatherings = append(gatherings, match.gathering)
+	}
+
+	return gatherings
+}
@@ -0,0 +1,42 @@
+package nexproto
+
+import (
+	"testing"
+)
+
+func TestGatheringManagerCustomFind(t *testing.T) {
+	manager := NewGatheringManager()
+
+	full := NewGathering()
+	full.MaxParticipants = 1
+
+	fullID := manager.Register(1, full)
+	emptyID := manager.Register(2, NewGathering())
+	busyID := manager.Register(3, NewGathering())
+
+	manager.mutex.Lock()
+	manager.register(4, MatchmakeSessionClassName, NewGathering())
+	manager.mutex.Unlock()
+
+	for _, join := range []struct{ pid, gatheringID uint32 }{{1, fullID}, {3, busyID}, {5, busyID}} {
+		if err := manager.Participate(join.pid, join.gatheringID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found := manager.CustomFind([]byte{1, 2, 3}, DefaultCustomFindScorer)
+	if len(found) != 2 || found[0].ID != busyID || found[1].ID != emptyID {
+		t.Fatalf("CustomFind returned %v", found)
+	}
+
+	var passed []byte
+
+	manager.CustomFind([]byte{1, 2, 3}, func(data []byte, gathering *Gathering, participants []uint32) (int, bool) {
+		passed = data
+		return 0, false
+	})
+
+	if len(passed) != 3 {
+		t.Fatalf("scorer got parameters %v", passed)
+	}
+}
@@ -12,22 +12,30 @@ const (
 	CustomFind = 0x1
 )
 
-// JsonProtocol handles the Json requests
+// CustomMatchmakingProtocol handles the RB3 CustomMatchmaking nex protocol.
+// CustomFind parameters are passed on undecoded: their RB3 layout has not been established from captured
+// traffic or from the client, so matching them is left to the CustomFindHandler or CustomFindScorer
 type CustomMatchmakingProtocol struct {
 	server              *nex.Server
 	ConnectionIDCounter *nex.Counter
 	CustomFindHandler   func(err error, client *nex.Client, callID uint32, data []byte)
+	CustomFindScorer    CustomFindScorer
+	gatheringManager    *GatheringManager
 }
 
 func (customMatchmakingProtocol *CustomMatchmakingProtocol) Setup() {
 	nexServer := customMatchmakingProtocol.server
 
+	trackPacketRoutes(nexServer)
+
 	nexServer.On("Data", func(packet nex.PacketInterface) {
 		request := packet.RMCRequest()
 
 		if CustomMatchmakingProtocolID == request.ProtocolID() {
+			rememberPacketRoute(packet)
+
 			switch request.MethodID() {
-			case RegisterGathering:
+			case CustomFind:
 				go customMatchmakingProtocol.handleCustomFind(packet)
 			default:
 				log.Printf("Unsupported CustomMatchmaking method ID: %#v\n", request.MethodID())
@@ -36,14 +44,49 @@ func (customMatchmakingProtocol *CustomMatchmakingProtocol) Setup() {
 	})
 }
 
+// CustomFind sets the CustomFind handler function
 func (customMatchmakingProtocol *CustomMatchmakingProtocol) CustomFind(handler func(err error, client *nex.Client, callID uint32, data []byte)) {
 	customMatchmakingProtocol.CustomFindHandler = handler
 }
 
+// SetCustomFindScorer sets the function the default CustomFind handler ranks gatherings with
+func (customMatchmakingProtocol *CustomMatchmakingProtocol) SetCustomFindScorer(scorer CustomFindScorer) {
+	customMatchmakingProtocol.CustomFindScorer = scorer
+}
+
+// UseGatheringManager answers CustomFind with the gatherings of manager accepted by the CustomFindScorer,
+// every gathering which is not full by default
+func (customMatchmakingProtocol *CustomMatchmakingProtocol) UseGatheringManager(manager *GatheringManager) {
+	customMatchmakingProtocol.gatheringManager = manager
+
+	customMatchmakingProtocol.CustomFind(customMatchmakingProtocol.handleCustomFindWithGatheringManager)
+}
+
+func (customMatchmakingProtocol *CustomMatchmakingProtocol) handleCustomFindWithGatheringManager(err error, client *nex.Client, callID uint32, data []byte) {
+	if err != nil {
+		respondError(client, CustomMatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	manager := customMatchmakingProtocol.gatheringManager
+
+	scorer := customMatchmakingProtocol.CustomFindScorer
+	if scorer == nil {
+		scorer = DefaultCustomFindScorer
+	}
+
+	gatherings := manager.CustomFind(data, scorer)
+
+	responseStream := NewStreamOut(client.Server())
+	responseStream.WriteListGathering(manager.GatheringClassName, gatherings)
+
+	respondSuccess(client, CustomMatchmakingProtocolID, CustomFind, callID, responseStream.Bytes())
+}
+
 func (customMatchmakingProtocol *CustomMatchmakingProtocol) handleCustomFind(packet nex.PacketInterface) {
 	if customMatchmakingProtocol.CustomFindHandler == nil {
 		log.Println("[Warning] CustomMatchmakingProtocol::CustomFind not implemented")
-		go respondNotImplemented(packet, SecureProtocolID)
+		go respondNotImplemented(packet, CustomMatchmakingProtocolID)
 		return
 	}
 
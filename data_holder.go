@@ -0,0 +1,66 @@
+package nexproto
+
+import (
+	nex "github.com/jnackmclain/nex-go"
+)
+
+// DataHolder is an encoded structure of any class, as carried by AnyDataHolder parameters
+type DataHolder struct {
+	ClassName string
+	Data      []byte
+
+	nex.Structure
+}
+
+// Bytes encodes the DataHolder and returns a byte array
+func (dataHolder *DataHolder) Bytes(stream *nex.StreamOut) []byte {
+	write4ByteString(stream, dataHolder.ClassName)
+	stream.WriteUInt32LE(uint32(len(dataHolder.Data) + 4))
+	stream.WriteBuffer(dataHolder.Data)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a DataHolder structure from a stream
+func (dataHolder *DataHolder) ExtractFromStream(stream *nex.StreamIn) error {
+	className, data, err := (&StreamIn{StreamIn: stream}).ReadDataHolder()
+	if err != nil {
+		return err
+	}
+
+	dataHolder.ClassName = className
+	dataHolder.Data = data
+
+	return nil
+}
+
+// Copy returns a deep copy of the DataHolder
+func (dataHolder *DataHolder) Copy() *DataHolder {
+	return &DataHolder{
+		ClassName: dataHolder.ClassName,
+		Data:      append([]byte(nil), dataHolder.Data...),
+	}
+}
+
+// ExtractStructure decodes the data of the DataHolder into structure
+func (dataHolder *DataHolder) ExtractStructure(structure nex.StructureInterface, server *nex.Server) error {
+	_, err := nex.NewStreamIn(dataHolder.Data, server).ReadStructure(structure)
+
+	return err
+}
+
+// NewStructureDataHolder returns a DataHolder of class className wrapping structure,
+// such as the public and private custom data of an account
+func NewStructureDataHolder(className string, structure nex.StructureInterface, server *nex.Server) *DataHolder {
+	return &DataHolder{
+		ClassName: className,
+		Data:      structure.Bytes(nex.NewStreamOut(server)),
+	}
+}
+
+// NewDataHolder returns a new DataHolder
+func NewDataHolder() *DataHolder {
+	return &DataHolder{
+		Data: make([]byte, 0),
+	}
+}
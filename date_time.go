@@ -0,0 +1,46 @@
+package nexproto
+
+import (
+	"time"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+// newDateTime returns t as a nex.DateTime, packed as second | minute << 6 | hour << 12 | day << 17 | month << 22 | year << 26.
+// The zero time gives the zero DateTime
+func newDateTime(t time.Time) *nex.DateTime {
+	if t.IsZero() {
+		return nex.NewDateTime(0)
+	}
+
+	t = t.UTC()
+
+	value := uint64(t.Second()) |
+		uint64(t.Minute())<<6 |
+		uint64(t.Hour())<<12 |
+		uint64(t.Day())<<17 |
+		uint64(t.Month())<<22 |
+		uint64(t.Year())<<26
+
+	return nex.NewDateTime(value)
+}
+
+// dateTimeTime returns dateTime as a UTC time.Time. The zero DateTime gives the zero time
+func dateTimeTime(dateTime *nex.DateTime) time.Time {
+	if dateTime == nil || dateTime.Value() == 0 {
+		return time.Time{}
+	}
+
+	value := dateTime.Value()
+
+	return time.Date(
+		int(value>>26),
+		time.Month((value>>22)&0xF),
+		int((value>>17)&0x1F),
+		int((value>>12)&0x1F),
+		int((value>>6)&0x3F),
+		int(value&0x3F),
+		0,
+		time.UTC,
+	)
+}
@@ -75,6 +75,7 @@ const (
 // FriendsProtocol handles the Friends (WiiU) nex protocol
 type FriendsProtocol struct {
 	server                              *nex.Server
+	moderator                           *Moderator
 	UpdateAndGetAllInformationHandler   func(err error, client *nex.Client, callID uint32, nnaInfo *NNAInfo, presence *NintendoPresenceV2, birthday *nex.DateTime)
 	AddFriendHandler                    func(err error, client *nex.Client, callID uint32, pid uint32)
 	AddFriendByNameHandler              func(err error, client *nex.Client, callID uint32, username string)
@@ -633,10 +634,14 @@ func NewPrincipalRequestBlockSetting() *PrincipalRequestBlockSetting {
 func (friendsProtocol *FriendsProtocol) Setup() {
 	nexServer := friendsProtocol.server
 
+	trackPacketRoutes(nexServer)
+
 	nexServer.On("Data", func(packet nex.PacketInterface) {
 		request := packet.RMCRequest()
 
 		if FriendsProtocolID == request.ProtocolID() {
+			rememberPacketRoute(packet)
+
 			switch request.MethodID() {
 			case FriendsMethodUpdateAndGetAllInformation:
 				go friendsProtocol.handleUpdateAndGetAllInformation(packet)
@@ -760,6 +765,11 @@ func (friendsProtocol *FriendsProtocol) UpdateComment(handler func(err error, cl
 	friendsProtocol.UpdateCommentHandler = handler
 }
 
+// UseModerator runs comments and presence messages through moderator before they reach their handlers
+func (friendsProtocol *FriendsProtocol) UseModerator(moderator *Moderator) {
+	friendsProtocol.moderator = moderator
+}
+
 // UpdatePreference sets the UpdatePreference handler function
 func (friendsProtocol *FriendsProtocol) UpdatePreference(handler func(err error, client *nex.Client, callID uint32, preference *PrincipalPreference)) {
 	friendsProtocol.UpdatePreferenceHandler = handler
@@ -816,6 +826,10 @@ func (friendsProtocol *FriendsProtocol) handleUpdateAndGetAllInformation(packet
 	presence := presenceStructureInterface.(*NintendoPresenceV2)
 	birthday := nex.NewDateTime(parametersStream.ReadUInt64LE())
 
+	if !friendsProtocol.moderatePresence(client, callID, presence) {
+		return
+	}
+
 	go friendsProtocol.UpdateAndGetAllInformationHandler(nil, client, callID, nnaInfo, presence, birthday)
 }
 
@@ -1159,6 +1173,10 @@ func (friendsProtocol *FriendsProtocol) handleUpdatePresence(packet nex.PacketIn
 
 	nintendoPresenceV2 := nintendoPresenceV2StructureInterface.(*NintendoPresenceV2)
 
+	if !friendsProtocol.moderatePresence(client, callID, nintendoPresenceV2) {
+		return
+	}
+
 	go friendsProtocol.UpdatePresenceHandler(nil, client, callID, nintendoPresenceV2)
 }
 
@@ -1211,9 +1229,37 @@ func (friendsProtocol *FriendsProtocol) handleUpdateComment(packet nex.PacketInt
 
 	comment := commentStructureInterface.(*Comment)
 
+	if friendsProtocol.moderator != nil {
+		contents, err := friendsProtocol.moderator.Check(client.PID(), ContentSourceComment, comment.Contents)
+		if err != nil {
+			go respondError(client, FriendsProtocolID, callID, ResultCoreAccessDenied)
+			return
+		}
+
+		comment.Contents = contents
+	}
+
 	go friendsProtocol.UpdateCommentHandler(nil, client, callID, comment)
 }
 
+// moderatePresence runs the message of a presence through the moderator, if one is set. A refused
+// message is answered with an error and false is returned
+func (friendsProtocol *FriendsProtocol) moderatePresence(client *nex.Client, callID uint32, presence *NintendoPresenceV2) bool {
+	if friendsProtocol.moderator == nil {
+		return true
+	}
+
+	message, err := friendsProtocol.moderator.Check(client.PID(), ContentSourcePresence, presence.Message)
+	if err != nil {
+		go respondError(client, FriendsProtocolID, callID, ResultCoreAccessDenied)
+		return false
+	}
+
+	presence.Message = message
+
+	return true
+}
+
 func (friendsProtocol *FriendsProtocol) handleUpdatePreference(packet nex.PacketInterface) {
 	if friendsProtocol.UpdatePreferenceHandler == nil {
 		log.Println("[Warning] FriendsProtocol::UpdatePreference not implemented")
@@ -0,0 +1,119 @@
+package nexproto
+
+import (
+	"time"
+)
+
+// Touch refreshes the lifetime of a gathering which has not expired yet, as UpdateGathering and SetState do
+func (manager *GatheringManager) Touch(gatheringID uint32) error {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	managed, ok := manager.gatherings[gatheringID]
+	if !ok || manager.expired(managed, manager.clock()) {
+		return ErrGatheringNotFound
+	}
+
+	managed.refreshedAt = manager.clock()
+
+	return nil
+}
+
+// SetLifetime overrides GatheringLifetime for a single gathering. A lifetime of 0 restores the default
+func (manager *GatheringManager) SetLifetime(gatheringID uint32, lifetime time.Duration) error {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	managed, ok := manager.gatherings[gatheringID]
+	if !ok {
+		return ErrGatheringNotFound
+	}
+
+	managed.lifetime = lifetime
+
+	return nil
+}
+
+// Reap terminates every gathering which has not been refreshed within its lifetime and returns them
+func (manager *GatheringManager) Reap() []*Gathering {
+	manager.mutex.Lock()
+
+	reaped := make([]*Gathering, 0)
+	events := make([]func(), 0)
+	now := manager.clock()
+
+	for _, managed := range manager.gatherings {
+		if !manager.expired(managed, now) {
+			continue
+		}
+
+		gathering := managed.gathering.Copy()
+		participants := append([]uint32(nil), managed.participants...)
+
+		reaped = append(reaped, gathering)
+		events = append(events, manager.terminate(managed))
+
+		if manager.GatheringReapedHandler != nil {
+			events = append(events, func() {
+				manager.GatheringReapedHandler(gathering, participants)
+			})
+		}
+	}
+
+	manager.mutex.Unlock()
+
+	fireEvents(events)
+
+	return reaped
+}
+
+// StartReaper calls Reap every interval in the background until StopReaper is called
+func (manager *GatheringManager) StartReaper(interval time.Duration) {
+	manager.StopReaper()
+
+	stop := make(chan struct{})
+
+	manager.mutex.Lock()
+	manager.stopReaper = stop
+	manager.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				manager.Reap()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopReaper stops the background reaper started by StartReaper
+func (manager *GatheringManager) StopReaper() {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	if manager.stopReaper != nil {
+		close(manager.stopReaper)
+		manager.stopReaper = nil
+	}
+}
+
+// OnGatheringReaped sets the function called for each gathering removed by Reap, with the participants it had
+func (manager *GatheringManager) OnGatheringReaped(handler func(gathering *Gathering, participants []uint32)) {
+	manager.GatheringReapedHandler = handler
+}
+
+// expired returns whether a gathering has outlived its lifetime at now. The caller must hold the lock
+func (manager *GatheringManager) expired(managed *managedGathering, now time.Time) bool {
+	lifetime := manager.GatheringLifetime
+	if managed.lifetime != 0 {
+		lifetime = managed.lifetime
+	}
+
+	return lifetime != 0 && now.Sub(managed.refreshedAt) > lifetime
+}
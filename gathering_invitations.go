@@ -0,0 +1,317 @@
+package nexproto
+
+import (
+	"sort"
+	"time"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+// pendingInvitation is an invitation waiting for its guest to answer
+type pendingInvitation struct {
+	invitation *Invitation
+	inviterPID uint32
+	sentAt     time.Time
+}
+
+// Invite records invitations to a gathering for every PID in guestPIDs not already participating.
+// The inviter must own or participate in the gathering. Re-inviting a guest refreshes its invitation
+func (manager *GatheringManager) Invite(inviterPID uint32, gatheringID uint32, guestPIDs []uint32, message string) error {
+	manager.mutex.Lock()
+
+	managed, ok := manager.gatherings[gatheringID]
+	if !ok {
+		manager.mutex.Unlock()
+		return ErrGatheringNotFound
+	}
+
+	if managed.gathering.OwnerPID != inviterPID && indexOfPID(managed.participants, inviterPID) == -1 {
+		manager.mutex.Unlock()
+		return ErrNotParticipating
+	}
+
+	sent := make([]*Invitation, 0, len(guestPIDs))
+	now := manager.clock()
+
+	for _, guestPID := range guestPIDs {
+		if indexOfPID(managed.participants, guestPID) != -1 {
+			continue
+		}
+
+		invitation := &Invitation{
+			GatheringID: gatheringID,
+			GuestPID:    guestPID,
+			Message:     message,
+		}
+
+		managed.invitations[guestPID] = &pendingInvitation{
+			invitation: invitation,
+			inviterPID: inviterPID,
+			sentAt:     now,
+		}
+
+		sent = append(sent, invitation.Copy())
+	}
+
+	manager.mutex.Unlock()
+
+	if manager.InvitationSentHandler != nil {
+		for _, invitation := range sent {
+			manager.InvitationSentHandler(invitation, inviterPID)
+		}
+	}
+
+	return nil
+}
+
+// AcceptInvitation makes guestPID participate in a gathering it has a pending invitation to, consuming the invitation.
+// The invitation is kept if the guest cannot join, such as when the gathering is full
+func (manager *GatheringManager) AcceptInvitation(guestPID uint32, gatheringID uint32) error {
+	manager.mutex.Lock()
+
+	managed, err := manager.invitedGathering(guestPID, gatheringID)
+	if err != nil {
+		manager.mutex.Unlock()
+		return err
+	}
+
+	event, err := manager.join(managed, guestPID)
+	if err != nil {
+		manager.mutex.Unlock()
+		return err
+	}
+
+	delete(managed.invitations, guestPID)
+
+	manager.mutex.Unlock()
+
+	fireEvents([]func(){event})
+
+	return nil
+}
+
+// DeclineInvitation discards the pending invitation of guestPID to a gathering
+func (manager *GatheringManager) DeclineInvitation(guestPID uint32, gatheringID uint32) error {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	managed, err := manager.invitedGathering(guestPID, gatheringID)
+	if err != nil {
+		return err
+	}
+
+	delete(managed.invitations, guestPID)
+
+	return nil
+}
+
+// InvitedGathering returns a copy of a gathering guestPID has a pending invitation to, leaving the invitation pending
+func (manager *GatheringManager) InvitedGathering(guestPID uint32, gatheringID uint32) (*Gathering, error) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	managed, err := manager.invitedGathering(guestPID, gatheringID)
+	if err != nil {
+		return nil, err
+	}
+
+	return managed.gathering.Copy(), nil
+}
+
+// CancelInvitations withdraws the invitations of guestPIDs to a gathering.
+// Only the gathering owner or the PID which sent an invitation may cancel it
+func (manager *GatheringManager) CancelInvitations(pid uint32, gatheringID uint32, guestPIDs []uint32) error {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	managed, ok := manager.gatherings[gatheringID]
+	if !ok {
+		return ErrGatheringNotFound
+	}
+
+	for _, guestPID := range guestPIDs {
+		pending, ok := managed.invitations[guestPID]
+		if !ok {
+			continue
+		}
+
+		if pending.inviterPID != pid && managed.gathering.OwnerPID != pid {
+			return ErrGatheringNotOwner
+		}
+
+		delete(managed.invitations, guestPID)
+	}
+
+	return nil
+}
+
+// InvitationsSent returns the pending invitations to a gathering, ordered by guest PID
+func (manager *GatheringManager) InvitationsSent(gatheringID uint32) []*Invitation {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	invitations := make([]*Invitation, 0)
+
+	managed, ok := manager.gatherings[gatheringID]
+	if !ok {
+		return invitations
+	}
+
+	manager.pruneInvitations(managed)
+
+	for _, pending := range managed.invitations {
+		invitations = append(invitations, pending.invitation.Copy())
+	}
+
+	sort.Slice(invitations, func(i, j int) bool {
+		return invitations[i].GuestPID < invitations[j].GuestPID
+	})
+
+	return invitations
+}
+
+// InvitationsReceived returns the pending invitations of guestPID, ordered by gathering ID
+func (manager *GatheringManager) InvitationsReceived(guestPID uint32) []*Invitation {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	invitations := make([]*Invitation, 0)
+
+	for _, managed := range manager.gatherings {
+		manager.pruneInvitations(managed)
+
+		if pending, ok := managed.invitations[guestPID]; ok {
+			invitations = append(invitations, pending.invitation.Copy())
+		}
+	}
+
+	sort.Slice(invitations, func(i, j int) bool {
+		return invitations[i].GatheringID < invitations[j].GatheringID
+	})
+
+	return invitations
+}
+
+// HandleInvite is the default MatchmakingProtocol::Invite handler, FindBySingleID in the Quazal SDK. It answers with
+// any live gathering other than a MatchmakeSession, so a friend's gathering can be looked up as well as one the
+// caller was invited to. Invitations are only checked by AcceptInvitation
+func (manager *GatheringManager) HandleInvite(err error, client *nex.Client, callID uint32, gatheringID uint32) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	gathering := manager.findableGathering(gatheringID)
+
+	responseStream := NewStreamOut(client.Server())
+
+	if gathering == nil {
+		responseStream.WriteBool(false)
+		responseStream.WriteDataHolder(manager.GatheringClassName, NewGathering())
+	} else {
+		responseStream.WriteBool(true)
+		responseStream.WriteDataHolder(gathering.dataHolderClassName(manager.GatheringClassName), gathering)
+	}
+
+	respondSuccess(client, MatchmakingProtocolID, Invite, callID, responseStream.Bytes())
+}
+
+// HandleSendInvitation is the default MatchmakingProtocol::SendInvitation handler
+func (manager *GatheringManager) HandleSendInvitation(err error, client *nex.Client, callID uint32, gatheringID uint32, pids []uint32, message string) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	manager.respondResult(client, callID, SendInvitation, manager.Invite(client.PID(), gatheringID, pids, message))
+}
+
+// HandleAcceptInvitation is the default MatchmakingProtocol::AcceptInvitation handler
+func (manager *GatheringManager) HandleAcceptInvitation(err error, client *nex.Client, callID uint32, gatheringID uint32, message string) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	manager.respondResult(client, callID, AcceptInvitation, manager.AcceptInvitation(client.PID(), gatheringID))
+}
+
+// HandleDeclineInvitation is the default MatchmakingProtocol::DeclineInvitation handler
+func (manager *GatheringManager) HandleDeclineInvitation(err error, client *nex.Client, callID uint32, gatheringID uint32, message string) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	manager.respondResult(client, callID, DeclineInvitation, manager.DeclineInvitation(client.PID(), gatheringID))
+}
+
+// HandleCancelInvitation is the default MatchmakingProtocol::CancelInvitation handler
+func (manager *GatheringManager) HandleCancelInvitation(err error, client *nex.Client, callID uint32, gatheringID uint32, pids []uint32, message string) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	manager.respondResult(client, callID, CancelInvitation, manager.CancelInvitations(client.PID(), gatheringID, pids))
+}
+
+// HandleGetInvitationsSent is the default MatchmakingProtocol::GetInvitationsSent handler
+func (manager *GatheringManager) HandleGetInvitationsSent(err error, client *nex.Client, callID uint32, gatheringID uint32) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	responseStream := NewStreamOut(client.Server())
+	responseStream.WriteListInvitation(manager.InvitationsSent(gatheringID))
+
+	respondSuccess(client, MatchmakingProtocolID, GetInvitationsSent, callID, responseStream.Bytes())
+}
+
+// HandleGetInvitationsReceived is the default MatchmakingProtocol::GetInvitationsReceived handler
+func (manager *GatheringManager) HandleGetInvitationsReceived(err error, client *nex.Client, callID uint32) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	responseStream := NewStreamOut(client.Server())
+	responseStream.WriteListInvitation(manager.InvitationsReceived(client.PID()))
+
+	respondSuccess(client, MatchmakingProtocolID, GetInvitationsReceived, callID, responseStream.Bytes())
+}
+
+// invitedGathering returns the gathering if guestPID has a live invitation to it. The caller must hold the write lock
+func (manager *GatheringManager) invitedGathering(guestPID uint32, gatheringID uint32) (*managedGathering, error) {
+	managed, ok := manager.gatherings[gatheringID]
+	if !ok || manager.expired(managed, manager.clock()) {
+		return nil, ErrGatheringNotFound
+	}
+
+	manager.pruneInvitations(managed)
+
+	if _, ok := managed.invitations[guestPID]; !ok {
+		return nil, ErrInvitationNotFound
+	}
+
+	return managed, nil
+}
+
+// pruneInvitations removes the expired invitations of a gathering. The caller must hold the lock
+func (manager *GatheringManager) pruneInvitations(managed *managedGathering) {
+	if manager.InvitationLifetime == 0 {
+		return
+	}
+
+	for guestPID, pending := range managed.invitations {
+		if manager.clock().Sub(pending.sentAt) > manager.InvitationLifetime {
+			delete(managed.invitations, guestPID)
+		}
+	}
+}
+
+// OnInvitationSent sets the function called for each invitation recorded by Invite
+func (manager *GatheringManager) OnInvitationSent(handler func(invitation *Invitation, inviterPID uint32)) {
+	manager.InvitationSentHandler = handler
+}
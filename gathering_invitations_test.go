@@ -0,0 +1,80 @@
+package nexproto
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGatheringManagerInvitations(t *testing.T) {
+	manager := NewGatheringManager()
+
+	var sent int
+
+	manager.OnInvitationSent(func(invitation *Invitation, inviterPID uint32) {
+		sent++
+	})
+
+	gatheringID := manager.Register(1, NewGathering())
+
+	if err := manager.Invite(2, gatheringID, []uint32{3}, "join us"); err != ErrNotParticipating {
+		t.Fatalf("invitation by an outsider returned %v", err)
+	}
+
+	if err := manager.Invite(1, gatheringID, []uint32{3, 4}, "join us"); err != nil || sent != 2 {
+		t.Fatalf("Invite returned %v after %d invitations", err, sent)
+	}
+
+	if received := manager.InvitationsReceived(3); len(received) != 1 || received[0].Message != "join us" {
+		t.Fatalf("InvitationsReceived returned %v", received)
+	}
+
+	if err := manager.AcceptInvitation(5, gatheringID); err != ErrInvitationNotFound {
+		t.Fatalf("accepting without an invitation returned %v", err)
+	}
+
+	if err := manager.AcceptInvitation(3, gatheringID); err != nil {
+		t.Fatal(err)
+	}
+
+	if participants := manager.Participants(gatheringID); len(participants) != 1 || participants[0] != 3 {
+		t.Fatalf("participants after accepting are %v", participants)
+	}
+
+	if err := manager.AcceptInvitation(3, gatheringID); err != ErrInvitationNotFound {
+		t.Fatalf("accepting twice returned %v", err)
+	}
+
+	if err := manager.CancelInvitations(9, gatheringID, []uint32{4}); err != ErrGatheringNotOwner {
+		t.Fatalf("cancelling by an outsider returned %v", err)
+	}
+
+	if err := manager.DeclineInvitation(4, gatheringID); err != nil {
+		t.Fatal(err)
+	}
+
+	if pending := manager.InvitationsSent(gatheringID); len(pending) != 0 {
+		t.Fatalf("invitations left after declining: %v", pending)
+	}
+}
+
+func TestGatheringManagerInvitationExpiry(t *testing.T) {
+	manager := NewGatheringManager()
+	manager.InvitationLifetime = time.Minute
+
+	now := time.Now()
+	manager.clock = func() time.Time {
+		return now
+	}
+
+	gatheringID := manager.Register(1, NewGathering())
+
+	if err := manager.Invite(1, gatheringID, []uint32{2}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(2 * time.Minute)
+
+	if err := manager.AcceptInvitation(2, gatheringID); err != ErrInvitationNotFound {
+		t.Fatalf("accepting an expired invitation returned %v", err)
+	}
+}
@@ -0,0 +1,801 @@
+package nexproto
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+var (
+	// ErrGatheringNotFound is returned when a gathering ID is not registered
+	ErrGatheringNotFound = errors.New("[GatheringManager] Gathering not found")
+
+	// ErrGatheringNotOwner is returned when the caller does not own the gathering
+	ErrGatheringNotOwner = errors.New("[GatheringManager] Caller does not own the gathering")
+
+	// ErrGatheringFull is returned when a gathering has reached its maximum participants
+	ErrGatheringFull = errors.New("[GatheringManager] Gathering is full")
+
+	// ErrAlreadyParticipating is returned when the caller already participates in the gathering
+	ErrAlreadyParticipating = errors.New("[GatheringManager] Already participating in the gathering")
+
+	// ErrNotParticipating is returned when the caller does not participate in the gathering
+	ErrNotParticipating = errors.New("[GatheringManager] Not participating in the gathering")
+
+	// ErrInvitationNotFound is returned when the caller has no pending invitation to the gathering
+	ErrInvitationNotFound = errors.New("[GatheringManager] Invitation not found")
+)
+
+// managedGathering is the server side state kept for a registered gathering
+type managedGathering struct {
+	gathering    *Gathering
+	participants []uint32
+	invitations  map[uint32]*pendingInvitation // keyed by guest PID
+	launched     bool
+	refreshedAt  time.Time
+	lifetime     time.Duration // overrides GatheringLifetime when not 0
+	sessionKey   []byte        // key of a MatchmakeSession, kept out of the serialized gathering
+}
+
+// GatheringManager keeps track of the gatherings registered through MatchmakingProtocol
+type GatheringManager struct {
+	mutex                      sync.RWMutex
+	gatherings                 map[uint32]*managedGathering
+	clients                    map[uint32]*nex.Client // latest client of each PID, so drops of stale connections are ignored
+	lastGatheringID            uint32
+	GatheringClassName         string // data holder class name gatherings are registered and returned as by Register
+	StateChangeHandler         func(gathering *Gathering, oldState uint32, newState uint32)
+	ParticipantJoinedHandler   func(gathering *Gathering, pid uint32, participants []uint32)
+	ParticipantLeftHandler     func(gathering *Gathering, pid uint32, disconnected bool, participants []uint32)
+	HostMigrationHandler       func(gathering *Gathering, oldHostPID uint32, participants []uint32)
+	OwnershipChangedHandler    func(gathering *Gathering, oldOwnerPID uint32, participants []uint32)
+	GatheringTerminatedHandler func(gathering *Gathering, participants []uint32)
+	InvitationLifetime         time.Duration // how long an invitation can be accepted for, 0 for no expiry
+	InvitationSentHandler      func(invitation *Invitation, inviterPID uint32)
+	GatheringLifetime          time.Duration // how long a gathering lives without being refreshed, 0 for no expiry
+	GatheringReapedHandler     func(gathering *Gathering, participants []uint32)
+	stopReaper                 chan struct{}
+	clock                      func() time.Time // current time of lifetimes and invitations, replaced by tests
+}
+
+// OnStateChange sets the function called after a gathering changes state
+func (manager *GatheringManager) OnStateChange(handler func(gathering *Gathering, oldState uint32, newState uint32)) {
+	manager.StateChangeHandler = handler
+}
+
+// OnParticipantJoined sets the function called after a PID joins a gathering, with the participants including it
+func (manager *GatheringManager) OnParticipantJoined(handler func(gathering *Gathering, pid uint32, participants []uint32)) {
+	manager.ParticipantJoinedHandler = handler
+}
+
+// OnParticipantLeft sets the function called after a PID leaves or is dropped from a gathering, with the remaining participants
+func (manager *GatheringManager) OnParticipantLeft(handler func(gathering *Gathering, pid uint32, disconnected bool, participants []uint32)) {
+	manager.ParticipantLeftHandler = handler
+}
+
+// OnHostMigration sets the function called after a gathering is given a new host, with its participants
+func (manager *GatheringManager) OnHostMigration(handler func(gathering *Gathering, oldHostPID uint32, participants []uint32)) {
+	manager.HostMigrationHandler = handler
+}
+
+// OnOwnershipChanged sets the function called after a gathering is given a new owner, with its participants
+func (manager *GatheringManager) OnOwnershipChanged(handler func(gathering *Gathering, oldOwnerPID uint32, participants []uint32)) {
+	manager.OwnershipChangedHandler = handler
+}
+
+// OnGatheringTerminated sets the function called after a gathering is removed, with the participants it had
+func (manager *GatheringManager) OnGatheringTerminated(handler func(gathering *Gathering, participants []uint32)) {
+	manager.GatheringTerminatedHandler = handler
+}
+
+// Register stores gathering under a newly allocated ID owned and hosted by ownerPID and returns the ID.
+// The gathering is returned as a data holder of the GatheringClassName it was registered with
+func (manager *GatheringManager) Register(ownerPID uint32, gathering *Gathering) uint32 {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	return manager.register(ownerPID, manager.GatheringClassName, gathering).gathering.ID
+}
+
+// Update replaces the gathering with the same ID as gathering, keeping its ID, owner and host
+func (manager *GatheringManager) Update(pid uint32, gathering *Gathering) error {
+	manager.mutex.Lock()
+
+	managed, err := manager.ownedGathering(pid, gathering.ID)
+	if err != nil {
+		manager.mutex.Unlock()
+		return err
+	}
+
+	oldState := managed.gathering.State
+
+	updated := gathering.Copy()
+	updated.OwnerPID = managed.gathering.OwnerPID
+	updated.HostPID = managed.gathering.HostPID
+	updated.className = managed.gathering.className
+	managed.gathering = updated
+	managed.refreshedAt = manager.clock()
+
+	changed := updated.Copy()
+
+	manager.mutex.Unlock()
+
+	manager.stateChanged(changed, oldState)
+
+	return nil
+}
+
+// Participate adds pid to the participants of a gathering which has not expired
+func (manager *GatheringManager) Participate(pid uint32, gatheringID uint32) error {
+	manager.mutex.Lock()
+
+	managed, ok := manager.gatherings[gatheringID]
+	if !ok || manager.expired(managed, manager.clock()) {
+		manager.mutex.Unlock()
+		return ErrGatheringNotFound
+	}
+
+	event, err := manager.join(managed, pid)
+
+	manager.mutex.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	fireEvents([]func(){event})
+
+	return nil
+}
+
+// Unparticipate removes pid from the participants of a gathering, migrating the host if pid was hosting
+func (manager *GatheringManager) Unparticipate(pid uint32, gatheringID uint32) error {
+	manager.mutex.Lock()
+
+	managed, ok := manager.gatherings[gatheringID]
+	if !ok {
+		manager.mutex.Unlock()
+		return ErrGatheringNotFound
+	}
+
+	if indexOfPID(managed.participants, pid) == -1 {
+		manager.mutex.Unlock()
+		return ErrNotParticipating
+	}
+
+	events := manager.leave(managed, pid, false)
+
+	manager.mutex.Unlock()
+
+	fireEvents(events)
+
+	return nil
+}
+
+// DropParticipant removes the PID of client from every gathering it participates in or owns, as when client disconnects.
+// Gatherings it was hosting are migrated to the longest standing remaining participant, and gatherings it owned
+// are terminated if no participant is left. Nothing is dropped if the PID has reconnected through another client
+func (manager *GatheringManager) DropParticipant(client *nex.Client) {
+	pid := client.PID()
+	if pid == 0 {
+		return
+	}
+
+	manager.mutex.Lock()
+
+	if current, ok := manager.clients[pid]; ok && current != client {
+		manager.mutex.Unlock()
+		return
+	}
+
+	delete(manager.clients, pid)
+
+	events := make([]func(), 0)
+
+	for _, managed := range manager.gatherings {
+		if indexOfPID(managed.participants, pid) != -1 || managed.gathering.OwnerPID == pid || managed.gathering.HostPID == pid {
+			events = append(events, manager.leave(managed, pid, true)...)
+		}
+	}
+
+	manager.mutex.Unlock()
+
+	fireEvents(events)
+}
+
+// connect records client as the current client of its PID
+func (manager *GatheringManager) connect(client *nex.Client) {
+	pid := client.PID()
+	if pid == 0 {
+		return
+	}
+
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	manager.clients[pid] = client
+}
+
+// LaunchSession marks a gathering as launched
+func (manager *GatheringManager) LaunchSession(pid uint32, gatheringID uint32) error {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	managed, err := manager.ownedGathering(pid, gatheringID)
+	if err != nil {
+		return err
+	}
+
+	managed.launched = true
+
+	return nil
+}
+
+// Terminate removes a gathering
+func (manager *GatheringManager) Terminate(pid uint32, gatheringID uint32) error {
+	manager.mutex.Lock()
+
+	managed, err := manager.ownedGathering(pid, gatheringID)
+	if err != nil {
+		manager.mutex.Unlock()
+		return err
+	}
+
+	event := manager.terminate(managed)
+
+	manager.mutex.Unlock()
+
+	fireEvents([]func(){event})
+
+	return nil
+}
+
+// SetState changes the state of a gathering
+func (manager *GatheringManager) SetState(pid uint32, gatheringID uint32, state uint32) error {
+	manager.mutex.Lock()
+
+	managed, err := manager.ownedGathering(pid, gatheringID)
+	if err != nil {
+		manager.mutex.Unlock()
+		return err
+	}
+
+	oldState := managed.gathering.State
+	managed.gathering.State = state
+	managed.refreshedAt = manager.clock()
+
+	changed := managed.gathering.Copy()
+
+	manager.mutex.Unlock()
+
+	manager.stateChanged(changed, oldState)
+
+	return nil
+}
+
+// Gathering returns a copy of a registered gathering, or nil if it does not exist or has expired
+func (manager *GatheringManager) Gathering(gatheringID uint32) *Gathering {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+
+	managed, ok := manager.gatherings[gatheringID]
+	if !ok || manager.expired(managed, manager.clock()) {
+		return nil
+	}
+
+	return managed.gathering.Copy()
+}
+
+// Gatherings returns copies of every registered gathering other than MatchmakeSessions, ordered by ID
+func (manager *GatheringManager) Gatherings() []*Gathering {
+	return manager.filter(func(managed *managedGathering) bool {
+		return managed.findable()
+	})
+}
+
+// FindByType returns the gatherings within resultRange registered with the class name gatheringType
+func (manager *GatheringManager) FindByType(gatheringType string, resultRange *ResultRange) []*Gathering {
+	gatherings := manager.filter(func(managed *managedGathering) bool {
+		return managed.gathering.className == gatheringType
+	})
+
+	return pageGatherings(gatherings, resultRange)
+}
+
+// FindByDescription returns the gatherings other than MatchmakeSessions within resultRange with the given description
+func (manager *GatheringManager) FindByDescription(description string, resultRange *ResultRange) []*Gathering {
+	gatherings := manager.filter(func(managed *managedGathering) bool {
+		return managed.findable() && managed.gathering.Description == description
+	})
+
+	return pageGatherings(gatherings, resultRange)
+}
+
+// FindByID returns the gatherings with the given IDs, in the order requested. Unknown IDs and MatchmakeSessions are skipped
+func (manager *GatheringManager) FindByID(gatheringIDs []uint32) []*Gathering {
+	gatherings := make([]*Gathering, 0, len(gatheringIDs))
+
+	for _, gatheringID := range gatheringIDs {
+		gathering := manager.findableGathering(gatheringID)
+		if gathering != nil {
+			gatherings = append(gatherings, gathering)
+		}
+	}
+
+	return gatherings
+}
+
+// FindByOwner returns the gatherings other than MatchmakeSessions within resultRange owned by ownerPID
+func (manager *GatheringManager) FindByOwner(ownerPID uint32, resultRange *ResultRange) []*Gathering {
+	gatherings := manager.filter(func(managed *managedGathering) bool {
+		return managed.findable() && managed.gathering.OwnerPID == ownerPID
+	})
+
+	return pageGatherings(gatherings, resultRange)
+}
+
+// FindByParticipants returns the gatherings other than MatchmakeSessions any of pids participates in
+func (manager *GatheringManager) FindByParticipants(pids []uint32) []*Gathering {
+	return manager.filter(func(managed *managedGathering) bool {
+		if !managed.findable() {
+			return false
+		}
+
+		for _, pid := range pids {
+			if indexOfPID(managed.participants, pid) != -1 {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// Participants returns the PIDs participating in a gathering
+func (manager *GatheringManager) Participants(gatheringID uint32) []uint32 {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+
+	managed, ok := manager.gatherings[gatheringID]
+	if !ok {
+		return nil
+	}
+
+	return append([]uint32(nil), managed.participants...)
+}
+
+// IsLaunched returns whether LaunchSession has been called for a gathering
+func (manager *GatheringManager) IsLaunched(gatheringID uint32) bool {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+
+	managed, ok := manager.gatherings[gatheringID]
+
+	return ok && managed.launched
+}
+
+// HandleRegisterGathering is the default MatchmakingProtocol::RegisterGathering handler
+func (manager *GatheringManager) HandleRegisterGathering(err error, client *nex.Client, callID uint32, gatheringData []byte) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	gathering, err := decodeGathering(gatheringData, client.Server())
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	gatheringID := manager.Register(client.PID(), gathering)
+
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt32LE(gatheringID)
+
+	respondSuccess(client, MatchmakingProtocolID, RegisterGathering, callID, responseStream.Bytes())
+}
+
+// HandleUpdateGathering is the default MatchmakingProtocol::UpdateGathering handler
+func (manager *GatheringManager) HandleUpdateGathering(err error, client *nex.Client, callID uint32, gatheringData []byte, gatheringID uint32) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	gathering, err := decodeGathering(gatheringData, client.Server())
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	gathering.ID = gatheringID
+
+	manager.respondResult(client, callID, UpdateGathering, manager.Update(client.PID(), gathering))
+}
+
+// HandleParticipate is the default MatchmakingProtocol::Participate handler
+func (manager *GatheringManager) HandleParticipate(err error, client *nex.Client, callID uint32, gatheringID uint32) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	manager.respondResult(client, callID, Participate, manager.Participate(client.PID(), gatheringID))
+}
+
+// HandleUnparticipate is the default MatchmakingProtocol::Unparticipate handler
+func (manager *GatheringManager) HandleUnparticipate(err error, client *nex.Client, callID uint32, gatheringID uint32) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	manager.respondResult(client, callID, Unparticipate, manager.Unparticipate(client.PID(), gatheringID))
+}
+
+// HandleLaunchSession is the default MatchmakingProtocol::LaunchSession handler
+func (manager *GatheringManager) HandleLaunchSession(err error, client *nex.Client, callID uint32, gatheringID uint32) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	manager.respondResult(client, callID, LaunchSession, manager.LaunchSession(client.PID(), gatheringID))
+}
+
+// HandleTerminateGathering is the default MatchmakingProtocol::TerminateGathering handler
+func (manager *GatheringManager) HandleTerminateGathering(err error, client *nex.Client, callID uint32, gatheringID uint32) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	manager.respondResult(client, callID, TerminateGathering, manager.Terminate(client.PID(), gatheringID))
+}
+
+// HandleSetState is the default MatchmakingProtocol::SetState handler
+func (manager *GatheringManager) HandleSetState(err error, client *nex.Client, callID uint32, gatheringID uint32, state uint32) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	manager.respondResult(client, callID, SetState, manager.SetState(client.PID(), gatheringID, state))
+}
+
+// HandleFindByType is the default MatchmakingProtocol::FindByType handler
+func (manager *GatheringManager) HandleFindByType(err error, client *nex.Client, callID uint32, gatheringType string, resultRange *ResultRange) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	manager.respondGatherings(client, callID, FindByType, manager.FindByType(gatheringType, resultRange))
+}
+
+// HandleFindByDescription is the default MatchmakingProtocol::FindByDescription handler
+func (manager *GatheringManager) HandleFindByDescription(err error, client *nex.Client, callID uint32, description string, resultRange *ResultRange) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	manager.respondGatherings(client, callID, FindByDescription, manager.FindByDescription(description, resultRange))
+}
+
+// HandleFindByID is the default MatchmakingProtocol::FindByID handler
+func (manager *GatheringManager) HandleFindByID(err error, client *nex.Client, callID uint32, gatheringIDs []uint32) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	manager.respondGatherings(client, callID, FindByID, manager.FindByID(gatheringIDs))
+}
+
+// HandleFindByOwner is the default MatchmakingProtocol::FindByOwner handler
+func (manager *GatheringManager) HandleFindByOwner(err error, client *nex.Client, callID uint32, ownerPID uint32, resultRange *ResultRange) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	manager.respondGatherings(client, callID, FindByOwner, manager.FindByOwner(ownerPID, resultRange))
+}
+
+// HandleFindByParticipants is the default MatchmakingProtocol::FindByParticipants handler
+func (manager *GatheringManager) HandleFindByParticipants(err error, client *nex.Client, callID uint32, pids []uint32) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	manager.respondGatherings(client, callID, FindByParticipants, manager.FindByParticipants(pids))
+}
+
+// respondGatherings answers a find method with a list of gatherings
+func (manager *GatheringManager) respondGatherings(client *nex.Client, callID uint32, methodID uint32, gatherings []*Gathering) {
+	responseStream := NewStreamOut(client.Server())
+	responseStream.WriteListGathering(manager.GatheringClassName, gatherings)
+
+	respondSuccess(client, MatchmakingProtocolID, methodID, callID, responseStream.Bytes())
+}
+
+// respondResult answers a bool returning gathering method, sending the matching result code if err is not nil
+func (manager *GatheringManager) respondResult(client *nex.Client, callID uint32, methodID uint32, err error) {
+	if err != nil {
+		respondError(client, MatchmakingProtocolID, callID, gatheringResultCode(err))
+		return
+	}
+
+	responseStream := nex.NewStreamOut(client.Server())
+	writeBool(responseStream, true)
+
+	respondSuccess(client, MatchmakingProtocolID, methodID, callID, responseStream.Bytes())
+}
+
+// findableGathering returns a copy of a live gathering the MatchMaking find methods answer with, or nil if it
+// does not exist, has expired or is a MatchmakeSession
+func (manager *GatheringManager) findableGathering(gatheringID uint32) *Gathering {
+	gathering := manager.Gathering(gatheringID)
+	if gathering == nil || gathering.className == MatchmakeSessionClassName {
+		return nil
+	}
+
+	return gathering
+}
+
+// findable reports whether the MatchMaking find methods return the gathering. MatchmakeSessions are left to
+// MatchmakeExtension, as a Gathering encoded under their class name would not decode as one
+func (managed *managedGathering) findable() bool {
+	return managed.gathering.className != MatchmakeSessionClassName
+}
+
+// filter returns copies of the unexpired gatherings matching keep, ordered by ID
+func (manager *GatheringManager) filter(keep func(managed *managedGathering) bool) []*Gathering {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+
+	gatherings := make([]*Gathering, 0)
+	now := manager.clock()
+
+	for _, managed := range manager.gatherings {
+		if !manager.expired(managed, now) && keep(managed) {
+			gatherings = append(gatherings, managed.gathering.Copy())
+		}
+	}
+
+	sort.Slice(gatherings, func(i, j int) bool {
+		return gatherings[i].ID < gatherings[j].ID
+	})
+
+	return gatherings
+}
+
+// join adds pid to the participants of a gathering and returns the event to fire once the lock is released.
+// The caller must hold the lock
+func (manager *GatheringManager) join(managed *managedGathering, pid uint32) (func(), error) {
+	if indexOfPID(managed.participants, pid) != -1 {
+		return nil, ErrAlreadyParticipating
+	}
+
+	maxParticipants := int(managed.gathering.MaxParticipants)
+	if maxParticipants != 0 && len(managed.participants) >= maxParticipants {
+		return nil, ErrGatheringFull
+	}
+
+	managed.participants = append(managed.participants, pid)
+
+	joined := managed.gathering.Copy()
+	participants := append([]uint32(nil), managed.participants...)
+
+	return func() {
+		if manager.ParticipantJoinedHandler != nil {
+			manager.ParticipantJoinedHandler(joined, pid, participants)
+		}
+	}, nil
+}
+
+// leave removes pid from a gathering and returns the events to fire once the lock is released.
+// The host and owner are handed to the first remaining participant when pid held them. A gathering is only
+// terminated when its owner disconnects with no participant left to hand it to. The caller must hold the lock
+func (manager *GatheringManager) leave(managed *managedGathering, pid uint32, disconnected bool) []func() {
+	events := make([]func(), 0)
+
+	index := indexOfPID(managed.participants, pid)
+	if index != -1 {
+		managed.participants = append(managed.participants[:index], managed.participants[index+1:]...)
+	}
+
+	if disconnected && len(managed.participants) == 0 && managed.gathering.OwnerPID == pid {
+		return append(events, manager.terminate(managed))
+	}
+
+	gathering := managed.gathering
+	participants := append([]uint32(nil), managed.participants...)
+
+	if index != -1 && manager.ParticipantLeftHandler != nil {
+		left := gathering.Copy()
+		events = append(events, func() {
+			manager.ParticipantLeftHandler(left, pid, disconnected, participants)
+		})
+	}
+
+	if gathering.HostPID != pid && gathering.OwnerPID != pid {
+		return events
+	}
+
+	oldHostPID := gathering.HostPID
+	oldOwnerPID := gathering.OwnerPID
+
+	if len(participants) != 0 {
+		if gathering.OwnerPID == pid {
+			gathering.OwnerPID = participants[0]
+		}
+
+		if gathering.HostPID == pid {
+			gathering.HostPID = participants[0]
+		}
+	} else if gathering.HostPID == pid {
+		// nobody is left to host, so hosting goes back to the owner
+		gathering.HostPID = gathering.OwnerPID
+	}
+
+	if oldHostPID != gathering.HostPID && manager.HostMigrationHandler != nil {
+		migrated := gathering.Copy()
+		events = append(events, func() {
+			manager.HostMigrationHandler(migrated, oldHostPID, participants)
+		})
+	}
+
+	if oldOwnerPID != gathering.OwnerPID && manager.OwnershipChangedHandler != nil {
+		changed := gathering.Copy()
+		events = append(events, func() {
+			manager.OwnershipChangedHandler(changed, oldOwnerPID, participants)
+		})
+	}
+
+	return events
+}
+
+// terminate removes a gathering and returns the event to fire once the lock is released. The caller must hold the lock
+func (manager *GatheringManager) terminate(managed *managedGathering) func() {
+	delete(manager.gatherings, managed.gathering.ID)
+
+	terminated := managed.gathering.Copy()
+	participants := append([]uint32(nil), managed.participants...)
+
+	return func() {
+		if manager.GatheringTerminatedHandler != nil {
+			manager.GatheringTerminatedHandler(terminated, participants)
+		}
+	}
+}
+
+// ownedGathering returns the gathering if pid owns it and it has not expired. The caller must hold the lock
+func (manager *GatheringManager) ownedGathering(pid uint32, gatheringID uint32) (*managedGathering, error) {
+	managed, ok := manager.gatherings[gatheringID]
+	if !ok || manager.expired(managed, manager.clock()) {
+		return nil, ErrGatheringNotFound
+	}
+
+	if managed.gathering.OwnerPID != pid {
+		return nil, ErrGatheringNotOwner
+	}
+
+	return managed, nil
+}
+
+// register stores gathering under a newly allocated ID as a data holder of className. The caller must hold the write lock
+func (manager *GatheringManager) register(ownerPID uint32, className string, gathering *Gathering) *managedGathering {
+	gatheringID := manager.nextGatheringID()
+
+	registered := gathering.Copy()
+	registered.ID = gatheringID
+	registered.OwnerPID = ownerPID
+	registered.HostPID = ownerPID
+	registered.className = className
+
+	managed := &managedGathering{
+		gathering:    registered,
+		participants: make([]uint32, 0),
+		invitations:  make(map[uint32]*pendingInvitation),
+		refreshedAt:  manager.clock(),
+	}
+
+	manager.gatherings[gatheringID] = managed
+
+	return managed
+}
+
+// nextGatheringID allocates an unused gathering ID. The caller must hold the lock
+func (manager *GatheringManager) nextGatheringID() uint32 {
+	for {
+		manager.lastGatheringID++
+
+		if manager.lastGatheringID == 0 {
+			continue
+		}
+
+		if _, ok := manager.gatherings[manager.lastGatheringID]; !ok {
+			return manager.lastGatheringID
+		}
+	}
+}
+
+func (manager *GatheringManager) stateChanged(gathering *Gathering, oldState uint32) {
+	if manager.StateChangeHandler != nil && gathering.State != oldState {
+		manager.StateChangeHandler(gathering, oldState, gathering.State)
+	}
+}
+
+// decodeGathering decodes the gathering carried in a RegisterGathering or UpdateGathering data holder
+func decodeGathering(gatheringData []byte, server *nex.Server) (*Gathering, error) {
+	gathering := NewGathering()
+
+	err := gathering.ExtractFromStream(nex.NewStreamIn(gatheringData, server))
+	if err != nil {
+		return nil, err
+	}
+
+	return gathering, nil
+}
+
+// gatheringResultCode maps a GatheringManager error to its Quazal result code
+func gatheringResultCode(err error) uint32 {
+	switch err {
+	case ErrGatheringNotFound:
+		return ResultRendezVousInvalidGID
+	case ErrGatheringNotOwner:
+		return ResultRendezVousPermissionDenied
+	case ErrGatheringFull:
+		return ResultRendezVousSessionFull
+	case ErrAlreadyParticipating:
+		return ResultRendezVousAlreadyParticipatedGathering
+	case ErrNotParticipating:
+		return ResultRendezVousNotParticipatedGathering
+	case ErrInvitationNotFound:
+		return ResultRendezVousInvalidOperation
+	default:
+		return ResultCoreInvalidArgument
+	}
+}
+
+// fireEvents calls the events collected while the lock was held, in order
+func fireEvents(events []func()) {
+	for _, event := range events {
+		event()
+	}
+}
+
+// pageGatherings returns the gatherings within resultRange
+func pageGatherings(gatherings []*Gathering, resultRange *ResultRange) []*Gathering {
+	start, end := resultRange.Bounds(len(gatherings))
+
+	return gatherings[start:end]
+}
+
+// indexOfPID returns the index of pid in pids, or -1
+func indexOfPID(pids []uint32, pid uint32) int {
+	for i, participant := range pids {
+		if participant == pid {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// NewGatheringManager returns a new GatheringManager
+func NewGatheringManager() *GatheringManager {
+	return &GatheringManager{
+		gatherings:         make(map[uint32]*managedGathering),
+		clients:            make(map[uint32]*nex.Client),
+		GatheringClassName: "HarmonixGathering",
+		InvitationLifetime: 5 * time.Minute,
+		clock:              time.Now,
+	}
+}
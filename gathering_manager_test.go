@@ -0,0 +1,232 @@
+package nexproto
+
+import (
+	"testing"
+	"time"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+// newTestClient returns a client logged in as pid
+func newTestClient(server *nex.Server, pid uint32) *nex.Client {
+	client := nex.NewClient(nil, server)
+	client.SetPID(pid)
+
+	return client
+}
+
+func TestGatheringManagerLifecycle(t *testing.T) {
+	manager := NewGatheringManager()
+
+	gathering := NewGathering()
+	gathering.MaxParticipants = 1
+
+	gatheringID := manager.Register(5, gathering)
+	if gatheringID != 1 {
+		t.Fatalf("first gathering ID is %d", gatheringID)
+	}
+
+	if err := manager.Participate(5, gatheringID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.Participate(6, gatheringID); err != ErrGatheringFull {
+		t.Fatalf("joining a full gathering returned %v", err)
+	}
+
+	if err := manager.SetState(6, gatheringID, 2); err != ErrGatheringNotOwner {
+		t.Fatalf("SetState by a non owner returned %v", err)
+	}
+
+	var oldState, newState uint32
+
+	manager.OnStateChange(func(gathering *Gathering, old uint32, new uint32) {
+		oldState, newState = old, new
+	})
+
+	if err := manager.SetState(5, gatheringID, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if oldState != 0 || newState != 2 {
+		t.Fatalf("state change handler got %d to %d", oldState, newState)
+	}
+
+	if err := manager.Unparticipate(5, gatheringID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := manager.Terminate(5, gatheringID); err != nil {
+		t.Fatal(err)
+	}
+
+	if manager.Gathering(gatheringID) != nil {
+		t.Fatal("terminated gathering still exists")
+	}
+}
+
+func TestGatheringManagerFind(t *testing.T) {
+	manager := NewGatheringManager()
+
+	for i := 0; i < 5; i++ {
+		gathering := NewGathering()
+		gathering.Description = "practice"
+
+		manager.Register(uint32(i%2), gathering)
+	}
+
+	if err := manager.Participate(9, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if found := manager.FindByType(manager.GatheringClassName, &ResultRange{Offset: 1, Size: 2}); len(found) != 2 || found[0].ID != 2 {
+		t.Fatalf("FindByType returned %v", found)
+	}
+
+	if found := manager.FindByOwner(1, &ResultRange{Size: 0xFFFFFFFF}); len(found) != 2 {
+		t.Fatalf("FindByOwner returned %v", found)
+	}
+
+	if found := manager.FindByDescription("practice", &ResultRange{Offset: 10, Size: 2}); len(found) != 0 {
+		t.Fatalf("FindByDescription past the end returned %v", found)
+	}
+
+	if found := manager.FindByParticipants([]uint32{9}); len(found) != 1 || found[0].ID != 3 {
+		t.Fatalf("FindByParticipants returned %v", found)
+	}
+
+	if found := manager.FindByID([]uint32{4, 99, 1}); len(found) != 2 || found[0].ID != 4 {
+		t.Fatalf("FindByID returned %v", found)
+	}
+}
+
+func TestGatheringManagerDropParticipant(t *testing.T) {
+	manager := NewGatheringManager()
+	server := nex.NewServer()
+
+	var left, migrated, ownershipChanged, terminated int
+
+	manager.OnParticipantLeft(func(gathering *Gathering, pid uint32, disconnected bool, participants []uint32) {
+		left++
+	})
+
+	manager.OnHostMigration(func(gathering *Gathering, oldHostPID uint32, participants []uint32) {
+		migrated++
+
+		if oldHostPID != 1 || gathering.HostPID != 2 {
+			t.Errorf("host migrated from %d to %d", oldHostPID, gathering.HostPID)
+		}
+	})
+
+	manager.OnOwnershipChanged(func(gathering *Gathering, oldOwnerPID uint32, participants []uint32) {
+		ownershipChanged++
+
+		if oldOwnerPID != 1 || gathering.OwnerPID != 2 {
+			t.Errorf("ownership changed from %d to %d", oldOwnerPID, gathering.OwnerPID)
+		}
+	})
+
+	manager.OnGatheringTerminated(func(gathering *Gathering, participants []uint32) {
+		terminated++
+	})
+
+	shared := manager.Register(1, NewGathering())
+	alone := manager.Register(1, NewGathering())
+
+	for _, join := range []struct{ pid, gatheringID uint32 }{{1, shared}, {2, shared}, {1, alone}} {
+		if err := manager.Participate(join.pid, join.gatheringID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stale := newTestClient(server, 1)
+	current := newTestClient(server, 1)
+
+	manager.connect(stale)
+	manager.connect(current)
+
+	manager.DropParticipant(stale)
+
+	if left != 0 {
+		t.Fatal("dropping a replaced client removed its PID")
+	}
+
+	manager.DropParticipant(current)
+
+	if left != 1 || migrated != 1 || ownershipChanged != 1 || terminated != 1 {
+		t.Fatalf("left %d, migrated %d, ownership changed %d, terminated %d", left, migrated, ownershipChanged, terminated)
+	}
+
+	if manager.Gathering(alone) != nil {
+		t.Fatal("gathering left empty by its owner still exists")
+	}
+
+	// A guest dropping last leaves the gathering to its owner
+	owned := manager.Register(7, NewGathering())
+
+	if err := manager.Participate(8, owned); err != nil {
+		t.Fatal(err)
+	}
+
+	manager.DropParticipant(newTestClient(server, 8))
+
+	if manager.Gathering(owned) == nil || terminated != 1 {
+		t.Fatal("a guest dropping terminated the gathering")
+	}
+}
+
+func TestGatheringManagerExpiry(t *testing.T) {
+	manager := NewGatheringManager()
+	manager.GatheringLifetime = time.Hour
+
+	now := time.Now()
+	manager.clock = func() time.Time {
+		return now
+	}
+
+	var reaped int
+
+	manager.OnGatheringReaped(func(gathering *Gathering, participants []uint32) {
+		reaped++
+	})
+
+	kept := manager.Register(1, NewGathering())
+	expired := manager.Register(1, NewGathering())
+
+	if err := manager.SetLifetime(expired, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(2 * time.Minute)
+
+	if manager.Gathering(expired) != nil || len(manager.FindByID([]uint32{expired})) != 0 {
+		t.Fatal("expired gathering is still returned")
+	}
+
+	if err := manager.Participate(2, expired); err != ErrGatheringNotFound {
+		t.Fatalf("joining an expired gathering returned %v", err)
+	}
+
+	if err := manager.SetState(1, expired, 2); err != ErrGatheringNotFound {
+		t.Fatalf("changing the state of an expired gathering returned %v", err)
+	}
+
+	if err := manager.LaunchSession(1, expired); err != ErrGatheringNotFound {
+		t.Fatalf("launching an expired gathering returned %v", err)
+	}
+
+	update := NewGathering()
+	update.ID = expired
+
+	if err := manager.Update(1, update); err != ErrGatheringNotFound {
+		t.Fatalf("updating an expired gathering returned %v", err)
+	}
+
+	if reapedGatherings := manager.Reap(); len(reapedGatherings) != 1 || reapedGatherings[0].ID != expired || reaped != 1 {
+		t.Fatalf("Reap returned %v", reapedGatherings)
+	}
+
+	if manager.Gathering(kept) == nil {
+		t.Fatal("unexpired gathering was reaped")
+	}
+}
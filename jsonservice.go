@@ -27,10 +27,14 @@ type JsonProtocol struct {
 func (jsonProtocol *JsonProtocol) Setup() {
 	nexServer := jsonProtocol.server
 
+	trackPacketRoutes(nexServer)
+
 	nexServer.On("Data", func(packet nex.PacketInterface) {
 		request := packet.RMCRequest()
 
 		if JsonProtocolID == request.ProtocolID() {
+			rememberPacketRoute(packet)
+
 			switch request.MethodID() {
 			case JsonRequest:
 				go jsonProtocol.handleRequest(packet)
@@ -0,0 +1,1064 @@
+package nexproto
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+var (
+	// ErrRankingScoreNotFound is returned when a PID has no score in a category
+	ErrRankingScoreNotFound = errors.New("ranking score not found")
+
+	// ErrInvalidRankingMode is returned for a ranking mode the Leaderboard does not know
+	ErrInvalidRankingMode = errors.New("invalid ranking mode")
+
+	// ErrRankingCommonDataNotFound is returned when a PID has no common data under a unique ID
+	ErrRankingCommonDataNotFound = errors.New("ranking common data not found")
+
+	// ErrRankingCommonDataTooLarge is returned when uploaded common data exceeds the size limit
+	ErrRankingCommonDataTooLarge = errors.New("ranking common data too large")
+
+	// ErrRankingCommonDataLimit is returned when a PID uploads common data under more unique IDs than allowed
+	ErrRankingCommonDataLimit = errors.New("ranking common data entry limit reached")
+)
+
+// rankingEntry is a score stored in a Leaderboard
+type rankingEntry struct {
+	principalID uint32
+	uniqueID    uint64
+	key         rankingKey
+	scoreData   *RankingScoreData
+	uploadedAt  time.Time
+}
+
+// rankingCommonDataKey identifies the common data of a PID under a unique ID
+type rankingCommonDataKey struct {
+	principalID uint32
+	uniqueID    uint64
+}
+
+// rankingGroupKey identifies the scores of a category matching a group filter
+type rankingGroupKey struct {
+	index uint8
+	num   uint8
+}
+
+// rankingCategory holds the scores of a category, indexed as a whole and per group value
+type rankingCategory struct {
+	orderBy    uint8
+	since      time.Time
+	endsAt     time.Time // end of the reset period, zero if the category is never reset
+	all        *rankingIndex
+	groups     map[rankingGroupKey]*rankingIndex
+	byPID      map[uint32]map[uint64]*rankingEntry
+	byUniqueID map[uint64]map[uint32]*rankingEntry
+}
+
+// sortKey returns the key a score is indexed by, lower keys ranking first
+func (category *rankingCategory) sortKey(score uint32) uint32 {
+	if category.orderBy == RankingOrderByAscending {
+		return score
+	}
+
+	return math.MaxUint32 - score
+}
+
+// entry returns the score of pid under uniqueID, or nil
+func (category *rankingCategory) entry(pid uint32, uniqueID uint64) *rankingEntry {
+	return category.byPID[pid][uniqueID]
+}
+
+// index returns the index of the scores passing the group filter of orderParam, or nil if none do
+func (category *rankingCategory) index(orderParam *RankingOrderParam) *rankingIndex {
+	if orderParam.GroupIndex == RankingGroupIndexNone {
+		return category.all
+	}
+
+	return category.groups[rankingGroupKey{orderParam.GroupIndex, orderParam.GroupNum}]
+}
+
+// add indexes entry in the whole category and in the groups a RankingOrderParam can filter on
+func (category *rankingCategory) add(entry *rankingEntry) {
+	category.all.add(entry)
+
+	for groupIndex, groupNum := range entry.scoreData.Groups {
+		if groupIndex >= rankingGroupCount {
+			break
+		}
+
+		groupKey := rankingGroupKey{uint8(groupIndex), groupNum}
+
+		index, ok := category.groups[groupKey]
+		if !ok {
+			index = newRankingIndex()
+			category.groups[groupKey] = index
+		}
+
+		index.add(entry)
+	}
+
+	if category.byPID[entry.principalID] == nil {
+		category.byPID[entry.principalID] = make(map[uint64]*rankingEntry)
+	}
+
+	if category.byUniqueID[entry.uniqueID] == nil {
+		category.byUniqueID[entry.uniqueID] = make(map[uint32]*rankingEntry)
+	}
+
+	category.byPID[entry.principalID][entry.uniqueID] = entry
+	category.byUniqueID[entry.uniqueID][entry.principalID] = entry
+}
+
+// remove drops entry from every index
+func (category *rankingCategory) remove(entry *rankingEntry) {
+	category.all.remove(entry)
+
+	for groupIndex, groupNum := range entry.scoreData.Groups {
+		if groupIndex >= rankingGroupCount {
+			break
+		}
+
+		groupKey := rankingGroupKey{uint8(groupIndex), groupNum}
+
+		index, ok := category.groups[groupKey]
+		if !ok {
+			continue
+		}
+
+		index.remove(entry)
+
+		if index.length() == 0 {
+			delete(category.groups, groupKey)
+		}
+	}
+
+	delete(category.byPID[entry.principalID], entry.uniqueID)
+	delete(category.byUniqueID[entry.uniqueID], entry.principalID)
+
+	if len(category.byPID[entry.principalID]) == 0 {
+		delete(category.byPID, entry.principalID)
+	}
+
+	if len(category.byUniqueID[entry.uniqueID]) == 0 {
+		delete(category.byUniqueID, entry.uniqueID)
+	}
+}
+
+// entries returns every score of the category
+func (category *rankingCategory) entries() []*rankingEntry {
+	entries := make([]*rankingEntry, 0, category.all.length())
+
+	for _, uniqueIDs := range category.byPID {
+		for _, entry := range uniqueIDs {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// newRankingCategory returns a new empty rankingCategory ordered by orderBy
+func newRankingCategory(orderBy uint8) *rankingCategory {
+	return &rankingCategory{
+		orderBy:    orderBy,
+		since:      time.Now(),
+		all:        newRankingIndex(),
+		groups:     make(map[rankingGroupKey]*rankingIndex),
+		byPID:      make(map[uint32]map[uint64]*rankingEntry),
+		byUniqueID: make(map[uint64]map[uint32]*rankingEntry),
+	}
+}
+
+// Leaderboard stores scores per category, PID and unique ID and answers ranking queries. Every category is indexed
+// in ranking order, so orders and pages of the whole ranking or of a group filter are found in logarithmic time.
+// Friend and list queries rank the matching scores among themselves.
+// The common data of each PID and unique ID is kept alongside and returned with their scores.
+// Categories with a reset schedule start over at the end of each period, the past periods being archived
+type Leaderboard struct {
+	mutex                sync.RWMutex
+	categories           map[uint32]*rankingCategory
+	categoryOrders       map[uint32]uint8
+	commonData           map[rankingCommonDataKey][]byte
+	commonDataCounts     map[uint32]int // unique IDs each PID has common data under
+	schedules            map[uint32]*RankingResetSchedule
+	archives             map[uint32][]*rankingCategory
+	periodCounts         map[uint32]int
+	nextRotation         time.Time
+	lastSequence         uint64
+	friends              func(pid uint32) []uint32
+	rotationHooks        []func(category uint32) // called with every category starting a new period
+	MaxCommonDataSize    int                     // bytes of common data kept per PID and unique ID. 0 for no limit
+	MaxCommonDataEntries int                     // unique IDs a PID can keep common data under. 0 for no limit
+	MaxArchivedPeriods   int                     // past periods kept per category, the oldest being dropped first. 0 for no limit
+	PeriodResetHandler   func(category uint32, period *RankingPeriod)
+}
+
+// SetCategoryOrder sets whether the scores of category rank in descending or ascending order. Categories without
+// a set order use the OrderBy of their first uploaded score. Scores already uploaded to category are reordered
+func (leaderboard *Leaderboard) SetCategoryOrder(category uint32, orderBy uint8) {
+	leaderboard.mutex.Lock()
+	defer leaderboard.mutex.Unlock()
+
+	leaderboard.categoryOrders[category] = orderBy
+
+	rankingCategory, ok := leaderboard.categories[category]
+	if !ok || rankingCategory.orderBy == orderBy {
+		return
+	}
+
+	reordered := newRankingCategory(orderBy)
+	reordered.since = rankingCategory.since
+	reordered.endsAt = rankingCategory.endsAt
+
+	for _, entry := range rankingCategory.entries() {
+		entry.key.sortKey = reordered.sortKey(entry.scoreData.Score)
+		reordered.add(entry)
+	}
+
+	leaderboard.categories[category] = reordered
+}
+
+// SetFriendsFunc sets the function returning the friends of a PID, used by the friend ranking modes.
+// Without it friend rankings only hold the caller
+func (leaderboard *Leaderboard) SetFriendsFunc(friends func(pid uint32) []uint32) {
+	leaderboard.mutex.Lock()
+	defer leaderboard.mutex.Unlock()
+
+	leaderboard.friends = friends
+}
+
+// UploadScore stores the score of pid under uniqueID in the category of scoreData. With RankingUpdateModeNormal
+// a stored score is only replaced by a better one, with RankingUpdateModeDeleteOld it is always replaced
+func (leaderboard *Leaderboard) UploadScore(pid uint32, uniqueID uint64, scoreData *RankingScoreData) error {
+	leaderboard.rotateIfDue()
+
+	leaderboard.mutex.Lock()
+	defer leaderboard.mutex.Unlock()
+
+	category := leaderboard.category(scoreData.Category, scoreData.OrderBy)
+	sortKey := category.sortKey(scoreData.Score)
+
+	existing := category.entry(pid, uniqueID)
+	if existing != nil {
+		if scoreData.UpdateMode != RankingUpdateModeDeleteOld && existing.key.sortKey <= sortKey {
+			return nil
+		}
+
+		category.remove(existing)
+	}
+
+	leaderboard.lastSequence++
+
+	category.add(&rankingEntry{
+		principalID: pid,
+		uniqueID:    uniqueID,
+		key:         rankingKey{sortKey, leaderboard.lastSequence},
+		scoreData:   scoreData.Copy(),
+		uploadedAt:  time.Now(),
+	})
+
+	return nil
+}
+
+// hasScores reports whether the current period of category holds any score
+func (leaderboard *Leaderboard) hasScores(category uint32) bool {
+	leaderboard.rotateIfDue()
+
+	leaderboard.mutex.RLock()
+	defer leaderboard.mutex.RUnlock()
+
+	rankingCategory, ok := leaderboard.categories[category]
+
+	return ok && rankingCategory.all.length() != 0
+}
+
+// Score returns the score of pid under uniqueID in category
+func (leaderboard *Leaderboard) Score(pid uint32, category uint32, uniqueID uint64) (*RankingScoreData, error) {
+	leaderboard.rotateIfDue()
+
+	leaderboard.mutex.RLock()
+	defer leaderboard.mutex.RUnlock()
+
+	entry := leaderboard.entry(pid, category, uniqueID)
+	if entry == nil {
+		return nil, ErrRankingScoreNotFound
+	}
+
+	return entry.scoreData.Copy(), nil
+}
+
+// DeleteScore removes the score of pid under uniqueID from category
+func (leaderboard *Leaderboard) DeleteScore(pid uint32, category uint32, uniqueID uint64) error {
+	leaderboard.rotateIfDue()
+
+	leaderboard.mutex.Lock()
+	defer leaderboard.mutex.Unlock()
+
+	entry := leaderboard.entry(pid, category, uniqueID)
+	if entry == nil {
+		return ErrRankingScoreNotFound
+	}
+
+	leaderboard.categories[category].remove(entry)
+
+	return nil
+}
+
+// DeleteAllScores removes the scores of pid under uniqueID from every category and returns how many were removed
+func (leaderboard *Leaderboard) DeleteAllScores(pid uint32, uniqueID uint64) int {
+	leaderboard.rotateIfDue()
+
+	leaderboard.mutex.Lock()
+	defer leaderboard.mutex.Unlock()
+
+	removed := 0
+
+	for _, category := range leaderboard.categories {
+		entry := category.entry(pid, uniqueID)
+		if entry != nil {
+			category.remove(entry)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// ChangeAttributes changes the groups and param of the score of pid under uniqueID in category,
+// as selected by the modification flag of changeParam
+func (leaderboard *Leaderboard) ChangeAttributes(pid uint32, category uint32, changeParam *RankingChangeAttributesParam, uniqueID uint64) error {
+	leaderboard.rotateIfDue()
+
+	leaderboard.mutex.Lock()
+	defer leaderboard.mutex.Unlock()
+
+	entry := leaderboard.entry(pid, category, uniqueID)
+	if entry == nil {
+		return ErrRankingScoreNotFound
+	}
+
+	leaderboard.changeAttributes(leaderboard.categories[category], entry, changeParam)
+
+	return nil
+}
+
+// ChangeAllAttributes changes the groups and param of the scores of pid under uniqueID in every category
+func (leaderboard *Leaderboard) ChangeAllAttributes(pid uint32, changeParam *RankingChangeAttributesParam, uniqueID uint64) error {
+	leaderboard.rotateIfDue()
+
+	leaderboard.mutex.Lock()
+	defer leaderboard.mutex.Unlock()
+
+	changed := false
+
+	for _, category := range leaderboard.categories {
+		entry := category.entry(pid, uniqueID)
+		if entry != nil {
+			leaderboard.changeAttributes(category, entry, changeParam)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return ErrRankingScoreNotFound
+	}
+
+	return nil
+}
+
+// UploadCommonData stores the common data of pid under uniqueID, replacing any previous one
+func (leaderboard *Leaderboard) UploadCommonData(pid uint32, uniqueID uint64, commonData []byte) error {
+	leaderboard.mutex.Lock()
+	defer leaderboard.mutex.Unlock()
+
+	if leaderboard.MaxCommonDataSize != 0 && len(commonData) > leaderboard.MaxCommonDataSize {
+		return ErrRankingCommonDataTooLarge
+	}
+
+	key := rankingCommonDataKey{pid, uniqueID}
+
+	if _, ok := leaderboard.commonData[key]; !ok {
+		if !commonDataEntryAllowed(leaderboard.commonDataCounts, pid, leaderboard.MaxCommonDataEntries) {
+			return ErrRankingCommonDataLimit
+		}
+
+		leaderboard.commonDataCounts[pid]++
+	}
+
+	leaderboard.commonData[key] = append([]byte(nil), commonData...)
+
+	return nil
+}
+
+// CommonData returns the common data of pid under uniqueID
+func (leaderboard *Leaderboard) CommonData(pid uint32, uniqueID uint64) ([]byte, error) {
+	leaderboard.mutex.RLock()
+	defer leaderboard.mutex.RUnlock()
+
+	commonData, ok := leaderboard.commonData[rankingCommonDataKey{pid, uniqueID}]
+	if !ok {
+		return nil, ErrRankingCommonDataNotFound
+	}
+
+	return append([]byte(nil), commonData...), nil
+}
+
+// DeleteCommonData removes the common data of pid under uniqueID
+func (leaderboard *Leaderboard) DeleteCommonData(pid uint32, uniqueID uint64) error {
+	leaderboard.mutex.Lock()
+	defer leaderboard.mutex.Unlock()
+
+	key := rankingCommonDataKey{pid, uniqueID}
+
+	if _, ok := leaderboard.commonData[key]; !ok {
+		return ErrRankingCommonDataNotFound
+	}
+
+	delete(leaderboard.commonData, key)
+	forgetCommonDataEntry(leaderboard.commonDataCounts, pid)
+
+	return nil
+}
+
+// Ranking returns the part of the ranking of category selected by rankingMode and orderParam.
+// pid and uniqueID identify the caller for the modes centered on or limited to them
+func (leaderboard *Leaderboard) Ranking(rankingMode uint8, category uint32, orderParam *RankingOrderParam, pid uint32, uniqueID uint64) (*RankingResult, error) {
+	leaderboard.rotateIfDue()
+
+	leaderboard.mutex.RLock()
+	defer leaderboard.mutex.RUnlock()
+
+	return leaderboard.ranking(leaderboard.categories[category], rankingMode, orderParam, pid, uniqueID)
+}
+
+// ApproxOrder returns the order score would get in the ranking of category selected by orderParam
+func (leaderboard *Leaderboard) ApproxOrder(category uint32, orderParam *RankingOrderParam, score uint32) uint32 {
+	leaderboard.rotateIfDue()
+
+	leaderboard.mutex.RLock()
+	defer leaderboard.mutex.RUnlock()
+
+	rankingCategory, ok := leaderboard.categories[category]
+	if !ok {
+		return 1
+	}
+
+	index := rankingCategory.index(orderParam)
+	if index == nil {
+		return 1
+	}
+
+	return index.order(rankingKey{rankingCategory.sortKey(score), math.MaxUint64}, orderParam.OrderCalculation)
+}
+
+// RankingByPIDList returns the scores under uniqueID of the PIDs of pids in category, ranked among themselves
+func (leaderboard *Leaderboard) RankingByPIDList(pids []uint32, category uint32, orderParam *RankingOrderParam, uniqueID uint64) *RankingResult {
+	leaderboard.rotateIfDue()
+
+	leaderboard.mutex.RLock()
+	defer leaderboard.mutex.RUnlock()
+
+	return leaderboard.rankingByPIDList(leaderboard.categories[category], pids, orderParam, uniqueID)
+}
+
+// RankingByUniqueIDList returns the scores of every PID under the unique IDs of uniqueIDs in category, ranked among themselves
+func (leaderboard *Leaderboard) RankingByUniqueIDList(uniqueIDs []uint64, category uint32, orderParam *RankingOrderParam) *RankingResult {
+	leaderboard.rotateIfDue()
+
+	leaderboard.mutex.RLock()
+	defer leaderboard.mutex.RUnlock()
+
+	result := NewRankingResult()
+
+	rankingCategory, ok := leaderboard.categories[category]
+	if !ok {
+		return result
+	}
+
+	entries := make([]*rankingEntry, 0, len(uniqueIDs))
+	seen := make(map[uint64]bool, len(uniqueIDs))
+
+	for _, uniqueID := range uniqueIDs {
+		if seen[uniqueID] {
+			continue
+		}
+
+		seen[uniqueID] = true
+
+		for _, entry := range rankingCategory.byUniqueID[uniqueID] {
+			if orderParam.MatchesGroups(entry.scoreData.Groups) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	result.SinceTime = newDateTime(rankingCategory.since)
+	result.RankData = leaderboard.listRankData(entries, orderParam.OrderCalculation, int(orderParam.Offset), int(orderParam.Length))
+	result.TotalCount = uint32(len(entries))
+
+	return result
+}
+
+// Stats returns the statistics of the scores of category passing the group filter of orderParam, holding the values
+// selected by flags in the order of the RankingStatsFlag bits. Statistics are kept up to date as scores change,
+// so they are found in logarithmic time
+func (leaderboard *Leaderboard) Stats(category uint32, orderParam *RankingOrderParam, flags uint32) *RankingStats {
+	leaderboard.rotateIfDue()
+
+	leaderboard.mutex.RLock()
+	defer leaderboard.mutex.RUnlock()
+
+	return rankingCategoryStats(leaderboard.categories[category], orderParam, flags)
+}
+
+// HandleUploadScore is the default RankingProtocol::UploadScore handler
+func (leaderboard *Leaderboard) HandleUploadScore(err error, client *nex.Client, callID uint32, scoreData *RankingScoreData, uniqueID uint64) {
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	err = leaderboard.UploadScore(client.PID(), uniqueID, scoreData)
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, rankingResultCode(err))
+		return
+	}
+
+	respondSuccess(client, RankingProtocolID, RankingMethodUploadScore, callID, make([]byte, 0))
+}
+
+// HandleUploadCommonData is the default RankingProtocol::UploadCommonData handler
+func (leaderboard *Leaderboard) HandleUploadCommonData(err error, client *nex.Client, callID uint32, commonData []byte, uniqueID uint64) {
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	err = leaderboard.UploadCommonData(client.PID(), uniqueID, commonData)
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, rankingResultCode(err))
+		return
+	}
+
+	respondSuccess(client, RankingProtocolID, RankingMethodUploadCommonData, callID, make([]byte, 0))
+}
+
+// HandleDeleteCommonData is the default RankingProtocol::DeleteCommonData handler
+func (leaderboard *Leaderboard) HandleDeleteCommonData(err error, client *nex.Client, callID uint32, uniqueID uint64) {
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	err = leaderboard.DeleteCommonData(client.PID(), uniqueID)
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, rankingResultCode(err))
+		return
+	}
+
+	respondSuccess(client, RankingProtocolID, RankingMethodDeleteCommonData, callID, make([]byte, 0))
+}
+
+// HandleGetCommonData is the default RankingProtocol::GetCommonData handler
+func (leaderboard *Leaderboard) HandleGetCommonData(err error, client *nex.Client, callID uint32, uniqueID uint64) {
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	commonData, err := leaderboard.CommonData(client.PID(), uniqueID)
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, rankingResultCode(err))
+		return
+	}
+
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteBuffer(commonData)
+
+	respondSuccess(client, RankingProtocolID, RankingMethodGetCommonData, callID, responseStream.Bytes())
+}
+
+// HandleDeleteScore is the default RankingProtocol::DeleteScore handler
+func (leaderboard *Leaderboard) HandleDeleteScore(err error, client *nex.Client, callID uint32, category uint32, uniqueID uint64) {
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	err = leaderboard.DeleteScore(client.PID(), category, uniqueID)
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, rankingResultCode(err))
+		return
+	}
+
+	respondSuccess(client, RankingProtocolID, RankingMethodDeleteScore, callID, make([]byte, 0))
+}
+
+// HandleDeleteAllScores is the default RankingProtocol::DeleteAllScores handler
+func (leaderboard *Leaderboard) HandleDeleteAllScores(err error, client *nex.Client, callID uint32, uniqueID uint64) {
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	leaderboard.DeleteAllScores(client.PID(), uniqueID)
+
+	respondSuccess(client, RankingProtocolID, RankingMethodDeleteAllScores, callID, make([]byte, 0))
+}
+
+// HandleChangeAttributes is the default RankingProtocol::ChangeAttributes handler
+func (leaderboard *Leaderboard) HandleChangeAttributes(err error, client *nex.Client, callID uint32, category uint32, changeParam *RankingChangeAttributesParam, uniqueID uint64) {
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	err = leaderboard.ChangeAttributes(client.PID(), category, changeParam, uniqueID)
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, rankingResultCode(err))
+		return
+	}
+
+	respondSuccess(client, RankingProtocolID, RankingMethodChangeAttributes, callID, make([]byte, 0))
+}
+
+// HandleChangeAllAttributes is the default RankingProtocol::ChangeAllAttributes handler
+func (leaderboard *Leaderboard) HandleChangeAllAttributes(err error, client *nex.Client, callID uint32, changeParam *RankingChangeAttributesParam, uniqueID uint64) {
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	err = leaderboard.ChangeAllAttributes(client.PID(), changeParam, uniqueID)
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, rankingResultCode(err))
+		return
+	}
+
+	respondSuccess(client, RankingProtocolID, RankingMethodChangeAllAttributes, callID, make([]byte, 0))
+}
+
+// HandleGetRanking is the default RankingProtocol::GetRanking handler. A principalID of 0 stands for the caller
+func (leaderboard *Leaderboard) HandleGetRanking(err error, client *nex.Client, callID uint32, rankingMode uint8, category uint32, orderParam *RankingOrderParam, uniqueID uint64, principalID uint32) {
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	if principalID == 0 {
+		principalID = client.PID()
+	}
+
+	result, err := leaderboard.Ranking(rankingMode, category, orderParam, principalID, uniqueID)
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, rankingResultCode(err))
+		return
+	}
+
+	respondRankingStructure(client, callID, RankingMethodGetRanking, result)
+}
+
+// HandleGetApproxOrder is the default RankingProtocol::GetApproxOrder handler
+func (leaderboard *Leaderboard) HandleGetApproxOrder(err error, client *nex.Client, callID uint32, category uint32, orderParam *RankingOrderParam, score uint32, uniqueID uint64, principalID uint32) {
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt32LE(leaderboard.ApproxOrder(category, orderParam, score))
+
+	respondSuccess(client, RankingProtocolID, RankingMethodGetApproxOrder, callID, responseStream.Bytes())
+}
+
+// HandleGetStats is the default RankingProtocol::GetStats handler
+func (leaderboard *Leaderboard) HandleGetStats(err error, client *nex.Client, callID uint32, category uint32, orderParam *RankingOrderParam, flags uint32) {
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	respondRankingStructure(client, callID, RankingMethodGetStats, leaderboard.Stats(category, orderParam, flags))
+}
+
+// HandleGetRankingByPIDList is the default RankingProtocol::GetRankingByPIDList handler
+func (leaderboard *Leaderboard) HandleGetRankingByPIDList(err error, client *nex.Client, callID uint32, principalIDs []uint32, rankingMode uint8, category uint32, orderParam *RankingOrderParam, uniqueID uint64) {
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	respondRankingStructure(client, callID, RankingMethodGetRankingByPIDList, leaderboard.RankingByPIDList(principalIDs, category, orderParam, uniqueID))
+}
+
+// HandleGetRankingByUniqueIDList is the default RankingProtocol::GetRankingByUniqueIDList handler
+func (leaderboard *Leaderboard) HandleGetRankingByUniqueIDList(err error, client *nex.Client, callID uint32, uniqueIDs []uint64, rankingMode uint8, category uint32, orderParam *RankingOrderParam, uniqueID uint64) {
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	respondRankingStructure(client, callID, RankingMethodGetRankingByUniqueIDList, leaderboard.RankingByUniqueIDList(uniqueIDs, category, orderParam))
+}
+
+// category returns category, creating it if needed. The caller must hold the write lock
+func (leaderboard *Leaderboard) category(category uint32, orderBy uint8) *rankingCategory {
+	rankingCategory, ok := leaderboard.categories[category]
+	if ok {
+		return rankingCategory
+	}
+
+	if configuredOrderBy, ok := leaderboard.categoryOrders[category]; ok {
+		orderBy = configuredOrderBy
+	}
+
+	rankingCategory = newRankingCategory(orderBy)
+	leaderboard.schedulePeriod(category, rankingCategory, time.Now())
+	leaderboard.categories[category] = rankingCategory
+
+	return rankingCategory
+}
+
+// entry returns the score of pid under uniqueID in category, or nil. The caller must hold the lock
+func (leaderboard *Leaderboard) entry(pid uint32, category uint32, uniqueID uint64) *rankingEntry {
+	rankingCategory, ok := leaderboard.categories[category]
+	if !ok {
+		return nil
+	}
+
+	return rankingCategory.entry(pid, uniqueID)
+}
+
+// changeAttributes applies changeParam to entry, reindexing it under its new groups. The caller must hold the write lock
+func (leaderboard *Leaderboard) changeAttributes(category *rankingCategory, entry *rankingEntry, changeParam *RankingChangeAttributesParam) {
+	category.remove(entry)
+
+	scoreData := entry.scoreData
+
+	for groupIndex, flag := range []uint8{RankingModificationFlagGroup0, RankingModificationFlagGroup1} {
+		if changeParam.ModificationFlag&flag == 0 || groupIndex >= len(changeParam.Groups) {
+			continue
+		}
+
+		for len(scoreData.Groups) <= groupIndex {
+			scoreData.Groups = append(scoreData.Groups, 0)
+		}
+
+		scoreData.Groups[groupIndex] = changeParam.Groups[groupIndex]
+	}
+
+	if changeParam.ModificationFlag&RankingModificationFlagParam != 0 {
+		scoreData.Param = changeParam.Param
+	}
+
+	category.add(entry)
+}
+
+// friendsOf returns the friends of pid. The caller must hold the lock
+func (leaderboard *Leaderboard) friendsOf(pid uint32) []uint32 {
+	if leaderboard.friends == nil {
+		return nil
+	}
+
+	return leaderboard.friends(pid)
+}
+
+// ranking returns the part of the ranking of rankingCategory selected by rankingMode and orderParam.
+// rankingCategory may be nil for a category without scores. The caller must hold the lock
+func (leaderboard *Leaderboard) ranking(rankingCategory *rankingCategory, rankingMode uint8, orderParam *RankingOrderParam, pid uint32, uniqueID uint64) (*RankingResult, error) {
+	result := NewRankingResult()
+
+	if rankingCategory == nil {
+		if rankingMode == RankingModeGlobalAroundSelf || rankingMode == RankingModeFriendsAroundSelf || rankingMode == RankingModeSelf {
+			return nil, ErrRankingScoreNotFound
+		}
+
+		return result, nil
+	}
+
+	result.SinceTime = newDateTime(rankingCategory.since)
+
+	switch rankingMode {
+	case RankingModeGlobal, RankingModeGlobalAroundSelf, RankingModeSelf:
+		index := rankingCategory.index(orderParam)
+		if index == nil {
+			if rankingMode != RankingModeGlobal {
+				return nil, ErrRankingScoreNotFound
+			}
+
+			return result, nil
+		}
+
+		self := rankingCategory.entry(pid, uniqueID)
+		if rankingMode != RankingModeGlobal && (self == nil || !orderParam.MatchesGroups(self.scoreData.Groups)) {
+			return nil, ErrRankingScoreNotFound
+		}
+
+		offset := int(orderParam.Offset)
+		length := int(orderParam.Length)
+
+		switch rankingMode {
+		case RankingModeGlobalAroundSelf:
+			offset = aroundRankingOffset(index.position(self.key), length, index.length())
+		case RankingModeSelf:
+			offset = index.position(self.key)
+			length = 1
+		}
+
+		result.RankData = leaderboard.indexRankData(index, orderParam.OrderCalculation, offset, length)
+		result.TotalCount = uint32(index.length())
+	case RankingModeFriends, RankingModeFriendsAroundSelf:
+		pids := append([]uint32{pid}, leaderboard.friendsOf(pid)...)
+		entries := make([]*rankingEntry, 0, len(pids))
+
+		for _, friendPID := range uniquePIDs(pids) {
+			entry := rankingCategory.entry(friendPID, uniqueID)
+			if entry != nil && orderParam.MatchesGroups(entry.scoreData.Groups) {
+				entries = append(entries, entry)
+			}
+		}
+
+		offset := int(orderParam.Offset)
+
+		if rankingMode == RankingModeFriendsAroundSelf {
+			self := rankingCategory.entry(pid, uniqueID)
+			if self == nil || !orderParam.MatchesGroups(self.scoreData.Groups) {
+				return nil, ErrRankingScoreNotFound
+			}
+
+			sortRankingEntries(entries)
+			offset = aroundRankingOffset(sort.Search(len(entries), func(i int) bool {
+				return !entries[i].key.less(self.key)
+			}), int(orderParam.Length), len(entries))
+		}
+
+		result.RankData = leaderboard.listRankData(entries, orderParam.OrderCalculation, offset, int(orderParam.Length))
+		result.TotalCount = uint32(len(entries))
+	default:
+		return nil, ErrInvalidRankingMode
+	}
+
+	return result, nil
+}
+
+// rankingByPIDList returns the scores under uniqueID of the PIDs of pids in rankingCategory, ranked among themselves.
+// rankingCategory may be nil for a category without scores. The caller must hold the lock
+func (leaderboard *Leaderboard) rankingByPIDList(rankingCategory *rankingCategory, pids []uint32, orderParam *RankingOrderParam, uniqueID uint64) *RankingResult {
+	result := NewRankingResult()
+
+	if rankingCategory == nil {
+		return result
+	}
+
+	entries := make([]*rankingEntry, 0, len(pids))
+
+	for _, pid := range uniquePIDs(pids) {
+		entry := rankingCategory.entry(pid, uniqueID)
+		if entry != nil && orderParam.MatchesGroups(entry.scoreData.Groups) {
+			entries = append(entries, entry)
+		}
+	}
+
+	result.SinceTime = newDateTime(rankingCategory.since)
+	result.RankData = leaderboard.listRankData(entries, orderParam.OrderCalculation, int(orderParam.Offset), int(orderParam.Length))
+	result.TotalCount = uint32(len(entries))
+
+	return result
+}
+
+// indexRankData returns up to length scores of index from the 0-based position offset
+func (leaderboard *Leaderboard) indexRankData(index *rankingIndex, orderCalculation uint8, offset int, length int) []*RankingRankData {
+	entries := index.page(offset, length)
+	if len(entries) == 0 {
+		return make([]*RankingRankData, 0)
+	}
+
+	firstOrder := index.order(entries[0].key, orderCalculation)
+
+	return leaderboard.rankData(entries, rankingOrders(entries, orderCalculation, offset, firstOrder))
+}
+
+// listRankData ranks entries among themselves and returns up to length of them from the 0-based position offset
+func (leaderboard *Leaderboard) listRankData(entries []*rankingEntry, orderCalculation uint8, offset int, length int) []*RankingRankData {
+	sortRankingEntries(entries)
+
+	orders := rankingOrders(entries, orderCalculation, 0, 1)
+
+	if offset >= len(entries) {
+		return make([]*RankingRankData, 0)
+	}
+
+	end := len(entries)
+	if offset+length < end {
+		end = offset + length
+	}
+
+	return leaderboard.rankData(entries[offset:end], orders[offset:end])
+}
+
+// rankData returns entries as RankingRankData with their orders and common data. The caller must hold the lock
+func (leaderboard *Leaderboard) rankData(entries []*rankingEntry, orders []uint32) []*RankingRankData {
+	rankDataList := make([]*RankingRankData, 0, len(entries))
+
+	for i, entry := range entries {
+		rankDataList = append(rankDataList, &RankingRankData{
+			PrincipalID: entry.principalID,
+			UniqueID:    entry.uniqueID,
+			Order:       orders[i],
+			Category:    entry.scoreData.Category,
+			Score:       entry.scoreData.Score,
+			Groups:      append([]byte(nil), entry.scoreData.Groups...),
+			Param:       entry.scoreData.Param,
+			CommonData:  append(make([]byte, 0), leaderboard.commonData[rankingCommonDataKey{entry.principalID, entry.uniqueID}]...),
+		})
+	}
+
+	return rankDataList
+}
+
+// rankingCategoryStats returns the statistics of the scores of rankingCategory passing the group filter of orderParam.
+// rankingCategory may be nil for a category without scores
+func rankingCategoryStats(rankingCategory *rankingCategory, orderParam *RankingOrderParam, flags uint32) *RankingStats {
+	var count, total, min, max float64
+
+	if rankingCategory != nil {
+		index := rankingCategory.index(orderParam)
+
+		if index != nil && index.length() != 0 {
+			count = float64(index.length())
+			total = float64(index.total)
+
+			best := float64(index.first().scoreData.Score)
+			worst := float64(index.last().scoreData.Score)
+
+			min, max = worst, best
+			if rankingCategory.orderBy == RankingOrderByAscending {
+				min, max = best, worst
+			}
+		}
+	}
+
+	var average float64
+	if count != 0 {
+		average = total / count
+	}
+
+	stats := NewRankingStats()
+
+	for i, value := range []float64{count, total, min, max, average} {
+		if flags&(1<<uint(i)) != 0 {
+			stats.StatsList = append(stats.StatsList, value)
+		}
+	}
+
+	return stats
+}
+
+// rankingResultCode returns the result code sent for a Leaderboard error
+func rankingResultCode(err error) uint32 {
+	switch err {
+	case ErrRankingScoreNotFound, ErrRankingCommonDataNotFound, ErrRankingPeriodNotFound:
+		return ResultRankingNotFound
+	case ErrRankingCommonDataTooLarge:
+		return ResultRankingInvalidDataSize
+	default:
+		return ResultRankingInvalidArgument
+	}
+}
+
+// commonDataEntryAllowed reports whether pid can store common data under one more unique ID, counts holding
+// the unique IDs each PID has common data under. The caller must hold the lock guarding counts
+func commonDataEntryAllowed(counts map[uint32]int, pid uint32, maxEntries int) bool {
+	return maxEntries == 0 || counts[pid] < maxEntries
+}
+
+// forgetCommonDataEntry counts one unique ID less for pid. The caller must hold the lock guarding counts
+func forgetCommonDataEntry(counts map[uint32]int, pid uint32) {
+	counts[pid]--
+
+	if counts[pid] <= 0 {
+		delete(counts, pid)
+	}
+}
+
+// rankingOrders returns the orders of consecutive entries in ranking order, the first one being at the 0-based
+// position firstPosition with order firstOrder
+func rankingOrders(entries []*rankingEntry, orderCalculation uint8, firstPosition int, firstOrder uint32) []uint32 {
+	orders := make([]uint32, len(entries))
+
+	for i, entry := range entries {
+		switch {
+		case i == 0:
+			orders[i] = firstOrder
+		case orderCalculation == RankingOrderCalculationOrdinal:
+			orders[i] = orders[i-1] + 1
+		case entry.key.sortKey == entries[i-1].key.sortKey:
+			orders[i] = orders[i-1]
+		case orderCalculation == RankingOrderCalculationDense:
+			orders[i] = orders[i-1] + 1
+		default:
+			orders[i] = uint32(firstPosition + i + 1)
+		}
+	}
+
+	return orders
+}
+
+// aroundRankingOffset returns the 0-based position of a page of length scores centered on position
+func aroundRankingOffset(position int, length int, total int) int {
+	offset := position - length/2
+
+	if offset > total-length {
+		offset = total - length
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	return offset
+}
+
+// sortRankingEntries sorts entries in ranking order
+func sortRankingEntries(entries []*rankingEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key.less(entries[j].key)
+	})
+}
+
+// uniquePIDs returns pids without duplicates, keeping the first occurrence of each
+func uniquePIDs(pids []uint32) []uint32 {
+	unique := make([]uint32, 0, len(pids))
+	seen := make(map[uint32]bool, len(pids))
+
+	for _, pid := range pids {
+		if !seen[pid] {
+			seen[pid] = true
+			unique = append(unique, pid)
+		}
+	}
+
+	return unique
+}
+
+// NewLeaderboard returns a new empty Leaderboard keeping up to 256 bytes of common data per PID and unique ID,
+// common data under up to 16 unique IDs per PID and the last 12 periods of reset categories
+func NewLeaderboard() *Leaderboard {
+	return &Leaderboard{
+		categories:           make(map[uint32]*rankingCategory),
+		categoryOrders:       make(map[uint32]uint8),
+		commonData:           make(map[rankingCommonDataKey][]byte),
+		commonDataCounts:     make(map[uint32]int),
+		schedules:            make(map[uint32]*RankingResetSchedule),
+		archives:             make(map[uint32][]*rankingCategory),
+		periodCounts:         make(map[uint32]int),
+		MaxCommonDataSize:    256,
+		MaxCommonDataEntries: 16,
+		MaxArchivedPeriods:   12,
+	}
+}
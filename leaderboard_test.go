@@ -0,0 +1,190 @@
+package nexproto
+
+import (
+	"testing"
+)
+
+// uploadTestScore uploads score for pid in category 1
+func uploadTestScore(t *testing.T, leaderboard *Leaderboard, pid uint32, score uint32) {
+	t.Helper()
+
+	scoreData := NewRankingScoreData()
+	scoreData.Category = 1
+	scoreData.Score = score
+
+	if err := leaderboard.UploadScore(pid, 0, scoreData); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLeaderboardOrderCalculations(t *testing.T) {
+	leaderboard := NewLeaderboard()
+
+	uploadTestScore(t, leaderboard, 1, 100)
+	uploadTestScore(t, leaderboard, 2, 90)
+	uploadTestScore(t, leaderboard, 3, 90)
+	uploadTestScore(t, leaderboard, 4, 80)
+
+	tests := []struct {
+		name             string
+		orderCalculation uint8
+		want             []uint32
+	}{
+		{"standard", RankingOrderCalculationStandard, []uint32{1, 2, 2, 4}},
+		{"ordinal", RankingOrderCalculationOrdinal, []uint32{1, 2, 3, 4}},
+		{"dense", RankingOrderCalculationDense, []uint32{1, 2, 2, 3}},
+	}
+
+	for _, test := range tests {
+		orderParam := NewRankingOrderParam()
+		orderParam.OrderCalculation = test.orderCalculation
+		orderParam.Length = 10
+
+		for _, offset := range []uint32{0, 2} {
+			orderParam.Offset = offset
+
+			result, err := leaderboard.Ranking(RankingModeGlobal, 1, orderParam, 0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(result.RankData) != len(test.want)-int(offset) {
+				t.Fatalf("%s: %d scores at offset %d", test.name, len(result.RankData), offset)
+			}
+
+			for i, rankData := range result.RankData {
+				if rankData.Order != test.want[int(offset)+i] {
+					t.Errorf("%s: order at %d is %d, want %d", test.name, int(offset)+i, rankData.Order, test.want[int(offset)+i])
+				}
+			}
+		}
+	}
+}
+
+func TestLeaderboardApproxOrder(t *testing.T) {
+	leaderboard := NewLeaderboard()
+
+	uploadTestScore(t, leaderboard, 1, 100)
+	uploadTestScore(t, leaderboard, 2, 90)
+	uploadTestScore(t, leaderboard, 3, 90)
+
+	tests := []struct {
+		orderCalculation uint8
+		score            uint32
+		want             uint32
+	}{
+		{RankingOrderCalculationStandard, 90, 2},
+		{RankingOrderCalculationOrdinal, 90, 4},
+		{RankingOrderCalculationDense, 95, 2},
+		{RankingOrderCalculationStandard, 200, 1},
+	}
+
+	for _, test := range tests {
+		orderParam := NewRankingOrderParam()
+		orderParam.OrderCalculation = test.orderCalculation
+
+		if order := leaderboard.ApproxOrder(1, orderParam, test.score); order != test.want {
+			t.Errorf("ApproxOrder(%d) under %d is %d, want %d", test.score, test.orderCalculation, order, test.want)
+		}
+	}
+}
+
+func TestLeaderboardUpdateMode(t *testing.T) {
+	leaderboard := NewLeaderboard()
+
+	uploadTestScore(t, leaderboard, 1, 100)
+	uploadTestScore(t, leaderboard, 1, 50)
+
+	if scoreData, _ := leaderboard.Score(1, 1, 0); scoreData.Score != 100 {
+		t.Fatalf("a worse score replaced the stored one: %d", scoreData.Score)
+	}
+
+	scoreData := NewRankingScoreData()
+	scoreData.Category = 1
+	scoreData.Score = 50
+	scoreData.UpdateMode = RankingUpdateModeDeleteOld
+
+	if err := leaderboard.UploadScore(1, 0, scoreData); err != nil {
+		t.Fatal(err)
+	}
+
+	if scoreData, _ := leaderboard.Score(1, 1, 0); scoreData.Score != 50 {
+		t.Fatalf("RankingUpdateModeDeleteOld kept %d", scoreData.Score)
+	}
+}
+
+func TestLeaderboardCommonDataLimits(t *testing.T) {
+	leaderboard := NewLeaderboard()
+	leaderboard.MaxCommonDataSize = 4
+	leaderboard.MaxCommonDataEntries = 2
+
+	if err := leaderboard.UploadCommonData(1, 0, make([]byte, 5)); err != ErrRankingCommonDataTooLarge {
+		t.Fatalf("oversized common data returned %v", err)
+	}
+
+	for uniqueID := uint64(0); uniqueID < 2; uniqueID++ {
+		if err := leaderboard.UploadCommonData(1, uniqueID, []byte{1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := leaderboard.UploadCommonData(1, 2, []byte{1}); err != ErrRankingCommonDataLimit {
+		t.Fatalf("common data over the entry limit returned %v", err)
+	}
+
+	if err := leaderboard.UploadCommonData(1, 1, []byte{2}); err != nil {
+		t.Fatalf("replacing common data under the limit returned %v", err)
+	}
+
+	if err := leaderboard.UploadCommonData(2, 2, []byte{1}); err != nil {
+		t.Fatalf("the entry limit of another PID applied: %v", err)
+	}
+
+	if err := leaderboard.DeleteCommonData(1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := leaderboard.UploadCommonData(1, 2, []byte{1}); err != nil {
+		t.Fatalf("common data after a deletion returned %v", err)
+	}
+}
+
+func TestLeaderboardGroupFilter(t *testing.T) {
+	leaderboard := NewLeaderboard()
+
+	scoreData := NewRankingScoreData()
+	scoreData.Category = 1
+	scoreData.Score = 10
+	scoreData.Groups = []byte{5, 6, 7, 8}
+
+	if err := leaderboard.UploadScore(1, 0, scoreData); err != nil {
+		t.Fatal(err)
+	}
+
+	if groups := len(leaderboard.categories[1].groups); groups != 2 {
+		t.Fatalf("%d group indexes built, want 2", groups)
+	}
+
+	tests := []struct {
+		groupIndex uint8
+		groupNum   uint8
+		want       int
+	}{
+		{0, 5, 1},
+		{1, 6, 1},
+		{1, 5, 0},
+		{2, 7, 0},
+	}
+
+	for _, test := range tests {
+		orderParam := NewRankingOrderParam()
+		orderParam.GroupIndex = test.groupIndex
+		orderParam.GroupNum = test.groupNum
+		orderParam.Length = 10
+
+		result, err := leaderboard.Ranking(RankingModeGlobal, 1, orderParam, 0, 0)
+		if err != nil || len(result.RankData) != test.want {
+			t.Errorf("group %d number %d ranked %v, %v", test.groupIndex, test.groupNum, result, err)
+		}
+	}
+}
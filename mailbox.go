@@ -0,0 +1,590 @@
+package nexproto
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+var (
+	// ErrMailboxAccessDenied is returned when a PID reads or deletes the messages of another PID
+	ErrMailboxAccessDenied = errors.New("mailbox belongs to another PID")
+
+	// ErrMailboxFull is returned when a message is delivered to a mailbox holding Quota messages
+	ErrMailboxFull = errors.New("mailbox is full")
+
+	// ErrInvalidRecipient is returned for a recipient which is neither a PID nor a gathering
+	ErrInvalidRecipient = errors.New("invalid message recipient type")
+)
+
+// Mailbox delivers messages to the mailbox of a PID or gathering and serves them back to their recipients.
+// Messages are kept by a MailboxStore, in memory or on disk
+type Mailbox struct {
+	mutex                   sync.Mutex
+	server                  *nex.Server
+	store                   MailboxStore
+	gatheringManager        *GatheringManager
+	stopReaper              chan struct{}
+	Quota                   int           // maximum number of messages per mailbox, 0 for no limit
+	DefaultLifetime         time.Duration // lifetime of messages sent without one, 0 to keep them until deleted
+	MaxLifetime             time.Duration // upper bound of message lifetimes, 0 for no limit
+	MessageDeliveredHandler func(message *MailboxMessage)
+}
+
+// Deliver stores message in the mailbox of every recipient, assigning each copy its own ID, sender and reception time.
+// A mailbox listed more than once receives a single copy. Nothing is delivered unless every recipient can receive
+// the message, and the copies already stored are removed again if the store fails midway.
+// A gathering can only be written to by its participants once UseGatheringManager is set
+func (mailbox *Mailbox) Deliver(senderPID uint32, recipients []*MessageRecipient, message UserMessageInterface) ([]*MailboxMessage, error) {
+	recipients = uniqueMessageRecipients(recipients)
+
+	mailbox.mutex.Lock()
+
+	for _, recipient := range recipients {
+		err := mailbox.authorizeDelivery(senderPID, recipient)
+		if err != nil {
+			mailbox.mutex.Unlock()
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	header := message.Header()
+	lifetime := mailbox.lifetime(header.LifeTime)
+	delivered := make([]*MailboxMessage, 0, len(recipients))
+
+	for _, recipient := range recipients {
+		messageID, err := mailbox.store.NextMessageID()
+		if err != nil {
+			mailbox.undeliver(delivered)
+			mailbox.mutex.Unlock()
+			return nil, err
+		}
+
+		header.ID = messageID
+		header.SenderPID = senderPID
+		header.ReceptionTime = newDateTime(now)
+		header.Recipient = &MessageRecipient{
+			ID:   recipient.ID,
+			Type: recipient.Type,
+		}
+
+		mailboxMessage := &MailboxMessage{
+			ID:            messageID,
+			RecipientType: recipient.Type,
+			RecipientID:   recipient.ID,
+			SenderPID:     senderPID,
+			ReceivedAt:    now,
+			ClassName:     message.ClassName(),
+			Data:          message.Bytes(nex.NewStreamOut(mailbox.server)),
+		}
+
+		if lifetime != 0 {
+			mailboxMessage.ExpiresAt = now.Add(lifetime)
+		}
+
+		err = mailbox.store.Add(mailboxMessage)
+		if err != nil {
+			mailbox.undeliver(delivered)
+			mailbox.mutex.Unlock()
+			return nil, err
+		}
+
+		delivered = append(delivered, mailboxMessage)
+	}
+
+	mailbox.mutex.Unlock()
+
+	if mailbox.MessageDeliveredHandler != nil {
+		for _, mailboxMessage := range delivered {
+			mailbox.MessageDeliveredHandler(mailboxMessage.Copy())
+		}
+	}
+
+	return delivered, nil
+}
+
+// Messages returns the unexpired messages of a mailbox within resultRange, ordered by ID
+func (mailbox *Mailbox) Messages(pid uint32, recipient *MessageRecipient, resultRange *ResultRange) ([]*MailboxMessage, error) {
+	messages, err := mailbox.messages(pid, recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := resultRange.Bounds(len(messages))
+
+	return messages[start:end], nil
+}
+
+// Count returns the number of unexpired messages in a mailbox
+func (mailbox *Mailbox) Count(pid uint32, recipient *MessageRecipient) (int, error) {
+	messages, err := mailbox.messages(pid, recipient)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(messages), nil
+}
+
+// Retrieve returns the unexpired messages of a mailbox with the given IDs, removing them unless leaveOnServer is set
+func (mailbox *Mailbox) Retrieve(pid uint32, recipient *MessageRecipient, messageIDs []uint32, leaveOnServer bool) ([]*MailboxMessage, error) {
+	mailbox.mutex.Lock()
+	defer mailbox.mutex.Unlock()
+
+	messages, err := mailbox.messages(pid, recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	retrieved := make([]*MailboxMessage, 0, len(messageIDs))
+
+	for _, message := range messages {
+		if containsMessageID(messageIDs, message.ID) {
+			retrieved = append(retrieved, message)
+		}
+	}
+
+	if !leaveOnServer && len(retrieved) != 0 {
+		_, err = mailbox.store.Delete(recipient.Type, recipient.ID, messageIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return retrieved, nil
+}
+
+// Delete removes the messages of a mailbox with the given IDs
+func (mailbox *Mailbox) Delete(pid uint32, recipient *MessageRecipient, messageIDs []uint32) error {
+	mailbox.mutex.Lock()
+	defer mailbox.mutex.Unlock()
+
+	err := mailbox.authorizeAccess(pid, recipient)
+	if err != nil {
+		return err
+	}
+
+	_, err = mailbox.store.Delete(recipient.Type, recipient.ID, messageIDs)
+
+	return err
+}
+
+// DeleteAll empties a mailbox and returns the number of messages removed
+func (mailbox *Mailbox) DeleteAll(pid uint32, recipient *MessageRecipient) (int, error) {
+	mailbox.mutex.Lock()
+	defer mailbox.mutex.Unlock()
+
+	err := mailbox.authorizeAccess(pid, recipient)
+	if err != nil {
+		return 0, err
+	}
+
+	return mailbox.store.DeleteAll(recipient.Type, recipient.ID)
+}
+
+// Reap removes every expired message and returns how many were removed
+func (mailbox *Mailbox) Reap() (int, error) {
+	mailbox.mutex.Lock()
+	defer mailbox.mutex.Unlock()
+
+	return mailbox.store.Expire(time.Now())
+}
+
+// StartReaper calls Reap every interval in the background until StopReaper is called
+func (mailbox *Mailbox) StartReaper(interval time.Duration) {
+	mailbox.StopReaper()
+
+	stop := make(chan struct{})
+
+	mailbox.mutex.Lock()
+	mailbox.stopReaper = stop
+	mailbox.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				_, err := mailbox.Reap()
+				if err != nil {
+					log.Printf("[Warning] Mailbox::Reap failed: %s\n", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopReaper stops the background reaper started by StartReaper
+func (mailbox *Mailbox) StopReaper() {
+	mailbox.mutex.Lock()
+	defer mailbox.mutex.Unlock()
+
+	if mailbox.stopReaper != nil {
+		close(mailbox.stopReaper)
+		mailbox.stopReaper = nil
+	}
+}
+
+// UseGatheringManager restricts gathering mailboxes to the participants of their gathering and
+// empties the mailbox of a gathering once it is terminated. Handlers already set on manager keep being called
+func (mailbox *Mailbox) UseGatheringManager(manager *GatheringManager) {
+	mailbox.gatheringManager = manager
+
+	gatheringTerminatedHandler := manager.GatheringTerminatedHandler
+	manager.OnGatheringTerminated(func(gathering *Gathering, participants []uint32) {
+		mailbox.mutex.Lock()
+		_, err := mailbox.store.DeleteAll(MessageRecipientTypeGathering, gathering.ID)
+		mailbox.mutex.Unlock()
+
+		if err != nil {
+			log.Printf("[Warning] Mailbox could not empty the mailbox of gathering %d: %s\n", gathering.ID, err)
+		}
+
+		if gatheringTerminatedHandler != nil {
+			gatheringTerminatedHandler(gathering, participants)
+		}
+	})
+}
+
+// OnMessageDelivered sets the function called for each message stored by Deliver
+func (mailbox *Mailbox) OnMessageDelivered(handler func(message *MailboxMessage)) {
+	mailbox.MessageDeliveredHandler = handler
+}
+
+// HandleDeliverMessage is the default MessagingProtocol::DeliverMessage handler. The recipient is read from the message
+func (mailbox *Mailbox) HandleDeliverMessage(err error, client *nex.Client, callID uint32, message *DataHolder) {
+	if err != nil {
+		respondError(client, MessagingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	userMessage, err := DecodeUserMessage(message, client.Server())
+	if err != nil {
+		respondError(client, MessagingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	_, err = mailbox.Deliver(client.PID(), []*MessageRecipient{userMessage.Header().Recipient}, userMessage)
+	if err != nil {
+		respondError(client, MessagingProtocolID, callID, mailboxResultCode(err))
+		return
+	}
+
+	respondSuccess(client, MessagingProtocolID, MessagingMethodDeliverMessage, callID, make([]byte, 0))
+}
+
+// HandleDeliverMessageMultiTarget is the default MessagingProtocol::DeliverMessageMultiTarget handler
+func (mailbox *Mailbox) HandleDeliverMessageMultiTarget(err error, client *nex.Client, callID uint32, recipients []*MessageRecipient, message *DataHolder) {
+	if err != nil {
+		respondError(client, MessagingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	userMessage, err := DecodeUserMessage(message, client.Server())
+	if err != nil {
+		respondError(client, MessagingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	_, err = mailbox.Deliver(client.PID(), recipients, userMessage)
+	if err != nil {
+		respondError(client, MessagingProtocolID, callID, mailboxResultCode(err))
+		return
+	}
+
+	respondSuccess(client, MessagingProtocolID, MessagingMethodDeliverMessageMultiTarget, callID, make([]byte, 0))
+}
+
+// HandleGetNumberOfMessages is the default MessagingProtocol::GetNumberOfMessages handler
+func (mailbox *Mailbox) HandleGetNumberOfMessages(err error, client *nex.Client, callID uint32, recipient *MessageRecipient) {
+	if err != nil {
+		respondError(client, MessagingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	count, err := mailbox.Count(client.PID(), recipient)
+	if err != nil {
+		respondError(client, MessagingProtocolID, callID, mailboxResultCode(err))
+		return
+	}
+
+	respondMessageCount(client, callID, MessagingMethodGetNumberOfMessages, uint32(count))
+}
+
+// HandleGetMessageHeaders is the default MessagingProtocol::GetMessageHeaders handler
+func (mailbox *Mailbox) HandleGetMessageHeaders(err error, client *nex.Client, callID uint32, pid uint32, recipientType uint32, rangeOffset uint32, rangeSize uint32) {
+	if err != nil {
+		respondError(client, MessagingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	recipient := &MessageRecipient{ID: pid, Type: recipientType}
+	resultRange := &ResultRange{Offset: rangeOffset, Size: rangeSize}
+
+	messages, err := mailbox.Messages(client.PID(), recipient, resultRange)
+	if err != nil {
+		respondError(client, MessagingProtocolID, callID, mailboxResultCode(err))
+		return
+	}
+
+	headers := make([]*UserMessage, 0, len(messages))
+
+	for _, message := range messages {
+		userMessage, err := DecodeUserMessage(message.DataHolder(), client.Server())
+		if err != nil {
+			log.Printf("[Warning] Mailbox could not decode message %d: %s\n", message.ID, err)
+			continue
+		}
+
+		headers = append(headers, userMessage.Header().Copy())
+	}
+
+	respondMessageHeaders(client, callID, headers)
+}
+
+// HandleRetrieveAllMessagesWithinRange is the default MessagingProtocol::RetrieveAllMessagesWithinRange handler.
+// Messages are left on the server
+func (mailbox *Mailbox) HandleRetrieveAllMessagesWithinRange(err error, client *nex.Client, callID uint32, recipient *MessageRecipient, resultRange *ResultRange) {
+	if err != nil {
+		respondError(client, MessagingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	messages, err := mailbox.Messages(client.PID(), recipient, resultRange)
+	if err != nil {
+		respondError(client, MessagingProtocolID, callID, mailboxResultCode(err))
+		return
+	}
+
+	respondMessages(client, callID, MessagingMethodRetrieveAllMessagesWithinRange, mailboxDataHolders(messages))
+}
+
+// HandleRetrieveMessages is the default MessagingProtocol::RetrieveMessages handler
+func (mailbox *Mailbox) HandleRetrieveMessages(err error, client *nex.Client, callID uint32, recipient *MessageRecipient, messageIDs []uint32, leaveOnServer bool) {
+	if err != nil {
+		respondError(client, MessagingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	messages, err := mailbox.Retrieve(client.PID(), recipient, messageIDs, leaveOnServer)
+	if err != nil {
+		respondError(client, MessagingProtocolID, callID, mailboxResultCode(err))
+		return
+	}
+
+	respondMessages(client, callID, MessagingMethodRetrieveMessages, mailboxDataHolders(messages))
+}
+
+// HandleDeleteMessages is the default MessagingProtocol::DeleteMessages handler
+func (mailbox *Mailbox) HandleDeleteMessages(err error, client *nex.Client, callID uint32, recipient *MessageRecipient, messageIDs []uint32) {
+	if err != nil {
+		respondError(client, MessagingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	err = mailbox.Delete(client.PID(), recipient, messageIDs)
+	if err != nil {
+		respondError(client, MessagingProtocolID, callID, mailboxResultCode(err))
+		return
+	}
+
+	respondSuccess(client, MessagingProtocolID, MessagingMethodDeleteMessages, callID, make([]byte, 0))
+}
+
+// HandleDeleteAllMessages is the default MessagingProtocol::DeleteAllMessages handler
+func (mailbox *Mailbox) HandleDeleteAllMessages(err error, client *nex.Client, callID uint32, recipient *MessageRecipient) {
+	if err != nil {
+		respondError(client, MessagingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	count, err := mailbox.DeleteAll(client.PID(), recipient)
+	if err != nil {
+		respondError(client, MessagingProtocolID, callID, mailboxResultCode(err))
+		return
+	}
+
+	respondMessageCount(client, callID, MessagingMethodDeleteAllMessages, uint32(count))
+}
+
+// messages returns the unexpired messages of a mailbox pid may read, ordered by ID
+func (mailbox *Mailbox) messages(pid uint32, recipient *MessageRecipient) ([]*MailboxMessage, error) {
+	err := mailbox.authorizeAccess(pid, recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := mailbox.store.Messages(recipient.Type, recipient.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*MailboxMessage, 0, len(stored))
+	now := time.Now()
+
+	for _, message := range stored {
+		if !message.Expired(now) {
+			messages = append(messages, message)
+		}
+	}
+
+	return messages, nil
+}
+
+// undeliver removes the copies of a message stored by a Deliver call which failed. The caller must hold the lock
+func (mailbox *Mailbox) undeliver(delivered []*MailboxMessage) {
+	for _, mailboxMessage := range delivered {
+		_, err := mailbox.store.Delete(mailboxMessage.RecipientType, mailboxMessage.RecipientID, []uint32{mailboxMessage.ID})
+		if err != nil {
+			log.Printf("[Warning] Mailbox could not remove message %d of a failed delivery: %s\n", mailboxMessage.ID, err)
+		}
+	}
+}
+
+// authorizeAccess checks that pid may read and delete the messages of a mailbox
+func (mailbox *Mailbox) authorizeAccess(pid uint32, recipient *MessageRecipient) error {
+	switch recipient.Type {
+	case MessageRecipientTypePID:
+		if recipient.ID != pid {
+			return ErrMailboxAccessDenied
+		}
+
+		return nil
+	case MessageRecipientTypeGathering:
+		return mailbox.authorizeGathering(pid, recipient.ID)
+	default:
+		return ErrInvalidRecipient
+	}
+}
+
+// authorizeDelivery checks that senderPID may write to a mailbox which has room left. The caller must hold the lock
+func (mailbox *Mailbox) authorizeDelivery(senderPID uint32, recipient *MessageRecipient) error {
+	switch recipient.Type {
+	case MessageRecipientTypePID:
+	case MessageRecipientTypeGathering:
+		err := mailbox.authorizeGathering(senderPID, recipient.ID)
+		if err != nil {
+			return err
+		}
+	default:
+		return ErrInvalidRecipient
+	}
+
+	if mailbox.Quota == 0 {
+		return nil
+	}
+
+	stored, err := mailbox.store.Messages(recipient.Type, recipient.ID)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	now := time.Now()
+
+	for _, message := range stored {
+		if !message.Expired(now) {
+			count++
+		}
+	}
+
+	if count >= mailbox.Quota {
+		return ErrMailboxFull
+	}
+
+	return nil
+}
+
+// authorizeGathering checks that pid participates in a gathering, when a GatheringManager is set
+func (mailbox *Mailbox) authorizeGathering(pid uint32, gatheringID uint32) error {
+	if mailbox.gatheringManager == nil {
+		return nil
+	}
+
+	gathering := mailbox.gatheringManager.Gathering(gatheringID)
+	if gathering == nil {
+		return ErrGatheringNotFound
+	}
+
+	if gathering.OwnerPID != pid && indexOfPID(mailbox.gatheringManager.Participants(gatheringID), pid) == -1 {
+		return ErrNotParticipating
+	}
+
+	return nil
+}
+
+// lifetime returns how long a message sent with a lifetime of seconds is kept
+func (mailbox *Mailbox) lifetime(seconds uint32) time.Duration {
+	lifetime := time.Duration(seconds) * time.Second
+	if lifetime == 0 {
+		lifetime = mailbox.DefaultLifetime
+	}
+
+	if mailbox.MaxLifetime != 0 && (lifetime == 0 || lifetime > mailbox.MaxLifetime) {
+		lifetime = mailbox.MaxLifetime
+	}
+
+	return lifetime
+}
+
+// uniqueMessageRecipients returns recipients without the mailboxes listed more than once
+func uniqueMessageRecipients(recipients []*MessageRecipient) []*MessageRecipient {
+	unique := make([]*MessageRecipient, 0, len(recipients))
+
+	for _, recipient := range recipients {
+		duplicate := false
+
+		for _, kept := range unique {
+			if kept.Type == recipient.Type && kept.ID == recipient.ID {
+				duplicate = true
+				break
+			}
+		}
+
+		if !duplicate {
+			unique = append(unique, recipient)
+		}
+	}
+
+	return unique
+}
+
+// mailboxDataHolders returns the data holders of messages
+func mailboxDataHolders(messages []*MailboxMessage) []*DataHolder {
+	dataHolders := make([]*DataHolder, 0, len(messages))
+
+	for _, message := range messages {
+		dataHolders = append(dataHolders, message.DataHolder())
+	}
+
+	return dataHolders
+}
+
+// mailboxResultCode maps a Mailbox error to its Quazal result code. Quazal has no dedicated quota error,
+// so a full mailbox is reported as access denied
+func mailboxResultCode(err error) uint32 {
+	switch err {
+	case ErrMailboxAccessDenied, ErrMailboxFull:
+		return ResultCoreAccessDenied
+	case ErrInvalidRecipient:
+		return ResultCoreInvalidArgument
+	default:
+		return gatheringResultCode(err)
+	}
+}
+
+// NewMailbox returns a new Mailbox keeping its messages in store, holding up to 100 messages per mailbox
+func NewMailbox(server *nex.Server, store MailboxStore) *Mailbox {
+	return &Mailbox{
+		server: server,
+		store:  store,
+		Quota:  100,
+	}
+}
@@ -0,0 +1,406 @@
+package nexproto
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MailboxMessage is a message kept in a mailbox, with the encoded message it was delivered as
+type MailboxMessage struct {
+	ID            uint32
+	RecipientType uint32
+	RecipientID   uint32
+	SenderPID     uint32
+	ReceivedAt    time.Time
+	ExpiresAt     time.Time // zero keeps the message until it is deleted
+	ClassName     string
+	Data          []byte
+}
+
+// DataHolder returns the message wrapped in a data holder, as sent to clients
+func (mailboxMessage *MailboxMessage) DataHolder() *DataHolder {
+	return &DataHolder{
+		ClassName: mailboxMessage.ClassName,
+		Data:      append([]byte(nil), mailboxMessage.Data...),
+	}
+}
+
+// Expired returns whether the message has outlived its lifetime at now
+func (mailboxMessage *MailboxMessage) Expired(now time.Time) bool {
+	return !mailboxMessage.ExpiresAt.IsZero() && now.After(mailboxMessage.ExpiresAt)
+}
+
+// Copy returns a deep copy of the MailboxMessage
+func (mailboxMessage *MailboxMessage) Copy() *MailboxMessage {
+	copied := *mailboxMessage
+	copied.Data = append([]byte(nil), mailboxMessage.Data...)
+
+	return &copied
+}
+
+// MailboxStore keeps the messages of every mailbox. A mailbox is identified by a recipient type and ID.
+// Implementations must be safe for concurrent use
+type MailboxStore interface {
+	// NextMessageID returns a message ID never returned before
+	NextMessageID() (uint32, error)
+
+	// Add stores a message in the mailbox of its recipient
+	Add(message *MailboxMessage) error
+
+	// Messages returns the messages of a mailbox ordered by ID
+	Messages(recipientType uint32, recipientID uint32) ([]*MailboxMessage, error)
+
+	// Delete removes the messages of a mailbox with the given IDs and returns how many were removed
+	Delete(recipientType uint32, recipientID uint32, messageIDs []uint32) (int, error)
+
+	// DeleteAll empties a mailbox and returns how many messages were removed
+	DeleteAll(recipientType uint32, recipientID uint32) (int, error)
+
+	// Expire removes the messages of every mailbox which expired at now and returns how many were removed
+	Expire(now time.Time) (int, error)
+}
+
+// mailboxKey identifies a mailbox within a MemoryMailboxStore
+type mailboxKey struct {
+	recipientType uint32
+	recipientID   uint32
+}
+
+// MemoryMailboxStore is a MailboxStore which keeps messages in memory
+type MemoryMailboxStore struct {
+	mutex         sync.Mutex
+	mailboxes     map[mailboxKey][]*MailboxMessage
+	lastMessageID uint32
+}
+
+// NextMessageID returns a message ID never returned before
+func (store *MemoryMailboxStore) NextMessageID() (uint32, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.lastMessageID++
+
+	return store.lastMessageID, nil
+}
+
+// Add stores a message in the mailbox of its recipient
+func (store *MemoryMailboxStore) Add(message *MailboxMessage) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	key := mailboxKey{message.RecipientType, message.RecipientID}
+	store.mailboxes[key] = insertMailboxMessage(store.mailboxes[key], message.Copy())
+
+	return nil
+}
+
+// Messages returns the messages of a mailbox ordered by ID
+func (store *MemoryMailboxStore) Messages(recipientType uint32, recipientID uint32) ([]*MailboxMessage, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	stored := store.mailboxes[mailboxKey{recipientType, recipientID}]
+	messages := make([]*MailboxMessage, 0, len(stored))
+
+	for _, message := range stored {
+		messages = append(messages, message.Copy())
+	}
+
+	return messages, nil
+}
+
+// Delete removes the messages of a mailbox with the given IDs and returns how many were removed
+func (store *MemoryMailboxStore) Delete(recipientType uint32, recipientID uint32, messageIDs []uint32) (int, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	key := mailboxKey{recipientType, recipientID}
+
+	kept, removed := removeMailboxMessages(store.mailboxes[key], func(message *MailboxMessage) bool {
+		return containsMessageID(messageIDs, message.ID)
+	})
+
+	store.setMailbox(key, kept)
+
+	return removed, nil
+}
+
+// DeleteAll empties a mailbox and returns how many messages were removed
+func (store *MemoryMailboxStore) DeleteAll(recipientType uint32, recipientID uint32) (int, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	key := mailboxKey{recipientType, recipientID}
+	removed := len(store.mailboxes[key])
+
+	delete(store.mailboxes, key)
+
+	return removed, nil
+}
+
+// Expire removes the messages of every mailbox which expired at now and returns how many were removed
+func (store *MemoryMailboxStore) Expire(now time.Time) (int, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	total := 0
+
+	for key, messages := range store.mailboxes {
+		kept, removed := removeMailboxMessages(messages, func(message *MailboxMessage) bool {
+			return message.Expired(now)
+		})
+
+		store.setMailbox(key, kept)
+		total += removed
+	}
+
+	return total, nil
+}
+
+// setMailbox replaces the messages of a mailbox, forgetting it once empty. The caller must hold the lock
+func (store *MemoryMailboxStore) setMailbox(key mailboxKey, messages []*MailboxMessage) {
+	if len(messages) == 0 {
+		delete(store.mailboxes, key)
+	} else {
+		store.mailboxes[key] = messages
+	}
+}
+
+// NewMemoryMailboxStore returns a new MemoryMailboxStore
+func NewMemoryMailboxStore() *MemoryMailboxStore {
+	return &MemoryMailboxStore{
+		mailboxes: make(map[mailboxKey][]*MailboxMessage),
+	}
+}
+
+// FileMailboxStore is a MailboxStore which keeps each mailbox in a JSON file of a directory,
+// so messages survive server restarts
+type FileMailboxStore struct {
+	mutex     sync.Mutex
+	directory string
+}
+
+// fileMailboxSequence is the name of the file holding the last message ID handed out
+const fileMailboxSequence = "sequence"
+
+// NextMessageID returns a message ID never returned before
+func (store *FileMailboxStore) NextMessageID() (uint32, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	var lastMessageID uint32
+
+	err := store.read(fileMailboxSequence, &lastMessageID)
+	if err != nil {
+		return 0, err
+	}
+
+	lastMessageID++
+
+	err = store.write(fileMailboxSequence, lastMessageID)
+	if err != nil {
+		return 0, err
+	}
+
+	return lastMessageID, nil
+}
+
+// Add stores a message in the mailbox of its recipient
+func (store *FileMailboxStore) Add(message *MailboxMessage) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	name := fileMailboxName(message.RecipientType, message.RecipientID)
+
+	messages, err := store.mailbox(name)
+	if err != nil {
+		return err
+	}
+
+	return store.write(name, insertMailboxMessage(messages, message))
+}
+
+// Messages returns the messages of a mailbox ordered by ID
+func (store *FileMailboxStore) Messages(recipientType uint32, recipientID uint32) ([]*MailboxMessage, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.mailbox(fileMailboxName(recipientType, recipientID))
+}
+
+// Delete removes the messages of a mailbox with the given IDs and returns how many were removed
+func (store *FileMailboxStore) Delete(recipientType uint32, recipientID uint32, messageIDs []uint32) (int, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.remove(fileMailboxName(recipientType, recipientID), func(message *MailboxMessage) bool {
+		return containsMessageID(messageIDs, message.ID)
+	})
+}
+
+// DeleteAll empties a mailbox and returns how many messages were removed
+func (store *FileMailboxStore) DeleteAll(recipientType uint32, recipientID uint32) (int, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.remove(fileMailboxName(recipientType, recipientID), func(message *MailboxMessage) bool {
+		return true
+	})
+}
+
+// Expire removes the messages of every mailbox which expired at now and returns how many were removed
+func (store *FileMailboxStore) Expire(now time.Time) (int, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	names, err := filepath.Glob(filepath.Join(store.directory, "mailbox-*.json"))
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+
+	for _, path := range names {
+		removed, err := store.remove(strings.TrimSuffix(filepath.Base(path), ".json"), func(message *MailboxMessage) bool {
+			return message.Expired(now)
+		})
+		if err != nil {
+			return total, err
+		}
+
+		total += removed
+	}
+
+	return total, nil
+}
+
+// mailbox reads the messages of the mailbox file name. The caller must hold the lock
+func (store *FileMailboxStore) mailbox(name string) ([]*MailboxMessage, error) {
+	messages := make([]*MailboxMessage, 0)
+
+	err := store.read(name, &messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// remove deletes the messages of the mailbox file name matching drop. The caller must hold the lock
+func (store *FileMailboxStore) remove(name string, drop func(message *MailboxMessage) bool) (int, error) {
+	messages, err := store.mailbox(name)
+	if err != nil {
+		return 0, err
+	}
+
+	kept, removed := removeMailboxMessages(messages, drop)
+	if removed == 0 {
+		return 0, nil
+	}
+
+	if len(kept) == 0 {
+		err = os.Remove(store.path(name))
+	} else {
+		err = store.write(name, kept)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return removed, nil
+}
+
+// read decodes the JSON file name into value, leaving value untouched if the file does not exist
+func (store *FileMailboxStore) read(name string, value interface{}) error {
+	data, err := os.ReadFile(store.path(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, value)
+}
+
+// write replaces the JSON file name with value. The file is renamed into place so a crash never leaves it half written
+func (store *FileMailboxStore) write(name string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	temporaryPath := store.path(name) + ".tmp"
+
+	err = os.WriteFile(temporaryPath, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temporaryPath, store.path(name))
+}
+
+// path returns the path of the JSON file name
+func (store *FileMailboxStore) path(name string) string {
+	return filepath.Join(store.directory, name+".json")
+}
+
+// fileMailboxName returns the file name of a mailbox, without extension
+func fileMailboxName(recipientType uint32, recipientID uint32) string {
+	return fmt.Sprintf("mailbox-%d-%d", recipientType, recipientID)
+}
+
+// NewFileMailboxStore returns a new FileMailboxStore keeping its files in directory, which is created if needed
+func NewFileMailboxStore(directory string) (*FileMailboxStore, error) {
+	err := os.MkdirAll(directory, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileMailboxStore{directory: directory}, nil
+}
+
+// insertMailboxMessage adds message to messages, keeping them ordered by ID
+func insertMailboxMessage(messages []*MailboxMessage, message *MailboxMessage) []*MailboxMessage {
+	index := sort.Search(len(messages), func(i int) bool {
+		return messages[i].ID >= message.ID
+	})
+
+	messages = append(messages, nil)
+	copy(messages[index+1:], messages[index:])
+	messages[index] = message
+
+	return messages
+}
+
+// removeMailboxMessages splits messages into the ones kept and the number matching drop
+func removeMailboxMessages(messages []*MailboxMessage, drop func(message *MailboxMessage) bool) ([]*MailboxMessage, int) {
+	kept := make([]*MailboxMessage, 0, len(messages))
+
+	for _, message := range messages {
+		if !drop(message) {
+			kept = append(kept, message)
+		}
+	}
+
+	return kept, len(messages) - len(kept)
+}
+
+// containsMessageID returns whether messageID is in messageIDs
+func containsMessageID(messageIDs []uint32, messageID uint32) bool {
+	for _, id := range messageIDs {
+		if id == messageID {
+			return true
+		}
+	}
+
+	return false
+}
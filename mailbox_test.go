@@ -0,0 +1,190 @@
+package nexproto
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+// errTestMailboxStore is returned by failingMailboxStore
+var errTestMailboxStore = errors.New("mailbox store failed")
+
+// failingMailboxStore is a MemoryMailboxStore whose Add fails once it has succeeded limit times
+type failingMailboxStore struct {
+	*MemoryMailboxStore
+	limit int
+}
+
+// Add stores message unless the limit is reached
+func (store *failingMailboxStore) Add(message *MailboxMessage) error {
+	if store.limit == 0 {
+		return errTestMailboxStore
+	}
+
+	store.limit--
+
+	return store.MemoryMailboxStore.Add(message)
+}
+
+// newTestTextMessage returns a TextMessage with body
+func newTestTextMessage(body string) *TextMessage {
+	message := NewTextMessage()
+	message.TextBody = body
+
+	return message
+}
+
+// testMailbox runs the Mailbox checks shared by every MailboxStore
+func testMailbox(t *testing.T, store MailboxStore) {
+	server := nex.NewServer()
+	mailbox := NewMailbox(server, store)
+	mailbox.Quota = 2
+
+	manager := NewGatheringManager()
+	mailbox.UseGatheringManager(manager)
+
+	gatheringID := manager.Register(1, NewGathering())
+	gathering := &MessageRecipient{ID: gatheringID, Type: MessageRecipientTypeGathering}
+	user := &MessageRecipient{ID: 5, Type: MessageRecipientTypePID}
+
+	if _, err := mailbox.Deliver(2, []*MessageRecipient{gathering}, newTestTextMessage("hello")); err != ErrNotParticipating {
+		t.Fatalf("delivery to a gathering by an outsider returned %v", err)
+	}
+
+	if _, err := mailbox.Deliver(1, []*MessageRecipient{gathering, user}, newTestTextMessage("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	expiring := newTestTextMessage("hello")
+	expiring.LifeTime = 1
+
+	if _, err := mailbox.Deliver(1, []*MessageRecipient{user}, expiring); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := mailbox.Deliver(1, []*MessageRecipient{user}, expiring); err != ErrMailboxFull {
+		t.Fatalf("delivery to a full mailbox returned %v", err)
+	}
+
+	if _, err := mailbox.Count(4, user); err != ErrMailboxAccessDenied {
+		t.Fatalf("counting the mailbox of another PID returned %v", err)
+	}
+
+	messages, err := mailbox.Messages(5, user, &ResultRange{Offset: 1, Size: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 1 || messages[0].ID != 3 || messages[0].ExpiresAt.IsZero() {
+		t.Fatalf("Messages returned %v", messages)
+	}
+
+	userMessage, err := DecodeUserMessage(messages[0].DataHolder(), server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if header := userMessage.Header(); header.ID != 3 || header.SenderPID != 1 || userMessage.(*TextMessage).TextBody != "hello" {
+		t.Fatalf("stored message decoded as %+v", userMessage)
+	}
+
+	retrieved, err := mailbox.Retrieve(5, user, []uint32{2, 99}, false)
+	if err != nil || len(retrieved) != 1 {
+		t.Fatalf("Retrieve returned %v, %v", retrieved, err)
+	}
+
+	if count, _ := mailbox.Count(5, user); count != 1 {
+		t.Fatalf("%d messages left after retrieving one", count)
+	}
+
+	if expired, err := store.Expire(time.Now().Add(2 * time.Second)); expired != 1 || err != nil {
+		t.Fatalf("Expire removed %d messages, %v", expired, err)
+	}
+
+	if count, _ := mailbox.Count(1, gathering); count != 1 {
+		t.Fatalf("gathering mailbox holds %d messages", count)
+	}
+
+	if err := manager.Terminate(1, gatheringID); err != nil {
+		t.Fatal(err)
+	}
+
+	if messages, _ := store.Messages(MessageRecipientTypeGathering, gatheringID); len(messages) != 0 {
+		t.Fatal("messages of a terminated gathering were kept")
+	}
+}
+
+func TestMailboxMemoryStore(t *testing.T) {
+	testMailbox(t, NewMemoryMailboxStore())
+}
+
+func TestMailboxFileStore(t *testing.T) {
+	store, err := NewFileMailboxStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testMailbox(t, store)
+}
+
+func TestFileMailboxStoreReopen(t *testing.T) {
+	directory := t.TempDir()
+
+	store, err := NewFileMailboxStore(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mailbox := NewMailbox(nex.NewServer(), store)
+
+	if _, err := mailbox.Deliver(1, []*MessageRecipient{{ID: 5, Type: MessageRecipientTypePID}}, newTestTextMessage("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewFileMailboxStore(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := reopened.Messages(MessageRecipientTypePID, 5)
+	if err != nil || len(messages) != 1 || messages[0].SenderPID != 1 {
+		t.Fatalf("reopened store returned %v, %v", messages, err)
+	}
+
+	if messageID, err := reopened.NextMessageID(); err != nil || messageID != 2 {
+		t.Fatalf("reopened store handed out message ID %d, %v", messageID, err)
+	}
+}
+
+func TestMailboxDeliverRollback(t *testing.T) {
+	store := &failingMailboxStore{MemoryMailboxStore: NewMemoryMailboxStore(), limit: 1}
+	mailbox := NewMailbox(nex.NewServer(), store)
+
+	recipients := []*MessageRecipient{{ID: 5, Type: MessageRecipientTypePID}, {ID: 6, Type: MessageRecipientTypePID}}
+
+	if _, err := mailbox.Deliver(1, recipients, NewTextMessage()); err != errTestMailboxStore {
+		t.Fatalf("failed delivery returned %v", err)
+	}
+
+	if messages, _ := store.Messages(MessageRecipientTypePID, 5); len(messages) != 0 {
+		t.Fatal("failed delivery kept the messages already stored")
+	}
+}
+
+func TestMailboxDeliverDuplicateRecipients(t *testing.T) {
+	mailbox := NewMailbox(nex.NewServer(), NewMemoryMailboxStore())
+	mailbox.Quota = 1
+
+	recipient := &MessageRecipient{ID: 5, Type: MessageRecipientTypePID}
+
+	delivered, err := mailbox.Deliver(1, []*MessageRecipient{recipient, recipient}, NewTextMessage())
+	if err != nil || len(delivered) != 1 {
+		t.Fatalf("delivery to a repeated recipient returned %v, %v", delivered, err)
+	}
+
+	if _, err := mailbox.Deliver(1, []*MessageRecipient{recipient}, NewTextMessage()); err != ErrMailboxFull {
+		t.Fatalf("delivery to a full mailbox returned %v", err)
+	}
+}
@@ -0,0 +1,611 @@
+package nexproto
+
+import (
+	"errors"
+	"log"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+const (
+	// MatchmakeExtensionProtocolID is the protocol ID for the MatchmakeExtension protocol
+	MatchmakeExtensionProtocolID = 0x6D
+
+	// MatchmakeExtensionMethodAutoMatchmakePostpone is the method ID for method AutoMatchmake_Postpone
+	MatchmakeExtensionMethodAutoMatchmakePostpone = 0x3
+
+	// MatchmakeExtensionMethodBrowseMatchmakeSession is the method ID for method BrowseMatchmakeSession
+	MatchmakeExtensionMethodBrowseMatchmakeSession = 0x4
+
+	// MatchmakeExtensionMethodCreateMatchmakeSession is the method ID for method CreateMatchmakeSession
+	MatchmakeExtensionMethodCreateMatchmakeSession = 0x6
+
+	// MatchmakeExtensionMethodJoinMatchmakeSession is the method ID for method JoinMatchmakeSession
+	MatchmakeExtensionMethodJoinMatchmakeSession = 0x7
+
+	// MatchmakeExtensionMethodGetSimplePlayingSession is the method ID for method GetSimplePlayingSession
+	MatchmakeExtensionMethodGetSimplePlayingSession = 0x1F
+)
+
+// MatchmakeSessionClassName is the data holder class name of a MatchmakeSession
+const MatchmakeSessionClassName = "MatchmakeSession"
+
+const (
+	// matchmakeSessionKeyNexVersion is the first NEX version whose MatchmakeSession carries SessionKey
+	matchmakeSessionKeyNexVersion = 30000
+
+	// matchmakeSessionProgressScoreNexVersion is the first NEX version whose MatchmakeSession carries ProgressScore
+	matchmakeSessionProgressScoreNexVersion = 30400
+)
+
+// nexVersionAtLeast reports whether server speaks NEX version or later, false when there is no server
+func nexVersionAtLeast(server *nex.Server, version int) bool {
+	return server != nil && server.NexVersion() >= version
+}
+
+// MatchmakeSession is a Gathering extended with the matchmaking fields used by MatchmakeExtension.
+// ProgressScore and SessionKey were added by later NEX versions and are only encoded when the server's version has them
+type MatchmakeSession struct {
+	Gathering           *Gathering
+	GameMode            uint32
+	Attributes          []uint32
+	OpenParticipation   bool
+	MatchmakeSystemType uint32
+	ApplicationBuffer   []byte
+	ParticipationCount  uint32
+	ProgressScore       uint8
+	SessionKey          []byte
+
+	nex.Structure
+}
+
+// Bytes encodes the MatchmakeSession and returns a byte array
+func (matchmakeSession *MatchmakeSession) Bytes(stream *nex.StreamOut) []byte {
+	matchmakeSession.Gathering.writeHeader(stream)
+	stream.WriteUInt32LE(matchmakeSession.GameMode)
+	stream.WriteListUInt32LE(matchmakeSession.Attributes)
+	writeBool(stream, matchmakeSession.OpenParticipation)
+	stream.WriteUInt32LE(matchmakeSession.MatchmakeSystemType)
+	stream.WriteBuffer(matchmakeSession.ApplicationBuffer)
+	stream.WriteUInt32LE(matchmakeSession.ParticipationCount)
+
+	if nexVersionAtLeast(stream.Server, matchmakeSessionProgressScoreNexVersion) {
+		stream.WriteUInt8(matchmakeSession.ProgressScore)
+	}
+
+	if nexVersionAtLeast(stream.Server, matchmakeSessionKeyNexVersion) {
+		stream.WriteBuffer(matchmakeSession.SessionKey)
+	}
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a MatchmakeSession structure from a stream
+func (matchmakeSession *MatchmakeSession) ExtractFromStream(stream *nex.StreamIn) error {
+	gathering := NewGathering()
+
+	err := gathering.extractHeader(stream)
+	if err != nil {
+		return err
+	}
+
+	if len(stream.Bytes()[stream.ByteOffset():]) < 8 {
+		// length check for the following fixed-size data
+		// gameMode + attributes length
+		return errors.New("[MatchmakeSession::ExtractFromStream] Data size too small")
+	}
+
+	gameMode := stream.ReadUInt32LE()
+	attributes := stream.ReadListUInt32LE()
+
+	if len(stream.Bytes()[stream.ByteOffset():]) < 5 {
+		// length check for the following fixed-size data
+		// openParticipation + matchmakeSystemType
+		return errors.New("[MatchmakeSession::ExtractFromStream] Data size too small")
+	}
+
+	openParticipation := stream.ReadUInt8() == 1
+	matchmakeSystemType := stream.ReadUInt32LE()
+	applicationBuffer, err := stream.ReadBuffer()
+	if err != nil {
+		return err
+	}
+
+	if len(stream.Bytes()[stream.ByteOffset():]) < 4 {
+		return errors.New("[MatchmakeSession::ExtractFromStream] Data missing participation count")
+	}
+
+	participationCount := stream.ReadUInt32LE()
+
+	var progressScore uint8
+	sessionKey := make([]byte, 0)
+
+	if nexVersionAtLeast(stream.Server, matchmakeSessionProgressScoreNexVersion) {
+		if len(stream.Bytes()[stream.ByteOffset():]) < 1 {
+			return errors.New("[MatchmakeSession::ExtractFromStream] Data missing progress score")
+		}
+
+		progressScore = stream.ReadUInt8()
+	}
+
+	if nexVersionAtLeast(stream.Server, matchmakeSessionKeyNexVersion) {
+		sessionKey, err = stream.ReadBuffer()
+		if err != nil {
+			return err
+		}
+	}
+
+	matchmakeSession.Gathering = gathering
+	matchmakeSession.GameMode = gameMode
+	matchmakeSession.Attributes = attributes
+	matchmakeSession.OpenParticipation = openParticipation
+	matchmakeSession.MatchmakeSystemType = matchmakeSystemType
+	matchmakeSession.ApplicationBuffer = applicationBuffer
+	matchmakeSession.ParticipationCount = participationCount
+	matchmakeSession.ProgressScore = progressScore
+	matchmakeSession.SessionKey = sessionKey
+
+	return nil
+}
+
+// NewMatchmakeSession returns a new MatchmakeSession
+func NewMatchmakeSession() *MatchmakeSession {
+	return &MatchmakeSession{
+		Gathering:         NewGathering(),
+		Attributes:        make([]uint32, 0),
+		ApplicationBuffer: make([]byte, 0),
+		SessionKey:        make([]byte, 0),
+	}
+}
+
+// MatchmakeSessionSearchCriteria holds the filters of BrowseMatchmakeSession.
+// Numeric filters are sent as strings, an empty string matching any value.
+// VacantParticipants was added by a later revision and is only read when present
+type MatchmakeSessionSearchCriteria struct {
+	Attributes          []string
+	GameMode            string
+	MinParticipants     string
+	MaxParticipants     string
+	MatchmakeSystemType string
+	VacantOnly          bool
+	ExcludeLocked       bool
+	ExcludeNonHostPID   bool
+	SelectionMethod     uint32
+	VacantParticipants  uint16
+
+	nex.Structure
+}
+
+// Bytes encodes the MatchmakeSessionSearchCriteria and returns a byte array
+func (searchCriteria *MatchmakeSessionSearchCriteria) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt32LE(uint32(len(searchCriteria.Attributes)))
+
+	for _, attribute := range searchCriteria.Attributes {
+		write4ByteString(stream, attribute)
+	}
+
+	write4ByteString(stream, searchCriteria.GameMode)
+	write4ByteString(stream, searchCriteria.MinParticipants)
+	write4ByteString(stream, searchCriteria.MaxParticipants)
+	write4ByteString(stream, searchCriteria.MatchmakeSystemType)
+	writeBool(stream, searchCriteria.VacantOnly)
+	writeBool(stream, searchCriteria.ExcludeLocked)
+	writeBool(stream, searchCriteria.ExcludeNonHostPID)
+	stream.WriteUInt32LE(searchCriteria.SelectionMethod)
+	stream.WriteUInt16LE(searchCriteria.VacantParticipants)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a MatchmakeSessionSearchCriteria structure from a stream
+func (searchCriteria *MatchmakeSessionSearchCriteria) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 4 {
+		return errors.New("[MatchmakeSessionSearchCriteria::ExtractFromStream] Data missing attributes length")
+	}
+
+	length := stream.ReadUInt32LE()
+	attributes := make([]string, 0)
+
+	for i := 0; i < int(length); i++ {
+		attribute, err := stream.Read4ByteString()
+		if err != nil {
+			return err
+		}
+
+		attributes = append(attributes, attribute)
+	}
+
+	gameMode, err := stream.Read4ByteString()
+	if err != nil {
+		return err
+	}
+
+	minParticipants, err := stream.Read4ByteString()
+	if err != nil {
+		return err
+	}
+
+	maxParticipants, err := stream.Read4ByteString()
+	if err != nil {
+		return err
+	}
+
+	matchmakeSystemType, err := stream.Read4ByteString()
+	if err != nil {
+		return err
+	}
+
+	if len(stream.Bytes()[stream.ByteOffset():]) < 7 {
+		// length check for the following fixed-size data
+		// vacantOnly + excludeLocked + excludeNonHostPID + selectionMethod
+		return errors.New("[MatchmakeSessionSearchCriteria::ExtractFromStream] Data size too small")
+	}
+
+	vacantOnly := stream.ReadUInt8() == 1
+	excludeLocked := stream.ReadUInt8() == 1
+	excludeNonHostPID := stream.ReadUInt8() == 1
+	selectionMethod := stream.ReadUInt32LE()
+
+	var vacantParticipants uint16
+
+	if len(stream.Bytes()[stream.ByteOffset():]) >= 2 {
+		vacantParticipants = stream.ReadUInt16LE()
+	}
+
+	searchCriteria.Attributes = attributes
+	searchCriteria.GameMode = gameMode
+	searchCriteria.MinParticipants = minParticipants
+	searchCriteria.MaxParticipants = maxParticipants
+	searchCriteria.MatchmakeSystemType = matchmakeSystemType
+	searchCriteria.VacantOnly = vacantOnly
+	searchCriteria.ExcludeLocked = excludeLocked
+	searchCriteria.ExcludeNonHostPID = excludeNonHostPID
+	searchCriteria.SelectionMethod = selectionMethod
+	searchCriteria.VacantParticipants = vacantParticipants
+
+	return nil
+}
+
+// NewMatchmakeSessionSearchCriteria returns a new MatchmakeSessionSearchCriteria
+func NewMatchmakeSessionSearchCriteria() *MatchmakeSessionSearchCriteria {
+	return &MatchmakeSessionSearchCriteria{
+		Attributes: make([]string, 0),
+	}
+}
+
+// SimplePlayingSession describes the session a PID is currently playing in
+type SimplePlayingSession struct {
+	PrincipalID uint32
+	GatheringID uint32
+	GameMode    uint32
+	Attribute0  uint32
+
+	nex.Structure
+}
+
+// Bytes encodes the SimplePlayingSession and returns a byte array
+func (simplePlayingSession *SimplePlayingSession) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt32LE(simplePlayingSession.PrincipalID)
+	stream.WriteUInt32LE(simplePlayingSession.GatheringID)
+	stream.WriteUInt32LE(simplePlayingSession.GameMode)
+	stream.WriteUInt32LE(simplePlayingSession.Attribute0)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a SimplePlayingSession structure from a stream
+func (simplePlayingSession *SimplePlayingSession) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 16 {
+		// length check for the following fixed-size data
+		// principalID + gatheringID + gameMode + attribute0
+		return errors.New("[SimplePlayingSession::ExtractFromStream] Data size too small")
+	}
+
+	simplePlayingSession.PrincipalID = stream.ReadUInt32LE()
+	simplePlayingSession.GatheringID = stream.ReadUInt32LE()
+	simplePlayingSession.GameMode = stream.ReadUInt32LE()
+	simplePlayingSession.Attribute0 = stream.ReadUInt32LE()
+
+	return nil
+}
+
+// NewSimplePlayingSession returns a new SimplePlayingSession
+func NewSimplePlayingSession() *SimplePlayingSession {
+	return &SimplePlayingSession{}
+}
+
+// MatchmakeExtensionProtocol handles the MatchmakeExtension nex protocol
+type MatchmakeExtensionProtocol struct {
+	server                         *nex.Server
+	gatheringManager               *GatheringManager
+	AutoMatchmakeHandler           func(err error, client *nex.Client, callID uint32, matchmakeSession *MatchmakeSession, message string)
+	BrowseMatchmakeSessionHandler  func(err error, client *nex.Client, callID uint32, searchCriteria *MatchmakeSessionSearchCriteria, resultRange *ResultRange)
+	CreateMatchmakeSessionHandler  func(err error, client *nex.Client, callID uint32, matchmakeSession *MatchmakeSession, message string, participationCount uint16)
+	JoinMatchmakeSessionHandler    func(err error, client *nex.Client, callID uint32, gatheringID uint32, message string)
+	GetSimplePlayingSessionHandler func(err error, client *nex.Client, callID uint32, pids []uint32, includeLoginUser bool)
+}
+
+// Setup initializes the protocol
+func (matchmakeExtensionProtocol *MatchmakeExtensionProtocol) Setup() {
+	nexServer := matchmakeExtensionProtocol.server
+
+	trackPacketRoutes(nexServer)
+
+	nexServer.On("Data", func(packet nex.PacketInterface) {
+		request := packet.RMCRequest()
+
+		if MatchmakeExtensionProtocolID == request.ProtocolID() {
+			rememberPacketRoute(packet)
+
+			if matchmakeExtensionProtocol.gatheringManager != nil {
+				matchmakeExtensionProtocol.gatheringManager.connect(packet.Sender())
+			}
+
+			switch request.MethodID() {
+			case MatchmakeExtensionMethodAutoMatchmakePostpone:
+				go matchmakeExtensionProtocol.handleAutoMatchmake(packet)
+			case MatchmakeExtensionMethodBrowseMatchmakeSession:
+				go matchmakeExtensionProtocol.handleBrowseMatchmakeSession(packet)
+			case MatchmakeExtensionMethodCreateMatchmakeSession:
+				go matchmakeExtensionProtocol.handleCreateMatchmakeSession(packet)
+			case MatchmakeExtensionMethodJoinMatchmakeSession:
+				go matchmakeExtensionProtocol.handleJoinMatchmakeSession(packet)
+			case MatchmakeExtensionMethodGetSimplePlayingSession:
+				go matchmakeExtensionProtocol.handleGetSimplePlayingSession(packet)
+			default:
+				log.Printf("Unsupported MatchmakeExtension method ID: %#v\n", request.MethodID())
+			}
+		}
+	})
+
+	// Sessions are ordinary gatherings, so dropped clients leave them like any other gathering
+	dropParticipant := func(packet nex.PacketInterface) {
+		if matchmakeExtensionProtocol.gatheringManager == nil {
+			return
+		}
+
+		go matchmakeExtensionProtocol.gatheringManager.DropParticipant(packet.Sender())
+	}
+
+	nexServer.On("Disconnect", dropParticipant)
+	nexServer.On("Kick", dropParticipant)
+}
+
+// AutoMatchmake sets the AutoMatchmake_Postpone handler function
+func (matchmakeExtensionProtocol *MatchmakeExtensionProtocol) AutoMatchmake(handler func(err error, client *nex.Client, callID uint32, matchmakeSession *MatchmakeSession, message string)) {
+	matchmakeExtensionProtocol.AutoMatchmakeHandler = handler
+}
+
+// BrowseMatchmakeSession sets the BrowseMatchmakeSession handler function
+func (matchmakeExtensionProtocol *MatchmakeExtensionProtocol) BrowseMatchmakeSession(handler func(err error, client *nex.Client, callID uint32, searchCriteria *MatchmakeSessionSearchCriteria, resultRange *ResultRange)) {
+	matchmakeExtensionProtocol.BrowseMatchmakeSessionHandler = handler
+}
+
+// CreateMatchmakeSession sets the CreateMatchmakeSession handler function
+func (matchmakeExtensionProtocol *MatchmakeExtensionProtocol) CreateMatchmakeSession(handler func(err error, client *nex.Client, callID uint32, matchmakeSession *MatchmakeSession, message string, participationCount uint16)) {
+	matchmakeExtensionProtocol.CreateMatchmakeSessionHandler = handler
+}
+
+// JoinMatchmakeSession sets the JoinMatchmakeSession handler function
+func (matchmakeExtensionProtocol *MatchmakeExtensionProtocol) JoinMatchmakeSession(handler func(err error, client *nex.Client, callID uint32, gatheringID uint32, message string)) {
+	matchmakeExtensionProtocol.JoinMatchmakeSessionHandler = handler
+}
+
+// GetSimplePlayingSession sets the GetSimplePlayingSession handler function
+func (matchmakeExtensionProtocol *MatchmakeExtensionProtocol) GetSimplePlayingSession(handler func(err error, client *nex.Client, callID uint32, pids []uint32, includeLoginUser bool)) {
+	matchmakeExtensionProtocol.GetSimplePlayingSessionHandler = handler
+}
+
+// UseGatheringManager stores MatchmakeSessions in manager and installs its default handlers for every method.
+// Sessions are kept as gatherings of class MatchmakeSession next to the other gatherings of manager,
+// their session keys being kept out of the gathering data other protocols return
+func (matchmakeExtensionProtocol *MatchmakeExtensionProtocol) UseGatheringManager(manager *GatheringManager) {
+	matchmakeExtensionProtocol.gatheringManager = manager
+
+	matchmakeExtensionProtocol.AutoMatchmake(manager.HandleAutoMatchmake)
+	matchmakeExtensionProtocol.BrowseMatchmakeSession(manager.HandleBrowseMatchmakeSession)
+	matchmakeExtensionProtocol.CreateMatchmakeSession(manager.HandleCreateMatchmakeSession)
+	matchmakeExtensionProtocol.JoinMatchmakeSession(manager.HandleJoinMatchmakeSession)
+	matchmakeExtensionProtocol.GetSimplePlayingSession(manager.HandleGetSimplePlayingSession)
+}
+
+// GatheringManager returns the GatheringManager set with UseGatheringManager, if any
+func (matchmakeExtensionProtocol *MatchmakeExtensionProtocol) GatheringManager() *GatheringManager {
+	return matchmakeExtensionProtocol.gatheringManager
+}
+
+func (matchmakeExtensionProtocol *MatchmakeExtensionProtocol) handleAutoMatchmake(packet nex.PacketInterface) {
+	if matchmakeExtensionProtocol.AutoMatchmakeHandler == nil {
+		log.Println("[Warning] MatchmakeExtensionProtocol::AutoMatchmake not implemented")
+		go respondNotImplemented(packet, MatchmakeExtensionProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, matchmakeExtensionProtocol.server)
+
+	matchmakeSession, err := matchmakeExtensionProtocol.readMatchmakeSession(parametersStream)
+	if err != nil {
+		go matchmakeExtensionProtocol.AutoMatchmakeHandler(err, client, callID, nil, "")
+		return
+	}
+
+	message, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go matchmakeExtensionProtocol.AutoMatchmakeHandler(err, client, callID, nil, "")
+		return
+	}
+
+	go matchmakeExtensionProtocol.AutoMatchmakeHandler(nil, client, callID, matchmakeSession, message)
+}
+
+func (matchmakeExtensionProtocol *MatchmakeExtensionProtocol) handleBrowseMatchmakeSession(packet nex.PacketInterface) {
+	if matchmakeExtensionProtocol.BrowseMatchmakeSessionHandler == nil {
+		log.Println("[Warning] MatchmakeExtensionProtocol::BrowseMatchmakeSession not implemented")
+		go respondNotImplemented(packet, MatchmakeExtensionProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, matchmakeExtensionProtocol.server)
+
+	searchCriteriaStructureInterface, err := parametersStream.ReadStructure(NewMatchmakeSessionSearchCriteria())
+	if err != nil {
+		go matchmakeExtensionProtocol.BrowseMatchmakeSessionHandler(err, client, callID, nil, nil)
+		return
+	}
+
+	resultRangeStructureInterface, err := parametersStream.ReadStructure(NewResultRange())
+	if err != nil {
+		go matchmakeExtensionProtocol.BrowseMatchmakeSessionHandler(err, client, callID, nil, nil)
+		return
+	}
+
+	searchCriteria := searchCriteriaStructureInterface.(*MatchmakeSessionSearchCriteria)
+	resultRange := resultRangeStructureInterface.(*ResultRange)
+
+	go matchmakeExtensionProtocol.BrowseMatchmakeSessionHandler(nil, client, callID, searchCriteria, resultRange)
+}
+
+func (matchmakeExtensionProtocol *MatchmakeExtensionProtocol) handleCreateMatchmakeSession(packet nex.PacketInterface) {
+	if matchmakeExtensionProtocol.CreateMatchmakeSessionHandler == nil {
+		log.Println("[Warning] MatchmakeExtensionProtocol::CreateMatchmakeSession not implemented")
+		go respondNotImplemented(packet, MatchmakeExtensionProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, matchmakeExtensionProtocol.server)
+
+	matchmakeSession, err := matchmakeExtensionProtocol.readMatchmakeSession(parametersStream)
+	if err != nil {
+		go matchmakeExtensionProtocol.CreateMatchmakeSessionHandler(err, client, callID, nil, "", 0)
+		return
+	}
+
+	message, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go matchmakeExtensionProtocol.CreateMatchmakeSessionHandler(err, client, callID, nil, "", 0)
+		return
+	}
+
+	// Older clients do not send a participation count and only create the session for themselves
+	participationCount := uint16(1)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) >= 2 {
+		participationCount = parametersStream.ReadUInt16LE()
+	}
+
+	go matchmakeExtensionProtocol.CreateMatchmakeSessionHandler(nil, client, callID, matchmakeSession, message, participationCount)
+}
+
+func (matchmakeExtensionProtocol *MatchmakeExtensionProtocol) handleJoinMatchmakeSession(packet nex.PacketInterface) {
+	if matchmakeExtensionProtocol.JoinMatchmakeSessionHandler == nil {
+		log.Println("[Warning] MatchmakeExtensionProtocol::JoinMatchmakeSession not implemented")
+		go respondNotImplemented(packet, MatchmakeExtensionProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, matchmakeExtensionProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[MatchmakeExtensionProtocol::JoinMatchmakeSession] Data missing gathering ID")
+		go matchmakeExtensionProtocol.JoinMatchmakeSessionHandler(err, client, callID, 0, "")
+		return
+	}
+
+	gatheringID := parametersStream.ReadUInt32LE()
+
+	message, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go matchmakeExtensionProtocol.JoinMatchmakeSessionHandler(err, client, callID, 0, "")
+		return
+	}
+
+	go matchmakeExtensionProtocol.JoinMatchmakeSessionHandler(nil, client, callID, gatheringID, message)
+}
+
+func (matchmakeExtensionProtocol *MatchmakeExtensionProtocol) handleGetSimplePlayingSession(packet nex.PacketInterface) {
+	if matchmakeExtensionProtocol.GetSimplePlayingSessionHandler == nil {
+		log.Println("[Warning] MatchmakeExtensionProtocol::GetSimplePlayingSession not implemented")
+		go respondNotImplemented(packet, MatchmakeExtensionProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, matchmakeExtensionProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[MatchmakeExtensionProtocol::GetSimplePlayingSession] Data missing PID list length")
+		go matchmakeExtensionProtocol.GetSimplePlayingSessionHandler(err, client, callID, nil, false)
+		return
+	}
+
+	pids := parametersStream.ReadListUInt32LE()
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 1 {
+		err := errors.New("[MatchmakeExtensionProtocol::GetSimplePlayingSession] Data missing include login user flag")
+		go matchmakeExtensionProtocol.GetSimplePlayingSessionHandler(err, client, callID, nil, false)
+		return
+	}
+
+	includeLoginUser := parametersStream.ReadUInt8() == 1
+
+	go matchmakeExtensionProtocol.GetSimplePlayingSessionHandler(nil, client, callID, pids, includeLoginUser)
+}
+
+// readMatchmakeSession reads a MatchmakeSession wrapped in a data holder
+func (matchmakeExtensionProtocol *MatchmakeExtensionProtocol) readMatchmakeSession(stream *StreamIn) (*MatchmakeSession, error) {
+	className, content, err := stream.ReadDataHolder()
+	if err != nil {
+		return nil, err
+	}
+
+	if className != MatchmakeSessionClassName {
+		return nil, errors.New("[MatchmakeExtensionProtocol] Data holder does not contain a MatchmakeSession")
+	}
+
+	matchmakeSession := NewMatchmakeSession()
+
+	err = matchmakeSession.ExtractFromStream(nex.NewStreamIn(content, matchmakeExtensionProtocol.server))
+	if err != nil {
+		return nil, err
+	}
+
+	return matchmakeSession, nil
+}
+
+// NewMatchmakeExtensionProtocol returns a new MatchmakeExtensionProtocol
+func NewMatchmakeExtensionProtocol(server *nex.Server) *MatchmakeExtensionProtocol {
+	matchmakeExtensionProtocol := &MatchmakeExtensionProtocol{server: server}
+
+	matchmakeExtensionProtocol.Setup()
+
+	return matchmakeExtensionProtocol
+}
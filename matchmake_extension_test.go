@@ -0,0 +1,94 @@
+package nexproto
+
+import (
+	"bytes"
+	"testing"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+func TestMatchmakeSessionNexVersions(t *testing.T) {
+	tests := []struct {
+		nexVersion       int
+		hasProgressScore bool
+		hasSessionKey    bool
+	}{
+		{20000, false, false},
+		{30000, false, true},
+		{30400, true, true},
+	}
+
+	for _, test := range tests {
+		server := nex.NewServer()
+		server.SetNexVersion(test.nexVersion)
+
+		matchmakeSession := NewMatchmakeSession()
+		matchmakeSession.GameMode = 3
+		matchmakeSession.ParticipationCount = 2
+		matchmakeSession.ProgressScore = 50
+		matchmakeSession.SessionKey = []byte{1, 2, 3, 4}
+
+		data := matchmakeSession.Bytes(nex.NewStreamOut(server))
+
+		decoded := NewMatchmakeSession()
+		stream := nex.NewStreamIn(data, server)
+
+		if err := decoded.ExtractFromStream(stream); err != nil {
+			t.Fatalf("NEX %d: %v", test.nexVersion, err)
+		}
+
+		if stream.ByteOffset() != int64(len(data)) {
+			t.Errorf("NEX %d: %d of %d bytes read", test.nexVersion, stream.ByteOffset(), len(data))
+		}
+
+		if decoded.GameMode != 3 || decoded.ParticipationCount != 2 {
+			t.Errorf("NEX %d: decoded as %+v", test.nexVersion, decoded)
+		}
+
+		if (decoded.ProgressScore == 50) != test.hasProgressScore {
+			t.Errorf("NEX %d: progress score is %d", test.nexVersion, decoded.ProgressScore)
+		}
+
+		if bytes.Equal(decoded.SessionKey, matchmakeSession.SessionKey) != test.hasSessionKey {
+			t.Errorf("NEX %d: session key is %v", test.nexVersion, decoded.SessionKey)
+		}
+	}
+}
+
+func TestGatheringManagerFindSkipsMatchmakeSessions(t *testing.T) {
+	manager := NewGatheringManager()
+	server := nex.NewServer()
+	server.SetNexVersion(30400)
+
+	gatheringID := manager.Register(1, NewGathering())
+
+	matchmakeSession := NewMatchmakeSession()
+	matchmakeSession.OpenParticipation = true
+
+	created, err := manager.createMatchmakeSession(1, matchmakeSession, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessionID := created.Gathering.ID
+
+	if found := manager.FindByOwner(1, &ResultRange{Size: 10}); len(found) != 1 || found[0].ID != gatheringID {
+		t.Fatalf("FindByOwner returned %v", found)
+	}
+
+	if found := manager.FindByID([]uint32{sessionID, gatheringID}); len(found) != 1 || found[0].ID != gatheringID {
+		t.Fatalf("FindByID returned %v", found)
+	}
+
+	if found := manager.FindByParticipants([]uint32{1}); len(found) != 0 {
+		t.Fatalf("FindByParticipants returned %v", found)
+	}
+
+	if found := manager.Gatherings(); len(found) != 1 {
+		t.Fatalf("Gatherings returned %v", found)
+	}
+
+	if found := manager.FindByType(MatchmakeSessionClassName, &ResultRange{Size: 10}); len(found) != 1 || found[0].ID != sessionID {
+		t.Fatalf("FindByType returned %v", found)
+	}
+}
@@ -0,0 +1,402 @@
+package nexproto
+
+import (
+	"crypto/rand"
+	"strconv"
+	"strings"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+// matchmakeSessionKeySize is the size of the key handed to the participants of a MatchmakeSession
+const matchmakeSessionKeySize = 32
+
+// HandleAutoMatchmake is the default MatchmakeExtensionProtocol::AutoMatchmake handler. The caller joins the
+// oldest open session with the same game mode, system type and attributes that has room, or creates one
+func (manager *GatheringManager) HandleAutoMatchmake(err error, client *nex.Client, callID uint32, matchmakeSession *MatchmakeSession, message string) {
+	if err != nil {
+		respondError(client, MatchmakeExtensionProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	server := client.Server()
+	pid := client.PID()
+
+	joined, err := manager.autoMatchmake(pid, matchmakeSession, server)
+	if err != nil {
+		respondError(client, MatchmakeExtensionProtocolID, callID, gatheringResultCode(err))
+		return
+	}
+
+	responseStream := NewStreamOut(server)
+	responseStream.WriteDataHolder(MatchmakeSessionClassName, joined)
+
+	respondSuccess(client, MatchmakeExtensionProtocolID, MatchmakeExtensionMethodAutoMatchmakePostpone, callID, responseStream.Bytes())
+}
+
+// HandleBrowseMatchmakeSession is the default MatchmakeExtensionProtocol::BrowseMatchmakeSession handler.
+// Matching sessions are returned ordered by ID whatever the selection method. Session keys are only
+// returned for sessions the caller participates in
+func (manager *GatheringManager) HandleBrowseMatchmakeSession(err error, client *nex.Client, callID uint32, searchCriteria *MatchmakeSessionSearchCriteria, resultRange *ResultRange) {
+	if err != nil {
+		respondError(client, MatchmakeExtensionProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	server := client.Server()
+
+	gatherings := manager.filter(func(managed *managedGathering) bool {
+		if managed.gathering.className != MatchmakeSessionClassName {
+			return false
+		}
+
+		matchmakeSession, err := gatheringMatchmakeSession(managed.gathering, server)
+		if err != nil {
+			return false
+		}
+
+		return searchCriteria.matches(matchmakeSession, len(managed.participants))
+	})
+
+	gatherings = pageGatherings(gatherings, resultRange)
+
+	responseStream := NewStreamOut(server)
+	responseStream.WriteUInt32LE(uint32(len(gatherings)))
+
+	for _, gathering := range gatherings {
+		matchmakeSession := manager.matchmakeSession(gathering, server)
+
+		if indexOfPID(manager.Participants(gathering.ID), client.PID()) == -1 {
+			matchmakeSession.SessionKey = make([]byte, 0)
+		}
+
+		responseStream.WriteDataHolder(MatchmakeSessionClassName, matchmakeSession)
+	}
+
+	respondSuccess(client, MatchmakeExtensionProtocolID, MatchmakeExtensionMethodBrowseMatchmakeSession, callID, responseStream.Bytes())
+}
+
+// HandleCreateMatchmakeSession is the default MatchmakeExtensionProtocol::CreateMatchmakeSession handler.
+// The caller owns, hosts and participates in the new session and receives its ID and session key
+func (manager *GatheringManager) HandleCreateMatchmakeSession(err error, client *nex.Client, callID uint32, matchmakeSession *MatchmakeSession, message string, participationCount uint16) {
+	if err != nil {
+		respondError(client, MatchmakeExtensionProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	maxParticipants := matchmakeSession.Gathering.MaxParticipants
+	if maxParticipants != 0 && participationCount > maxParticipants {
+		respondError(client, MatchmakeExtensionProtocolID, callID, ResultRendezVousSessionFull)
+		return
+	}
+
+	server := client.Server()
+
+	created, err := manager.createMatchmakeSession(client.PID(), matchmakeSession, server)
+	if err != nil {
+		respondError(client, MatchmakeExtensionProtocolID, callID, gatheringResultCode(err))
+		return
+	}
+
+	responseStream := nex.NewStreamOut(server)
+	responseStream.WriteUInt32LE(created.Gathering.ID)
+	responseStream.WriteBuffer(created.SessionKey)
+
+	respondSuccess(client, MatchmakeExtensionProtocolID, MatchmakeExtensionMethodCreateMatchmakeSession, callID, responseStream.Bytes())
+}
+
+// HandleJoinMatchmakeSession is the default MatchmakeExtensionProtocol::JoinMatchmakeSession handler.
+// Sessions closed to participation can not be joined
+func (manager *GatheringManager) HandleJoinMatchmakeSession(err error, client *nex.Client, callID uint32, gatheringID uint32, message string) {
+	if err != nil {
+		respondError(client, MatchmakeExtensionProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	server := client.Server()
+
+	gathering := manager.Gathering(gatheringID)
+	if gathering == nil || gathering.className != MatchmakeSessionClassName {
+		respondError(client, MatchmakeExtensionProtocolID, callID, ResultRendezVousInvalidGID)
+		return
+	}
+
+	matchmakeSession, err := gatheringMatchmakeSession(gathering, server)
+	if err != nil {
+		respondError(client, MatchmakeExtensionProtocolID, callID, ResultRendezVousInvalidGID)
+		return
+	}
+
+	if !matchmakeSession.OpenParticipation {
+		respondError(client, MatchmakeExtensionProtocolID, callID, ResultRendezVousPermissionDenied)
+		return
+	}
+
+	err = manager.Participate(client.PID(), gatheringID)
+	if err != nil {
+		respondError(client, MatchmakeExtensionProtocolID, callID, gatheringResultCode(err))
+		return
+	}
+
+	responseStream := nex.NewStreamOut(server)
+	responseStream.WriteBuffer(manager.matchmakeSessionKey(gatheringID))
+
+	respondSuccess(client, MatchmakeExtensionProtocolID, MatchmakeExtensionMethodJoinMatchmakeSession, callID, responseStream.Bytes())
+}
+
+// HandleGetSimplePlayingSession is the default MatchmakeExtensionProtocol::GetSimplePlayingSession handler.
+// PIDs which are not participating in a session are left out of the response
+func (manager *GatheringManager) HandleGetSimplePlayingSession(err error, client *nex.Client, callID uint32, pids []uint32, includeLoginUser bool) {
+	if err != nil {
+		respondError(client, MatchmakeExtensionProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	server := client.Server()
+
+	if includeLoginUser && indexOfPID(pids, client.PID()) == -1 {
+		pids = append(pids, client.PID())
+	}
+
+	simplePlayingSessions := make([]*SimplePlayingSession, 0, len(pids))
+
+	for _, pid := range pids {
+		gatherings := manager.filter(func(managed *managedGathering) bool {
+			return managed.gathering.className == MatchmakeSessionClassName && indexOfPID(managed.participants, pid) != -1
+		})
+
+		if len(gatherings) == 0 {
+			continue
+		}
+
+		matchmakeSession, err := gatheringMatchmakeSession(gatherings[0], server)
+		if err != nil {
+			continue
+		}
+
+		simplePlayingSession := &SimplePlayingSession{
+			PrincipalID: pid,
+			GatheringID: matchmakeSession.Gathering.ID,
+			GameMode:    matchmakeSession.GameMode,
+		}
+
+		if len(matchmakeSession.Attributes) > 0 {
+			simplePlayingSession.Attribute0 = matchmakeSession.Attributes[0]
+		}
+
+		simplePlayingSessions = append(simplePlayingSessions, simplePlayingSession)
+	}
+
+	responseStream := nex.NewStreamOut(server)
+	responseStream.WriteUInt32LE(uint32(len(simplePlayingSessions)))
+
+	for _, simplePlayingSession := range simplePlayingSessions {
+		responseStream.WriteStructure(simplePlayingSession)
+	}
+
+	respondSuccess(client, MatchmakeExtensionProtocolID, MatchmakeExtensionMethodGetSimplePlayingSession, callID, responseStream.Bytes())
+}
+
+// createMatchmakeSession registers a session owned by pid with a fresh session key and makes pid participate in it.
+// The key is kept next to the gathering rather than in its data, so finding the gathering does not reveal it
+func (manager *GatheringManager) createMatchmakeSession(pid uint32, matchmakeSession *MatchmakeSession, server *nex.Server) (*MatchmakeSession, error) {
+	sessionKey := make([]byte, matchmakeSessionKeySize)
+
+	_, err := rand.Read(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	matchmakeSession.SessionKey = make([]byte, 0)
+
+	gathering, err := matchmakeSessionGathering(matchmakeSession, server)
+	if err != nil {
+		return nil, err
+	}
+
+	manager.mutex.Lock()
+
+	managed := manager.register(pid, MatchmakeSessionClassName, gathering)
+	managed.sessionKey = sessionKey
+	gatheringID := managed.gathering.ID
+
+	manager.mutex.Unlock()
+
+	err = manager.Participate(pid, gatheringID)
+	if err != nil {
+		return nil, err
+	}
+
+	return manager.matchmakeSession(manager.Gathering(gatheringID), server), nil
+}
+
+// autoMatchmake makes pid participate in the oldest session matching matchmakeSession, creating one if none has room
+func (manager *GatheringManager) autoMatchmake(pid uint32, matchmakeSession *MatchmakeSession, server *nex.Server) (*MatchmakeSession, error) {
+	for {
+		candidates := manager.filter(func(managed *managedGathering) bool {
+			if managed.gathering.className != MatchmakeSessionClassName || indexOfPID(managed.participants, pid) != -1 {
+				return false
+			}
+
+			maxParticipants := int(managed.gathering.MaxParticipants)
+			if maxParticipants != 0 && len(managed.participants) >= maxParticipants {
+				return false
+			}
+
+			candidate, err := gatheringMatchmakeSession(managed.gathering, server)
+			if err != nil {
+				return false
+			}
+
+			return candidate.OpenParticipation && sameMatchmakeSessionKind(candidate, matchmakeSession)
+		})
+
+		if len(candidates) == 0 {
+			return manager.createMatchmakeSession(pid, matchmakeSession, server)
+		}
+
+		// Another PID may have taken the last slot or terminated the session since it was found
+		err := manager.Participate(pid, candidates[0].ID)
+		if err == ErrGatheringFull || err == ErrGatheringNotFound {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return manager.matchmakeSession(manager.Gathering(candidates[0].ID), server), nil
+	}
+}
+
+// matchmakeSession decodes a stored session and fills in its current participation count and session key
+func (manager *GatheringManager) matchmakeSession(gathering *Gathering, server *nex.Server) *MatchmakeSession {
+	if gathering == nil {
+		return NewMatchmakeSession()
+	}
+
+	matchmakeSession, err := gatheringMatchmakeSession(gathering, server)
+	if err != nil {
+		return NewMatchmakeSession()
+	}
+
+	matchmakeSession.ParticipationCount = uint32(len(manager.Participants(gathering.ID)))
+	matchmakeSession.SessionKey = manager.matchmakeSessionKey(gathering.ID)
+
+	return matchmakeSession
+}
+
+// matchmakeSessionKey returns the session key of a session, empty if the gathering is not a session
+func (manager *GatheringManager) matchmakeSessionKey(gatheringID uint32) []byte {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+
+	managed, ok := manager.gatherings[gatheringID]
+	if !ok || managed.sessionKey == nil {
+		return make([]byte, 0)
+	}
+
+	return append([]byte(nil), managed.sessionKey...)
+}
+
+// matches reports whether a session with participantCount participants satisfies the search criteria
+func (searchCriteria *MatchmakeSessionSearchCriteria) matches(matchmakeSession *MatchmakeSession, participantCount int) bool {
+	gathering := matchmakeSession.Gathering
+
+	if !matchesCriterion(searchCriteria.GameMode, matchmakeSession.GameMode) ||
+		!matchesCriterion(searchCriteria.MatchmakeSystemType, matchmakeSession.MatchmakeSystemType) ||
+		!matchesCriterion(searchCriteria.MinParticipants, uint32(gathering.MinParticipants)) ||
+		!matchesCriterion(searchCriteria.MaxParticipants, uint32(gathering.MaxParticipants)) {
+		return false
+	}
+
+	for i, attribute := range searchCriteria.Attributes {
+		if attribute == "" {
+			continue
+		}
+
+		if i >= len(matchmakeSession.Attributes) || !matchesCriterion(attribute, matchmakeSession.Attributes[i]) {
+			return false
+		}
+	}
+
+	if searchCriteria.ExcludeLocked && !matchmakeSession.OpenParticipation {
+		return false
+	}
+
+	if searchCriteria.ExcludeNonHostPID && gathering.HostPID == 0 {
+		return false
+	}
+
+	if searchCriteria.VacantOnly && gathering.MaxParticipants != 0 {
+		vacantParticipants := int(searchCriteria.VacantParticipants)
+		if vacantParticipants == 0 {
+			vacantParticipants = 1
+		}
+
+		if participantCount+vacantParticipants > int(gathering.MaxParticipants) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesCriterion reports whether value satisfies a search criterion, which is either empty,
+// a single value or an inclusive "min,max" range. Malformed criteria match nothing
+func matchesCriterion(criterion string, value uint32) bool {
+	if criterion == "" {
+		return true
+	}
+
+	bounds := strings.SplitN(criterion, ",", 2)
+
+	min, err := strconv.ParseUint(strings.TrimSpace(bounds[0]), 10, 32)
+	if err != nil {
+		return false
+	}
+
+	max := min
+
+	if len(bounds) == 2 {
+		max, err = strconv.ParseUint(strings.TrimSpace(bounds[1]), 10, 32)
+		if err != nil {
+			return false
+		}
+	}
+
+	return uint64(value) >= min && uint64(value) <= max
+}
+
+// sameMatchmakeSessionKind reports whether two sessions share their game mode, system type and attributes
+func sameMatchmakeSessionKind(a *MatchmakeSession, b *MatchmakeSession) bool {
+	if a.GameMode != b.GameMode || a.MatchmakeSystemType != b.MatchmakeSystemType || len(a.Attributes) != len(b.Attributes) {
+		return false
+	}
+
+	for i := range a.Attributes {
+		if a.Attributes[i] != b.Attributes[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchmakeSessionGathering converts a session to the gathering stored by the GatheringManager,
+// keeping the session fields as its application data
+func matchmakeSessionGathering(matchmakeSession *MatchmakeSession, server *nex.Server) (*Gathering, error) {
+	return decodeGathering(matchmakeSession.Bytes(nex.NewStreamOut(server)), server)
+}
+
+// gatheringMatchmakeSession decodes the session stored as a gathering by matchmakeSessionGathering
+func gatheringMatchmakeSession(gathering *Gathering, server *nex.Server) (*MatchmakeSession, error) {
+	matchmakeSession := NewMatchmakeSession()
+
+	err := matchmakeSession.ExtractFromStream(nex.NewStreamIn(gathering.Bytes(nex.NewStreamOut(server)), server))
+	if err != nil {
+		return nil, err
+	}
+
+	return matchmakeSession, nil
+}
@@ -1,6 +1,7 @@
 package nexproto
 
 import (
+	"errors"
 	"log"
 
 	nex "github.com/jnackmclain/nex-go"
@@ -9,37 +10,282 @@ import (
 const (
 	MatchmakingProtocolID = 0x15 // the first matchmaking service protocol
 
-	RegisterGathering  = 0x1  // registers a gathering with the server
-	TerminateGathering = 0x2  // ends a gathering
-	UpdateGathering    = 0x4  // updates a gathering
-	Participate        = 0xB  // unsure on this one, going off NintendoClients wiki for the name
-	Unparticipate      = 0xC  // unsure on this one, going off NintendoClients wiki for the name
-	LaunchSession      = 0x1A // unsure on this one, going off NintendoClients wiki for the name
-	SetState           = 0x1E // sets the state of a gathering
-	Invite		   = 0x15 // Accept invite
+	RegisterGathering      = 0x1  // registers a gathering with the server
+	TerminateGathering     = 0x2  // ends a gathering
+	UpdateGathering        = 0x4  // updates a gathering
+	Participate            = 0xB  // unsure on this one, going off NintendoClients wiki for the name
+	Unparticipate          = 0xC  // unsure on this one, going off NintendoClients wiki for the name
+	LaunchSession          = 0x1A // unsure on this one, going off NintendoClients wiki for the name
+	SetState               = 0x1E // sets the state of a gathering
+	Invite                 = 0x15 // Accept invite. FindBySingleID in the Quazal SDK, RB3 looks up the gathering it was invited to with it
+	SendInvitation         = 0x5  // invites a list of PIDs to a gathering. Invite in the Quazal SDK
+	AcceptInvitation       = 0x6  // accepts a pending invitation
+	DeclineInvitation      = 0x7  // declines a pending invitation
+	CancelInvitation       = 0x8  // withdraws invitations sent to a list of PIDs
+	GetInvitationsSent     = 0x9  // lists the pending invitations to a gathering
+	GetInvitationsReceived = 0xA  // lists the pending invitations of the caller
+	FindByType             = 0x11 // finds gatherings by data holder class name
+	FindByDescription      = 0x12 // finds gatherings by description
+	FindByID               = 0x14 // finds gatherings from a list of gathering IDs
+	FindByOwner            = 0x16 // finds gatherings owned by a PID
+	FindByParticipants     = 0x17 // finds gatherings any of a list of PIDs participates in
 )
 
-// JsonProtocol handles the Json requests
+// MatchmakingProtocol handles the MatchMaking nex protocol
 type MatchmakingProtocol struct {
-	server                    *nex.Server
-	ConnectionIDCounter       *nex.Counter
-	RegisterGatheringHandler  func(err error, client *nex.Client, callID uint32, gathering []byte)
-	UpdateGatheringHandler    func(err error, client *nex.Client, callID uint32, gathering []byte, gatheringID uint32)
-	ParticipateHandler        func(err error, client *nex.Client, callID uint32, gatheringID uint32)
-	UnparticipateHandler      func(err error, client *nex.Client, callID uint32, gatheringID uint32)
-	LaunchSessionHandler      func(err error, client *nex.Client, callID uint32, gatheringID uint32)
-	TerminateGatheringHandler func(err error, client *nex.Client, callID uint32, gatheringID uint32)
-	SetStateHandler           func(err error, client *nex.Client, callID uint32, gatheringID uint32, state uint32)
-	InviteHandler             func(err error, client *nex.Client, callID uint32, gatheringID uint32)
+	server                        *nex.Server
+	ConnectionIDCounter           *nex.Counter
+	gatheringManager              *GatheringManager
+	RegisterGatheringHandler      func(err error, client *nex.Client, callID uint32, gathering []byte)
+	UpdateGatheringHandler        func(err error, client *nex.Client, callID uint32, gathering []byte, gatheringID uint32)
+	ParticipateHandler            func(err error, client *nex.Client, callID uint32, gatheringID uint32)
+	UnparticipateHandler          func(err error, client *nex.Client, callID uint32, gatheringID uint32)
+	LaunchSessionHandler          func(err error, client *nex.Client, callID uint32, gatheringID uint32)
+	TerminateGatheringHandler     func(err error, client *nex.Client, callID uint32, gatheringID uint32)
+	SetStateHandler               func(err error, client *nex.Client, callID uint32, gatheringID uint32, state uint32)
+	InviteHandler                 func(err error, client *nex.Client, callID uint32, gatheringID uint32)
+	FindByTypeHandler             func(err error, client *nex.Client, callID uint32, gatheringType string, resultRange *ResultRange)
+	FindByDescriptionHandler      func(err error, client *nex.Client, callID uint32, description string, resultRange *ResultRange)
+	FindByIDHandler               func(err error, client *nex.Client, callID uint32, gatheringIDs []uint32)
+	FindByOwnerHandler            func(err error, client *nex.Client, callID uint32, ownerPID uint32, resultRange *ResultRange)
+	FindByParticipantsHandler     func(err error, client *nex.Client, callID uint32, pids []uint32)
+	SendInvitationHandler         func(err error, client *nex.Client, callID uint32, gatheringID uint32, pids []uint32, message string)
+	AcceptInvitationHandler       func(err error, client *nex.Client, callID uint32, gatheringID uint32, message string)
+	DeclineInvitationHandler      func(err error, client *nex.Client, callID uint32, gatheringID uint32, message string)
+	CancelInvitationHandler       func(err error, client *nex.Client, callID uint32, gatheringID uint32, pids []uint32, message string)
+	GetInvitationsSentHandler     func(err error, client *nex.Client, callID uint32, gatheringID uint32)
+	GetInvitationsReceivedHandler func(err error, client *nex.Client, callID uint32)
 }
 
+// Invitation is a pending invitation of a guest PID to a gathering
+type Invitation struct {
+	GatheringID uint32
+	GuestPID    uint32
+	Message     string
+
+	nex.Structure
+}
+
+// Bytes encodes the Invitation and returns a byte array
+func (invitation *Invitation) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt32LE(invitation.GatheringID)
+	stream.WriteUInt32LE(invitation.GuestPID)
+	write4ByteString(stream, invitation.Message)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts an Invitation structure from a stream
+func (invitation *Invitation) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 8 {
+		// length check for the following fixed-size data
+		// gatheringID + guestPID
+		return errors.New("[Invitation::ExtractFromStream] Data size too small")
+	}
+
+	gatheringID := stream.ReadUInt32LE()
+	guestPID := stream.ReadUInt32LE()
+	message, err := stream.Read4ByteString()
+	if err != nil {
+		return err
+	}
+
+	invitation.GatheringID = gatheringID
+	invitation.GuestPID = guestPID
+	invitation.Message = message
+
+	return nil
+}
+
+// Copy returns a copy of the Invitation
+func (invitation *Invitation) Copy() *Invitation {
+	copied := *invitation
+
+	return &copied
+}
+
+// NewInvitation returns a new Invitation
+func NewInvitation() *Invitation {
+	return &Invitation{}
+}
+
+// ResultRange selects a window of results to return
+type ResultRange struct {
+	Offset uint32
+	Size   uint32
+
+	nex.Structure
+}
+
+// Bytes encodes the ResultRange and returns a byte array
+func (resultRange *ResultRange) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt32LE(resultRange.Offset)
+	stream.WriteUInt32LE(resultRange.Size)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a ResultRange structure from a stream
+func (resultRange *ResultRange) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 8 {
+		return errors.New("[ResultRange::ExtractFromStream] Data size too small")
+	}
+
+	resultRange.Offset = stream.ReadUInt32LE()
+	resultRange.Size = stream.ReadUInt32LE()
+
+	return nil
+}
+
+// Bounds returns the start and end indexes of the range within a result set of length total
+func (resultRange *ResultRange) Bounds(total int) (int, int) {
+	start := int(resultRange.Offset)
+	if start > total {
+		start = total
+	}
+
+	end := total
+	if uint64(resultRange.Size) < uint64(total-start) {
+		end = start + int(resultRange.Size)
+	}
+
+	return start, end
+}
+
+// NewResultRange returns a new ResultRange
+func NewResultRange() *ResultRange {
+	return &ResultRange{}
+}
+
+// Gathering contains the common fields of a Quazal gathering
+type Gathering struct {
+	ID                  uint32
+	OwnerPID            uint32
+	HostPID             uint32
+	MinParticipants     uint16
+	MaxParticipants     uint16
+	ParticipationPolicy uint32
+	PolicyArgument      uint32
+	Flags               uint32
+	State               uint32
+	Description         string
+	ApplicationData     []byte // title specific data following the gathering (HarmonixGathering fields on RB3)
+	className           string // data holder class name a GatheringManager registered the gathering as
+
+	nex.Structure
+}
+
+// dataHolderClassName returns the class name the gathering was registered as, or fallback if it was not registered
+func (gathering *Gathering) dataHolderClassName(fallback string) string {
+	if gathering.className == "" {
+		return fallback
+	}
+
+	return gathering.className
+}
+
+// Bytes encodes the Gathering and returns a byte array
+func (gathering *Gathering) Bytes(stream *nex.StreamOut) []byte {
+	gathering.writeHeader(stream)
+	writeBytes(stream, gathering.ApplicationData)
+
+	return stream.Bytes()
+}
+
+// writeHeader writes the Gathering fields without the title specific data
+func (gathering *Gathering) writeHeader(stream *nex.StreamOut) {
+	stream.WriteUInt32LE(gathering.ID)
+	stream.WriteUInt32LE(gathering.OwnerPID)
+	stream.WriteUInt32LE(gathering.HostPID)
+	stream.WriteUInt16LE(gathering.MinParticipants)
+	stream.WriteUInt16LE(gathering.MaxParticipants)
+	stream.WriteUInt32LE(gathering.ParticipationPolicy)
+	stream.WriteUInt32LE(gathering.PolicyArgument)
+	stream.WriteUInt32LE(gathering.Flags)
+	stream.WriteUInt32LE(gathering.State)
+	write4ByteString(stream, gathering.Description)
+}
+
+// ExtractFromStream extracts a Gathering structure from a stream
+func (gathering *Gathering) ExtractFromStream(stream *nex.StreamIn) error {
+	err := gathering.extractHeader(stream)
+	if err != nil {
+		return err
+	}
+
+	applicationData := make([]byte, len(stream.Bytes()[stream.ByteOffset():]))
+	copy(applicationData, stream.Bytes()[stream.ByteOffset():])
+
+	gathering.ApplicationData = applicationData
+
+	return nil
+}
+
+// extractHeader extracts the Gathering fields without the title specific data
+func (gathering *Gathering) extractHeader(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 32 {
+		// length check for the following fixed-size data
+		// id + ownerPID + hostPID + minParticipants + maxParticipants + participationPolicy + policyArgument + flags + state
+		return errors.New("[Gathering::ExtractFromStream] Data size too small")
+	}
+
+	id := stream.ReadUInt32LE()
+	ownerPID := stream.ReadUInt32LE()
+	hostPID := stream.ReadUInt32LE()
+	minParticipants := stream.ReadUInt16LE()
+	maxParticipants := stream.ReadUInt16LE()
+	participationPolicy := stream.ReadUInt32LE()
+	policyArgument := stream.ReadUInt32LE()
+	flags := stream.ReadUInt32LE()
+	state := stream.ReadUInt32LE()
+	description, err := stream.Read4ByteString()
+	if err != nil {
+		return err
+	}
+
+	gathering.ID = id
+	gathering.OwnerPID = ownerPID
+	gathering.HostPID = hostPID
+	gathering.MinParticipants = minParticipants
+	gathering.MaxParticipants = maxParticipants
+	gathering.ParticipationPolicy = participationPolicy
+	gathering.PolicyArgument = policyArgument
+	gathering.Flags = flags
+	gathering.State = state
+	gathering.Description = description
+
+	return nil
+}
+
+// Copy returns a deep copy of the Gathering
+func (gathering *Gathering) Copy() *Gathering {
+	copied := *gathering
+	copied.ApplicationData = append([]byte(nil), gathering.ApplicationData...)
+
+	return &copied
+}
+
+// NewGathering returns a new Gathering
+func NewGathering() *Gathering {
+	return &Gathering{}
+}
+
+// Setup initializes the protocol
 func (matchmakingProtocol *MatchmakingProtocol) Setup() {
 	nexServer := matchmakingProtocol.server
 
+	trackPacketRoutes(nexServer)
+
 	nexServer.On("Data", func(packet nex.PacketInterface) {
 		request := packet.RMCRequest()
 
 		if MatchmakingProtocolID == request.ProtocolID() {
+			rememberPacketRoute(packet)
+
+			if matchmakingProtocol.gatheringManager != nil {
+				matchmakingProtocol.gatheringManager.connect(packet.Sender())
+			}
+
 			switch request.MethodID() {
 			case RegisterGathering:
 				go matchmakingProtocol.handleRegisterGathering(packet)
@@ -57,11 +303,45 @@ func (matchmakingProtocol *MatchmakingProtocol) Setup() {
 				go matchmakingProtocol.handleSetState(packet)
 			case Invite:
 				go matchmakingProtocol.handleInvite(packet)
+			case SendInvitation:
+				go matchmakingProtocol.handleSendInvitation(packet)
+			case AcceptInvitation:
+				go matchmakingProtocol.handleAcceptInvitation(packet)
+			case DeclineInvitation:
+				go matchmakingProtocol.handleDeclineInvitation(packet)
+			case CancelInvitation:
+				go matchmakingProtocol.handleCancelInvitation(packet)
+			case GetInvitationsSent:
+				go matchmakingProtocol.handleGetInvitationsSent(packet)
+			case GetInvitationsReceived:
+				go matchmakingProtocol.handleGetInvitationsReceived(packet)
+			case FindByType:
+				go matchmakingProtocol.handleFindByType(packet)
+			case FindByDescription:
+				go matchmakingProtocol.handleFindByDescription(packet)
+			case FindByID:
+				go matchmakingProtocol.handleFindByID(packet)
+			case FindByOwner:
+				go matchmakingProtocol.handleFindByOwner(packet)
+			case FindByParticipants:
+				go matchmakingProtocol.handleFindByParticipants(packet)
 			default:
 				log.Printf("Unsupported Matchmaking method ID: %#v\n", request.MethodID())
 			}
 		}
 	})
+
+	// Clients that drop without leaving their gatherings are removed from them once the server sees them go
+	dropParticipant := func(packet nex.PacketInterface) {
+		if matchmakingProtocol.gatheringManager == nil {
+			return
+		}
+
+		go matchmakingProtocol.gatheringManager.DropParticipant(packet.Sender())
+	}
+
+	nexServer.On("Disconnect", dropParticipant)
+	nexServer.On("Kick", dropParticipant)
 }
 
 func (matchmakingProtocol *MatchmakingProtocol) RegisterGathering(handler func(err error, client *nex.Client, callID uint32, gathering []byte)) {
@@ -96,6 +376,91 @@ func (matchmakingProtocol *MatchmakingProtocol) Invite(handler func(err error, c
 	matchmakingProtocol.InviteHandler = handler
 }
 
+// SendInvitation sets the SendInvitation handler function
+func (matchmakingProtocol *MatchmakingProtocol) SendInvitation(handler func(err error, client *nex.Client, callID uint32, gatheringID uint32, pids []uint32, message string)) {
+	matchmakingProtocol.SendInvitationHandler = handler
+}
+
+// AcceptInvitation sets the AcceptInvitation handler function
+func (matchmakingProtocol *MatchmakingProtocol) AcceptInvitation(handler func(err error, client *nex.Client, callID uint32, gatheringID uint32, message string)) {
+	matchmakingProtocol.AcceptInvitationHandler = handler
+}
+
+// DeclineInvitation sets the DeclineInvitation handler function
+func (matchmakingProtocol *MatchmakingProtocol) DeclineInvitation(handler func(err error, client *nex.Client, callID uint32, gatheringID uint32, message string)) {
+	matchmakingProtocol.DeclineInvitationHandler = handler
+}
+
+// CancelInvitation sets the CancelInvitation handler function
+func (matchmakingProtocol *MatchmakingProtocol) CancelInvitation(handler func(err error, client *nex.Client, callID uint32, gatheringID uint32, pids []uint32, message string)) {
+	matchmakingProtocol.CancelInvitationHandler = handler
+}
+
+// GetInvitationsSent sets the GetInvitationsSent handler function
+func (matchmakingProtocol *MatchmakingProtocol) GetInvitationsSent(handler func(err error, client *nex.Client, callID uint32, gatheringID uint32)) {
+	matchmakingProtocol.GetInvitationsSentHandler = handler
+}
+
+// GetInvitationsReceived sets the GetInvitationsReceived handler function
+func (matchmakingProtocol *MatchmakingProtocol) GetInvitationsReceived(handler func(err error, client *nex.Client, callID uint32)) {
+	matchmakingProtocol.GetInvitationsReceivedHandler = handler
+}
+
+// FindByType sets the FindByType handler function
+func (matchmakingProtocol *MatchmakingProtocol) FindByType(handler func(err error, client *nex.Client, callID uint32, gatheringType string, resultRange *ResultRange)) {
+	matchmakingProtocol.FindByTypeHandler = handler
+}
+
+// FindByDescription sets the FindByDescription handler function
+func (matchmakingProtocol *MatchmakingProtocol) FindByDescription(handler func(err error, client *nex.Client, callID uint32, description string, resultRange *ResultRange)) {
+	matchmakingProtocol.FindByDescriptionHandler = handler
+}
+
+// FindByID sets the FindByID handler function
+func (matchmakingProtocol *MatchmakingProtocol) FindByID(handler func(err error, client *nex.Client, callID uint32, gatheringIDs []uint32)) {
+	matchmakingProtocol.FindByIDHandler = handler
+}
+
+// FindByOwner sets the FindByOwner handler function
+func (matchmakingProtocol *MatchmakingProtocol) FindByOwner(handler func(err error, client *nex.Client, callID uint32, ownerPID uint32, resultRange *ResultRange)) {
+	matchmakingProtocol.FindByOwnerHandler = handler
+}
+
+// FindByParticipants sets the FindByParticipants handler function
+func (matchmakingProtocol *MatchmakingProtocol) FindByParticipants(handler func(err error, client *nex.Client, callID uint32, pids []uint32)) {
+	matchmakingProtocol.FindByParticipantsHandler = handler
+}
+
+// UseGatheringManager installs the default handlers of manager for every gathering method
+func (matchmakingProtocol *MatchmakingProtocol) UseGatheringManager(manager *GatheringManager) {
+	matchmakingProtocol.gatheringManager = manager
+
+	matchmakingProtocol.RegisterGathering(manager.HandleRegisterGathering)
+	matchmakingProtocol.UpdateGathering(manager.HandleUpdateGathering)
+	matchmakingProtocol.Participate(manager.HandleParticipate)
+	matchmakingProtocol.Unparticipate(manager.HandleUnparticipate)
+	matchmakingProtocol.LaunchSession(manager.HandleLaunchSession)
+	matchmakingProtocol.TerminateGathering(manager.HandleTerminateGathering)
+	matchmakingProtocol.SetState(manager.HandleSetState)
+	matchmakingProtocol.Invite(manager.HandleInvite)
+	matchmakingProtocol.SendInvitation(manager.HandleSendInvitation)
+	matchmakingProtocol.AcceptInvitation(manager.HandleAcceptInvitation)
+	matchmakingProtocol.DeclineInvitation(manager.HandleDeclineInvitation)
+	matchmakingProtocol.CancelInvitation(manager.HandleCancelInvitation)
+	matchmakingProtocol.GetInvitationsSent(manager.HandleGetInvitationsSent)
+	matchmakingProtocol.GetInvitationsReceived(manager.HandleGetInvitationsReceived)
+	matchmakingProtocol.FindByType(manager.HandleFindByType)
+	matchmakingProtocol.FindByDescription(manager.HandleFindByDescription)
+	matchmakingProtocol.FindByID(manager.HandleFindByID)
+	matchmakingProtocol.FindByOwner(manager.HandleFindByOwner)
+	matchmakingProtocol.FindByParticipants(manager.HandleFindByParticipants)
+}
+
+// GatheringManager returns the GatheringManager installed with UseGatheringManager, or nil
+func (matchmakingProtocol *MatchmakingProtocol) GatheringManager() *GatheringManager {
+	return matchmakingProtocol.gatheringManager
+}
+
 func (matchmakingProtocol *MatchmakingProtocol) handleRegisterGathering(packet nex.PacketInterface) {
 	if matchmakingProtocol.RegisterGatheringHandler == nil {
 		log.Println("[Warning] MatchmakingProtocol::RegisterGathering not implemented")
@@ -138,7 +503,7 @@ func (matchmakingProtocol *MatchmakingProtocol) handleUpdateGathering(packet nex
 	parameters := request.Parameters()
 
 	parametersStream := NewStreamIn(parameters, matchmakingProtocol.server)
-	
+
 	parametersStream.Read4ByteString()
 	parametersStream.ReadUInt32LE()
 	gathering, err := parametersStream.ReadBuffer()
@@ -278,7 +643,328 @@ func (matchmakingProtocol *MatchmakingProtocol) handleInvite(packet nex.PacketIn
 	go matchmakingProtocol.InviteHandler(nil, client, callID, gatheringID)
 }
 
-// NewSecureProtocol returns a new SecureProtocol
+func (matchmakingProtocol *MatchmakingProtocol) handleSendInvitation(packet nex.PacketInterface) {
+	if matchmakingProtocol.SendInvitationHandler == nil {
+		log.Println("[Warning] MatchmakingProtocol::SendInvitation not implemented")
+		go respondNotImplemented(packet, MatchmakingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, matchmakingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 8 {
+		err := errors.New("[MatchmakingProtocol::SendInvitation] Data missing gathering ID or list length")
+		go matchmakingProtocol.SendInvitationHandler(err, client, callID, 0, make([]uint32, 0), "")
+		return
+	}
+
+	gatheringID := parametersStream.ReadUInt32LE()
+	pids := parametersStream.ReadListUInt32LE()
+
+	message, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go matchmakingProtocol.SendInvitationHandler(err, client, callID, 0, make([]uint32, 0), "")
+		return
+	}
+
+	go matchmakingProtocol.SendInvitationHandler(nil, client, callID, gatheringID, pids, message)
+}
+
+func (matchmakingProtocol *MatchmakingProtocol) handleAcceptInvitation(packet nex.PacketInterface) {
+	if matchmakingProtocol.AcceptInvitationHandler == nil {
+		log.Println("[Warning] MatchmakingProtocol::AcceptInvitation not implemented")
+		go respondNotImplemented(packet, MatchmakingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, matchmakingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[MatchmakingProtocol::AcceptInvitation] Data missing gathering ID")
+		go matchmakingProtocol.AcceptInvitationHandler(err, client, callID, 0, "")
+		return
+	}
+
+	gatheringID := parametersStream.ReadUInt32LE()
+
+	message, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go matchmakingProtocol.AcceptInvitationHandler(err, client, callID, 0, "")
+		return
+	}
+
+	go matchmakingProtocol.AcceptInvitationHandler(nil, client, callID, gatheringID, message)
+}
+
+func (matchmakingProtocol *MatchmakingProtocol) handleDeclineInvitation(packet nex.PacketInterface) {
+	if matchmakingProtocol.DeclineInvitationHandler == nil {
+		log.Println("[Warning] MatchmakingProtocol::DeclineInvitation not implemented")
+		go respondNotImplemented(packet, MatchmakingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, matchmakingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[MatchmakingProtocol::DeclineInvitation] Data missing gathering ID")
+		go matchmakingProtocol.DeclineInvitationHandler(err, client, callID, 0, "")
+		return
+	}
+
+	gatheringID := parametersStream.ReadUInt32LE()
+
+	message, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go matchmakingProtocol.DeclineInvitationHandler(err, client, callID, 0, "")
+		return
+	}
+
+	go matchmakingProtocol.DeclineInvitationHandler(nil, client, callID, gatheringID, message)
+}
+
+func (matchmakingProtocol *MatchmakingProtocol) handleCancelInvitation(packet nex.PacketInterface) {
+	if matchmakingProtocol.CancelInvitationHandler == nil {
+		log.Println("[Warning] MatchmakingProtocol::CancelInvitation not implemented")
+		go respondNotImplemented(packet, MatchmakingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, matchmakingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 8 {
+		err := errors.New("[MatchmakingProtocol::CancelInvitation] Data missing gathering ID or list length")
+		go matchmakingProtocol.CancelInvitationHandler(err, client, callID, 0, make([]uint32, 0), "")
+		return
+	}
+
+	gatheringID := parametersStream.ReadUInt32LE()
+	pids := parametersStream.ReadListUInt32LE()
+
+	message, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go matchmakingProtocol.CancelInvitationHandler(err, client, callID, 0, make([]uint32, 0), "")
+		return
+	}
+
+	go matchmakingProtocol.CancelInvitationHandler(nil, client, callID, gatheringID, pids, message)
+}
+
+func (matchmakingProtocol *MatchmakingProtocol) handleGetInvitationsSent(packet nex.PacketInterface) {
+	if matchmakingProtocol.GetInvitationsSentHandler == nil {
+		log.Println("[Warning] MatchmakingProtocol::GetInvitationsSent not implemented")
+		go respondNotImplemented(packet, MatchmakingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, matchmakingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[MatchmakingProtocol::GetInvitationsSent] Data missing gathering ID")
+		go matchmakingProtocol.GetInvitationsSentHandler(err, client, callID, 0)
+		return
+	}
+
+	gatheringID := parametersStream.ReadUInt32LE()
+
+	go matchmakingProtocol.GetInvitationsSentHandler(nil, client, callID, gatheringID)
+}
+
+func (matchmakingProtocol *MatchmakingProtocol) handleGetInvitationsReceived(packet nex.PacketInterface) {
+	if matchmakingProtocol.GetInvitationsReceivedHandler == nil {
+		log.Println("[Warning] MatchmakingProtocol::GetInvitationsReceived not implemented")
+		go respondNotImplemented(packet, MatchmakingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+
+	go matchmakingProtocol.GetInvitationsReceivedHandler(nil, client, callID)
+}
+
+func (matchmakingProtocol *MatchmakingProtocol) handleFindByType(packet nex.PacketInterface) {
+	if matchmakingProtocol.FindByTypeHandler == nil {
+		log.Println("[Warning] MatchmakingProtocol::FindByType not implemented")
+		go respondNotImplemented(packet, MatchmakingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, matchmakingProtocol.server)
+
+	gatheringType, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go matchmakingProtocol.FindByTypeHandler(err, client, callID, "", nil)
+		return
+	}
+
+	resultRangeStructureInterface, err := parametersStream.ReadStructure(NewResultRange())
+	if err != nil {
+		go matchmakingProtocol.FindByTypeHandler(err, client, callID, "", nil)
+		return
+	}
+
+	resultRange := resultRangeStructureInterface.(*ResultRange)
+
+	go matchmakingProtocol.FindByTypeHandler(nil, client, callID, gatheringType, resultRange)
+}
+
+func (matchmakingProtocol *MatchmakingProtocol) handleFindByDescription(packet nex.PacketInterface) {
+	if matchmakingProtocol.FindByDescriptionHandler == nil {
+		log.Println("[Warning] MatchmakingProtocol::FindByDescription not implemented")
+		go respondNotImplemented(packet, MatchmakingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, matchmakingProtocol.server)
+
+	description, err := parametersStream.Read4ByteString()
+	if err != nil {
+		go matchmakingProtocol.FindByDescriptionHandler(err, client, callID, "", nil)
+		return
+	}
+
+	resultRangeStructureInterface, err := parametersStream.ReadStructure(NewResultRange())
+	if err != nil {
+		go matchmakingProtocol.FindByDescriptionHandler(err, client, callID, "", nil)
+		return
+	}
+
+	resultRange := resultRangeStructureInterface.(*ResultRange)
+
+	go matchmakingProtocol.FindByDescriptionHandler(nil, client, callID, description, resultRange)
+}
+
+func (matchmakingProtocol *MatchmakingProtocol) handleFindByID(packet nex.PacketInterface) {
+	if matchmakingProtocol.FindByIDHandler == nil {
+		log.Println("[Warning] MatchmakingProtocol::FindByID not implemented")
+		go respondNotImplemented(packet, MatchmakingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, matchmakingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[MatchmakingProtocol::FindByID] Data missing list length")
+		go matchmakingProtocol.FindByIDHandler(err, client, callID, make([]uint32, 0))
+		return
+	}
+
+	gatheringIDs := parametersStream.ReadListUInt32LE()
+
+	go matchmakingProtocol.FindByIDHandler(nil, client, callID, gatheringIDs)
+}
+
+func (matchmakingProtocol *MatchmakingProtocol) handleFindByOwner(packet nex.PacketInterface) {
+	if matchmakingProtocol.FindByOwnerHandler == nil {
+		log.Println("[Warning] MatchmakingProtocol::FindByOwner not implemented")
+		go respondNotImplemented(packet, MatchmakingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, matchmakingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[MatchmakingProtocol::FindByOwner] Data missing owner PID")
+		go matchmakingProtocol.FindByOwnerHandler(err, client, callID, 0, nil)
+		return
+	}
+
+	ownerPID := parametersStream.ReadUInt32LE()
+
+	resultRangeStructureInterface, err := parametersStream.ReadStructure(NewResultRange())
+	if err != nil {
+		go matchmakingProtocol.FindByOwnerHandler(err, client, callID, 0, nil)
+		return
+	}
+
+	resultRange := resultRangeStructureInterface.(*ResultRange)
+
+	go matchmakingProtocol.FindByOwnerHandler(nil, client, callID, ownerPID, resultRange)
+}
+
+func (matchmakingProtocol *MatchmakingProtocol) handleFindByParticipants(packet nex.PacketInterface) {
+	if matchmakingProtocol.FindByParticipantsHandler == nil {
+		log.Println("[Warning] MatchmakingProtocol::FindByParticipants not implemented")
+		go respondNotImplemented(packet, MatchmakingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, matchmakingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[MatchmakingProtocol::FindByParticipants] Data missing list length")
+		go matchmakingProtocol.FindByParticipantsHandler(err, client, callID, make([]uint32, 0))
+		return
+	}
+
+	pids := parametersStream.ReadListUInt32LE()
+
+	go matchmakingProtocol.FindByParticipantsHandler(nil, client, callID, pids)
+}
+
+// NewMatchmakingProtocol returns a new MatchmakingProtocol
 func NewMatchmakingProtocol(server *nex.Server) *MatchmakingProtocol {
 	matchmakingProtocol := &MatchmakingProtocol{
 		server:              server,
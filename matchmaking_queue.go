@@ -0,0 +1,522 @@
+package nexproto
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+// InstrumentAny is the instrument of a queued player who can fill any band role
+const InstrumentAny = 0xFFFFFFFF
+
+// QueueEntry is a player waiting in a MatchmakingQueue
+type QueueEntry struct {
+	PID        uint32
+	Instrument uint32
+	Skill      int
+	Region     string
+	EnqueuedAt time.Time
+	client     *nex.Client // client which queued the player, if any, so drops of stale connections are ignored
+}
+
+// MatchmakingQueue groups queued players into bands by instrument, skill and estimated latency,
+// registering a gathering in its GatheringManager for every complete band
+type MatchmakingQueue struct {
+	mutex             sync.Mutex
+	manager           *GatheringManager
+	notifications     *NotificationEventsProtocol
+	entries           []*QueueEntry
+	stopMatching      chan struct{}
+	BandRoles         []uint32         // instruments a complete band needs, one entry per member
+	LobbyGatheringID  uint32           // Participate calls on this gathering ID enqueue the caller, 0 to disable
+	SkillWindow       int              // largest skill difference allowed in a band when nobody has waited
+	SkillWindowGrowth int              // how much the skill window widens per second the oldest member has waited
+	MaxLatency        time.Duration    // largest estimated latency allowed between band members when nobody has waited
+	RelaxLatencyAfter time.Duration    // wait after which latency is no longer considered, 0 to always consider it
+	GatheringTemplate *Gathering       // copied for every gathering created for a band
+	Scorer            CustomFindScorer // ranks the gatherings answered by HandleCustomFind, DefaultCustomFindScorer when nil
+	SkillRating       func(pid uint32) int
+	LatencyEstimate   func(regionA string, regionB string) time.Duration
+	RegionResolver    func(client *nex.Client) string // region of a client queued by a handler, RegionFromAddress of its address when nil
+	BandFormedHandler func(gathering *Gathering, members []*QueueEntry)
+}
+
+// OnBandFormed sets the function called after a band is taken out of the queue and its gathering registered
+func (queue *MatchmakingQueue) OnBandFormed(handler func(gathering *Gathering, members []*QueueEntry)) {
+	queue.BandFormedHandler = handler
+}
+
+// UseNotificationEvents sends a SwitchGathering notification to the members of every band formed
+func (queue *MatchmakingQueue) UseNotificationEvents(notificationEventsProtocol *NotificationEventsProtocol) {
+	queue.notifications = notificationEventsProtocol
+}
+
+// Enqueue adds or replaces the entry of entry.PID and tries to form bands
+func (queue *MatchmakingQueue) Enqueue(entry *QueueEntry) {
+	queue.mutex.Lock()
+
+	queue.remove(entry.PID)
+
+	if entry.EnqueuedAt.IsZero() {
+		entry.EnqueuedAt = time.Now()
+	}
+
+	queue.entries = append(queue.entries, entry)
+
+	queue.mutex.Unlock()
+
+	queue.Match()
+}
+
+// Remove takes pid out of the queue
+func (queue *MatchmakingQueue) Remove(pid uint32) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	queue.remove(pid)
+}
+
+// Entries returns copies of the queued entries, oldest first
+func (queue *MatchmakingQueue) Entries() []*QueueEntry {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	entries := make([]*QueueEntry, 0, len(queue.entries))
+
+	for _, entry := range queue.entries {
+		copied := *entry
+		entries = append(entries, &copied)
+	}
+
+	return entries
+}
+
+// Match forms as many bands as the queue allows, oldest players first, and returns their gathering IDs.
+// Players who could not be added to the gathering of their band are queued again for the next Match
+func (queue *MatchmakingQueue) Match() []uint32 {
+	gatheringIDs := make([]uint32, 0)
+	failed := make([]*QueueEntry, 0)
+
+	for {
+		queue.mutex.Lock()
+		members := queue.nextBand(time.Now())
+		if members != nil {
+			for _, member := range members {
+				queue.remove(member.PID)
+			}
+		}
+		queue.mutex.Unlock()
+
+		if members == nil {
+			break
+		}
+
+		gatheringID, notJoined := queue.formBand(members)
+		if gatheringID != 0 {
+			gatheringIDs = append(gatheringIDs, gatheringID)
+		}
+
+		failed = append(failed, notJoined...)
+	}
+
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	for _, entry := range failed {
+		queue.requeue(entry)
+	}
+
+	return gatheringIDs
+}
+
+// Start calls Match every interval in the background so waiting players are grouped as their limits relax
+func (queue *MatchmakingQueue) Start(interval time.Duration) {
+	queue.Stop()
+
+	stop := make(chan struct{})
+
+	queue.mutex.Lock()
+	queue.stopMatching = stop
+	queue.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				queue.Match()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background matching started by Start
+func (queue *MatchmakingQueue) Stop() {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if queue.stopMatching != nil {
+		close(queue.stopMatching)
+		queue.stopMatching = nil
+	}
+}
+
+// HandleCustomFind is a CustomMatchmakingProtocol::CustomFind handler which enqueues the caller for any
+// instrument unless they are already queued, then answers with the gatherings already open ranked by Scorer
+func (queue *MatchmakingQueue) HandleCustomFind(err error, client *nex.Client, callID uint32, data []byte) {
+	if err != nil {
+		respondError(client, CustomMatchmakingProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	queue.enqueueNew(queue.newEntry(client, InstrumentAny))
+
+	scorer := queue.Scorer
+	if scorer == nil {
+		scorer = DefaultCustomFindScorer
+	}
+
+	gatherings := queue.manager.CustomFind(data, scorer)
+
+	responseStream := NewStreamOut(client.Server())
+	responseStream.WriteListGathering(queue.manager.GatheringClassName, gatherings)
+
+	respondSuccess(client, CustomMatchmakingProtocolID, CustomFind, callID, responseStream.Bytes())
+}
+
+// HandleParticipate is a MatchmakingProtocol::Participate handler which enqueues callers participating in
+// LobbyGatheringID for any instrument unless they are already queued, and passes every other gathering to the GatheringManager
+func (queue *MatchmakingQueue) HandleParticipate(err error, client *nex.Client, callID uint32, gatheringID uint32) {
+	if err != nil || queue.LobbyGatheringID == 0 || gatheringID != queue.LobbyGatheringID {
+		queue.manager.HandleParticipate(err, client, callID, gatheringID)
+		return
+	}
+
+	queue.enqueueNew(queue.newEntry(client, InstrumentAny))
+
+	responseStream := nex.NewStreamOut(client.Server())
+	writeBool(responseStream, true)
+
+	respondSuccess(client, MatchmakingProtocolID, Participate, callID, responseStream.Bytes())
+}
+
+// SetRegion sets the region a queued PID is matched by, such as one resolved from its station URLs
+func (queue *MatchmakingQueue) SetRegion(pid uint32, region string) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	for _, entry := range queue.entries {
+		if entry.PID == pid {
+			entry.Region = region
+		}
+	}
+}
+
+// enqueueNew adds entry and tries to form bands, unless entry.PID is already queued
+func (queue *MatchmakingQueue) enqueueNew(entry *QueueEntry) {
+	queue.mutex.Lock()
+
+	for _, queued := range queue.entries {
+		if queued.PID == entry.PID {
+			queue.mutex.Unlock()
+			return
+		}
+	}
+
+	entry.EnqueuedAt = time.Now()
+	queue.entries = append(queue.entries, entry)
+
+	queue.mutex.Unlock()
+
+	queue.Match()
+}
+
+// leave takes the PID of client out of the queue, unless it was queued again by another client
+func (queue *MatchmakingQueue) leave(client *nex.Client) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	for _, entry := range queue.entries {
+		if entry.PID == client.PID() && entry.client != nil && entry.client != client {
+			return
+		}
+	}
+
+	queue.remove(client.PID())
+}
+
+func (queue *MatchmakingQueue) newEntry(client *nex.Client, instrument uint32) *QueueEntry {
+	entry := &QueueEntry{
+		PID:        client.PID(),
+		Instrument: instrument,
+		client:     client,
+	}
+
+	if queue.SkillRating != nil {
+		entry.Skill = queue.SkillRating(entry.PID)
+	}
+
+	if queue.RegionResolver != nil {
+		entry.Region = queue.RegionResolver(client)
+	} else if address := client.Address(); address != nil {
+		entry.Region = RegionFromAddress(address.IP.String())
+	}
+
+	return entry
+}
+
+// nextBand returns the members of a complete band around the oldest player who can be matched, or nil. The caller must hold the lock
+func (queue *MatchmakingQueue) nextBand(now time.Time) []*QueueEntry {
+	for _, anchor := range queue.entries {
+		if members := queue.bandAround(anchor, now); members != nil {
+			return members
+		}
+	}
+
+	return nil
+}
+
+// bandAround fills every band role with players compatible with anchor, preferring players of the exact instrument.
+// The caller must hold the lock
+func (queue *MatchmakingQueue) bandAround(anchor *QueueEntry, now time.Time) []*QueueEntry {
+	if len(queue.BandRoles) == 0 {
+		return nil
+	}
+
+	waited := now.Sub(anchor.EnqueuedAt)
+	members := make([]*QueueEntry, 0, len(queue.BandRoles))
+	taken := map[uint32]bool{}
+	openRoles := make([]uint32, 0, len(queue.BandRoles))
+
+	for _, role := range queue.BandRoles {
+		if !taken[anchor.PID] && anchor.Instrument == role {
+			members = append(members, anchor)
+			taken[anchor.PID] = true
+			continue
+		}
+
+		openRoles = append(openRoles, role)
+	}
+
+	if !taken[anchor.PID] {
+		if anchor.Instrument != InstrumentAny || len(openRoles) == 0 {
+			return nil
+		}
+
+		members = append(members, anchor)
+		taken[anchor.PID] = true
+		openRoles = openRoles[1:]
+	}
+
+	unfilled := make([]uint32, 0, len(openRoles))
+
+	for _, role := range openRoles {
+		if member := queue.compatible(anchor, members, taken, waited, role); member != nil {
+			members = append(members, member)
+			taken[member.PID] = true
+		} else {
+			unfilled = append(unfilled, role)
+		}
+	}
+
+	for range unfilled {
+		member := queue.compatible(anchor, members, taken, waited, InstrumentAny)
+		if member == nil {
+			return nil
+		}
+
+		members = append(members, member)
+		taken[member.PID] = true
+	}
+
+	return members
+}
+
+// compatible returns the oldest untaken player of instrument within the skill and latency limits of every member. The caller must hold the lock
+func (queue *MatchmakingQueue) compatible(anchor *QueueEntry, members []*QueueEntry, taken map[uint32]bool, waited time.Duration, instrument uint32) *QueueEntry {
+	skillWindow := queue.SkillWindow + int(waited.Seconds())*queue.SkillWindowGrowth
+	considerLatency := queue.RelaxLatencyAfter == 0 || waited < queue.RelaxLatencyAfter
+
+	for _, candidate := range queue.entries {
+		if taken[candidate.PID] || candidate.Instrument != instrument {
+			continue
+		}
+
+		fits := true
+
+		for _, member := range members {
+			if absInt(candidate.Skill-member.Skill) > skillWindow {
+				fits = false
+				break
+			}
+
+			if considerLatency && queue.MaxLatency != 0 && queue.latency(candidate.Region, member.Region) > queue.MaxLatency {
+				fits = false
+				break
+			}
+		}
+
+		if fits {
+			return candidate
+		}
+	}
+
+	return nil
+}
+
+// formBand registers a gathering owned and hosted by the first member, adds every member to it and announces it
+// to the members who joined. It returns the gathering ID, 0 if no member could join, and the members who could not
+func (queue *MatchmakingQueue) formBand(members []*QueueEntry) (uint32, []*QueueEntry) {
+	gathering := NewGathering()
+	if queue.GatheringTemplate != nil {
+		gathering = queue.GatheringTemplate.Copy()
+	}
+
+	gathering.MaxParticipants = uint16(len(members))
+
+	gatheringID := queue.manager.Register(members[0].PID, gathering)
+
+	joined := make([]*QueueEntry, 0, len(members))
+	failed := make([]*QueueEntry, 0)
+	pids := make([]uint32, 0, len(members))
+
+	for _, member := range members {
+		err := queue.manager.Participate(member.PID, gatheringID)
+		if err != nil {
+			failed = append(failed, member)
+			continue
+		}
+
+		joined = append(joined, member)
+		pids = append(pids, member.PID)
+	}
+
+	if len(joined) == 0 {
+		_ = queue.manager.Terminate(members[0].PID, gatheringID)
+		return 0, failed
+	}
+
+	if queue.notifications != nil {
+		queue.notifications.SendToPIDs(pids, &NotificationEvent{
+			PIDSource: members[0].PID,
+			Type:      NotificationEventSwitchGathering,
+			Param1:    gatheringID,
+		})
+	}
+
+	if queue.BandFormedHandler != nil {
+		queue.BandFormedHandler(queue.manager.Gathering(gatheringID), joined)
+	}
+
+	return gatheringID, failed
+}
+
+func (queue *MatchmakingQueue) latency(regionA string, regionB string) time.Duration {
+	if queue.LatencyEstimate != nil {
+		return queue.LatencyEstimate(regionA, regionB)
+	}
+
+	return DefaultLatencyEstimate(regionA, regionB)
+}
+
+// requeue puts back an entry taken out of the queue, in the order of its EnqueuedAt, unless its PID was queued
+// again in the meantime. The caller must hold the lock
+func (queue *MatchmakingQueue) requeue(entry *QueueEntry) {
+	for _, queued := range queue.entries {
+		if queued.PID == entry.PID {
+			return
+		}
+	}
+
+	index := sort.Search(len(queue.entries), func(i int) bool {
+		return queue.entries[i].EnqueuedAt.After(entry.EnqueuedAt)
+	})
+
+	queue.entries = append(queue.entries, nil)
+	copy(queue.entries[index+1:], queue.entries[index:])
+	queue.entries[index] = entry
+}
+
+// remove takes pid out of the queue. The caller must hold the lock
+func (queue *MatchmakingQueue) remove(pid uint32) {
+	for i, entry := range queue.entries {
+		if entry.PID == pid {
+			queue.entries = append(queue.entries[:i], queue.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// DefaultLatencyEstimate estimates 30ms between players of the same region and 150ms otherwise
+func DefaultLatencyEstimate(regionA string, regionB string) time.Duration {
+	if regionA != "" && regionA == regionB {
+		return 30 * time.Millisecond
+	}
+
+	return 150 * time.Millisecond
+}
+
+// RegionFromStationURL returns the RegionFromAddress of the address in a station URL
+func RegionFromStationURL(stationURL string) string {
+	if index := strings.Index(stationURL, ":/"); index != -1 {
+		stationURL = stationURL[index+2:]
+	}
+
+	for _, field := range strings.Split(stationURL, ";") {
+		if strings.HasPrefix(field, "address=") {
+			return RegionFromAddress(strings.TrimPrefix(field, "address="))
+		}
+	}
+
+	return ""
+}
+
+// RegionFromAddress is a coarse region heuristic: the first two octets of an IPv4 address, any other address being
+// its own region. It only tells whether two players share a /16 network and knows nothing of geography, so servers
+// with location data should set RegionResolver or call SetRegion instead
+func RegionFromAddress(address string) string {
+	octets := strings.Split(address, ".")
+	if len(octets) != 4 {
+		return address
+	}
+
+	return octets[0] + "." + octets[1]
+}
+
+func absInt(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
+
+// NewMatchmakingQueue returns a new MatchmakingQueue registering a gathering in manager for every band filling bandRoles.
+// Players are taken out of the queue when their client disconnects
+func NewMatchmakingQueue(server *nex.Server, manager *GatheringManager, bandRoles []uint32) *MatchmakingQueue {
+	queue := &MatchmakingQueue{
+		manager:           manager,
+		entries:           make([]*QueueEntry, 0),
+		BandRoles:         bandRoles,
+		SkillWindow:       100,
+		SkillWindowGrowth: 10,
+		MaxLatency:        100 * time.Millisecond,
+		RelaxLatencyAfter: 60 * time.Second,
+	}
+
+	leave := func(packet nex.PacketInterface) {
+		queue.leave(packet.Sender())
+	}
+
+	server.On("Disconnect", leave)
+	server.On("Kick", leave)
+
+	return queue
+}
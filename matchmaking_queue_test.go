@@ -0,0 +1,165 @@
+package nexproto
+
+import (
+	"testing"
+	"time"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+func TestMatchmakingQueueBandFormation(t *testing.T) {
+	manager := NewGatheringManager()
+	queue := NewMatchmakingQueue(nex.NewServer(), manager, []uint32{1, 2, 3})
+
+	var bands [][]*QueueEntry
+
+	queue.OnBandFormed(func(gathering *Gathering, members []*QueueEntry) {
+		bands = append(bands, members)
+	})
+
+	queue.Enqueue(&QueueEntry{PID: 1, Instrument: 1, Skill: 1000, Region: "10.1"})
+	queue.Enqueue(&QueueEntry{PID: 2, Instrument: 2, Skill: 1500, Region: "10.1"})
+	queue.Enqueue(&QueueEntry{PID: 3, Instrument: 3, Skill: 1050, Region: "10.2"})
+	queue.Enqueue(&QueueEntry{PID: 4, Instrument: 2, Skill: 1020, Region: "10.1"})
+
+	if len(bands) != 0 {
+		t.Fatal("a band was formed across regions over MaxLatency")
+	}
+
+	queue.Enqueue(&QueueEntry{PID: 5, Instrument: InstrumentAny, Skill: 990, Region: "10.1"})
+
+	if len(bands) != 1 || len(bands[0]) != 3 {
+		t.Fatalf("bands formed: %v", bands)
+	}
+
+	for i, want := range []uint32{1, 4, 5} {
+		if bands[0][i].PID != want {
+			t.Fatalf("band member %d is %d, want %d", i, bands[0][i].PID, want)
+		}
+	}
+
+	if left := queue.Entries(); len(left) != 2 || left[0].PID != 2 || left[1].PID != 3 {
+		t.Fatalf("left in the queue: %v", left)
+	}
+
+	gatherings := manager.Gatherings()
+	if len(gatherings) != 1 || gatherings[0].OwnerPID != 1 || len(manager.Participants(gatherings[0].ID)) != 3 {
+		t.Fatalf("band gatherings: %v", gatherings)
+	}
+}
+
+func TestMatchmakingQueueRequeuesFailedMembers(t *testing.T) {
+	manager := NewGatheringManager()
+	queue := NewMatchmakingQueue(nex.NewServer(), manager, []uint32{1, 2, 3})
+
+	// A PID taking a slot of the band as it is being formed leaves the last member out
+	manager.OnParticipantJoined(func(gathering *Gathering, pid uint32, participants []uint32) {
+		if pid == 1 {
+			manager.Participate(99, gathering.ID)
+		}
+	})
+
+	var members []*QueueEntry
+
+	queue.OnBandFormed(func(gathering *Gathering, bandMembers []*QueueEntry) {
+		members = bandMembers
+	})
+
+	enqueuedAt := time.Now().Add(-time.Minute)
+
+	queue.Enqueue(&QueueEntry{PID: 1, Instrument: 1, EnqueuedAt: enqueuedAt})
+	queue.Enqueue(&QueueEntry{PID: 2, Instrument: 2, EnqueuedAt: enqueuedAt})
+	queue.Enqueue(&QueueEntry{PID: 4, Instrument: 1, EnqueuedAt: enqueuedAt.Add(time.Second)})
+	queue.Enqueue(&QueueEntry{PID: 3, Instrument: 3, EnqueuedAt: enqueuedAt})
+
+	if len(members) != 2 || members[0].PID != 1 || members[1].PID != 2 {
+		t.Fatalf("band formed with %v", members)
+	}
+
+	left := queue.Entries()
+	if len(left) != 2 || left[0].PID != 3 || left[1].PID != 4 || !left[0].EnqueuedAt.Equal(enqueuedAt) {
+		t.Fatalf("left in the queue: %v", left)
+	}
+}
+
+func TestMatchmakingQueueRequeuesUnjoinableBand(t *testing.T) {
+	manager := NewGatheringManager()
+	manager.GatheringLifetime = time.Minute
+
+	// Every gathering has expired by the time the band joins it
+	now := time.Now()
+	manager.clock = func() time.Time {
+		now = now.Add(time.Hour)
+		return now
+	}
+
+	queue := NewMatchmakingQueue(nex.NewServer(), manager, []uint32{1, 2})
+
+	formed := 0
+
+	queue.OnBandFormed(func(gathering *Gathering, members []*QueueEntry) {
+		formed++
+	})
+
+	queue.Enqueue(&QueueEntry{PID: 1, Instrument: 1})
+	queue.Enqueue(&QueueEntry{PID: 2, Instrument: 2})
+
+	if formed != 0 || len(queue.Entries()) != 2 {
+		t.Fatalf("%d bands formed, %d players left in the queue", formed, len(queue.Entries()))
+	}
+}
+
+func TestMatchmakingQueueClients(t *testing.T) {
+	server := nex.NewServer()
+	queue := NewMatchmakingQueue(server, NewGatheringManager(), []uint32{1, 2})
+	queue.RegionResolver = func(client *nex.Client) string {
+		return "region"
+	}
+
+	stale := newTestClient(server, 1)
+	current := newTestClient(server, 1)
+
+	queue.enqueueNew(queue.newEntry(stale, InstrumentAny))
+
+	queued := queue.Entries()
+	if len(queued) != 1 || queued[0].Region != "region" {
+		t.Fatalf("queued %v", queued)
+	}
+
+	queue.enqueueNew(queue.newEntry(current, InstrumentAny))
+
+	if entries := queue.Entries(); len(entries) != 1 || !entries[0].EnqueuedAt.Equal(queued[0].EnqueuedAt) {
+		t.Fatalf("queuing an already queued PID again gave %v", entries)
+	}
+
+	queue.Enqueue(queue.newEntry(current, InstrumentAny))
+	queue.leave(stale)
+
+	if len(queue.Entries()) != 1 {
+		t.Fatal("a stale client dropping took the PID out of the queue")
+	}
+
+	queue.leave(current)
+
+	if len(queue.Entries()) != 0 {
+		t.Fatal("the current client dropping left the PID in the queue")
+	}
+}
+
+func TestRegionFromStationURL(t *testing.T) {
+	tests := []struct {
+		stationURL string
+		want       string
+	}{
+		{"prudp:/address=10.1.2.3;port=9103;type=2", "10.1"},
+		{"prudps:/port=9103;address=192.168.0.4", "192.168"},
+		{"prudp:/address=::1;port=9103", "::1"},
+		{"prudp:/port=9103", ""},
+	}
+
+	for _, test := range tests {
+		if region := RegionFromStationURL(test.stationURL); region != test.want {
+			t.Errorf("RegionFromStationURL(%q) is %q, want %q", test.stationURL, region, test.want)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package nexproto
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+const (
+	// MessageDeliveryProtocolID is the protocol ID for the MessageDelivery protocol
+	MessageDeliveryProtocolID = 0x1B
+
+	// MessageDeliveryMethodDeliverMessage is the method ID for method DeliverMessage
+	MessageDeliveryMethodDeliverMessage = 0x1
+)
+
+// MessageDeliveryProtocol pushes messages to connected clients with DeliverMessage requests.
+// Messages for PIDs which are not connected are queued and pushed once a client bound to the PID talks to the server again,
+// unless a Mailbox already keeps them for the PID
+type MessageDeliveryProtocol struct {
+	server                *nex.Server
+	mutex                 sync.Mutex
+	lastCallID            uint32
+	pending               map[uint32][]*DataHolder
+	gatheringManager      *GatheringManager
+	mailbox               *Mailbox
+	moderator             *Moderator
+	MaxQueuedMessages     int // messages kept per offline PID, the oldest being dropped first. 0 for no limit
+	MaxQueuedPIDs         int // offline PIDs messages are queued for, messages to further PIDs being dropped. 0 for no limit
+	DeliverMessageHandler func(err error, client *nex.Client, callID uint32, message *DataHolder)
+}
+
+// Setup initializes the protocol
+func (messageDeliveryProtocol *MessageDeliveryProtocol) Setup() {
+	nexServer := messageDeliveryProtocol.server
+
+	trackPacketRoutes(nexServer)
+
+	nexServer.On("Data", func(packet nex.PacketInterface) {
+		messageDeliveryProtocol.flush(packet.Sender())
+
+		request := packet.RMCRequest()
+
+		if MessageDeliveryProtocolID == request.ProtocolID() {
+			rememberPacketRoute(packet)
+
+			switch request.MethodID() {
+			case MessageDeliveryMethodDeliverMessage:
+				go messageDeliveryProtocol.handleDeliverMessage(packet)
+			default:
+				log.Printf("Unsupported MessageDelivery method ID: %#v\n", request.MethodID())
+			}
+		}
+	})
+}
+
+// DeliverMessage sets the DeliverMessage handler function, called when a client delivers a message through this protocol
+func (messageDeliveryProtocol *MessageDeliveryProtocol) DeliverMessage(handler func(err error, client *nex.Client, callID uint32, message *DataHolder)) {
+	messageDeliveryProtocol.DeliverMessageHandler = handler
+}
+
+// Send pushes message to client with a DeliverMessage request
+func (messageDeliveryProtocol *MessageDeliveryProtocol) Send(client *nex.Client, message *DataHolder) {
+	request := nex.NewRMCRequest()
+	request.SetProtocolID(MessageDeliveryProtocolID)
+	request.SetCallID(atomic.AddUint32(&messageDeliveryProtocol.lastCallID, 1))
+	request.SetMethodID(MessageDeliveryMethodDeliverMessage)
+	request.SetParameters(message.Bytes(nex.NewStreamOut(messageDeliveryProtocol.server)))
+
+	sendRMCPayload(client, request.Bytes())
+}
+
+// SendToPID pushes message to the client bound to pid, queueing it if pid is not connected.
+// It returns whether the message was pushed immediately
+func (messageDeliveryProtocol *MessageDeliveryProtocol) SendToPID(pid uint32, message *DataHolder) bool {
+	return messageDeliveryProtocol.sendToPID(pid, message, true)
+}
+
+// SendToRecipient pushes message to a PID, or to every participant of a gathering except excludePID.
+// Gathering recipients are only resolved once UseGatheringManager is set
+func (messageDeliveryProtocol *MessageDeliveryProtocol) SendToRecipient(recipient *MessageRecipient, message *DataHolder, excludePID uint32) {
+	messageDeliveryProtocol.sendToRecipient(recipient, message, excludePID, true)
+}
+
+// sendToPID pushes message to the client bound to pid, queueing it if pid is not connected and queue is set
+func (messageDeliveryProtocol *MessageDeliveryProtocol) sendToPID(pid uint32, message *DataHolder, queue bool) bool {
+	client := messageDeliveryProtocol.server.FindClientFromPID(pid)
+	if client != nil {
+		messageDeliveryProtocol.Send(client, message)
+		return true
+	}
+
+	if !queue {
+		return false
+	}
+
+	messageDeliveryProtocol.mutex.Lock()
+	defer messageDeliveryProtocol.mutex.Unlock()
+
+	queued, ok := messageDeliveryProtocol.pending[pid]
+
+	maxQueuedPIDs := messageDeliveryProtocol.MaxQueuedPIDs
+	if !ok && maxQueuedPIDs != 0 && len(messageDeliveryProtocol.pending) >= maxQueuedPIDs {
+		return false
+	}
+
+	queued = append(queued, message.Copy())
+
+	maxQueuedMessages := messageDeliveryProtocol.MaxQueuedMessages
+	if maxQueuedMessages != 0 && len(queued) > maxQueuedMessages {
+		queued = queued[len(queued)-maxQueuedMessages:]
+	}
+
+	messageDeliveryProtocol.pending[pid] = queued
+
+	return false
+}
+
+// sendToRecipient pushes message to a PID or the participants of a gathering, queueing it for offline PIDs if queue is set
+func (messageDeliveryProtocol *MessageDeliveryProtocol) sendToRecipient(recipient *MessageRecipient, message *DataHolder, excludePID uint32, queue bool) {
+	switch recipient.Type {
+	case MessageRecipientTypePID:
+		messageDeliveryProtocol.sendToPID(recipient.ID, message, queue)
+	case MessageRecipientTypeGathering:
+		if messageDeliveryProtocol.gatheringManager == nil {
+			log.Println("[Warning] MessageDeliveryProtocol::SendToRecipient called for a gathering without a GatheringManager")
+			return
+		}
+
+		participants := messageDeliveryProtocol.gatheringManager.Participants(recipient.ID)
+
+		for _, pid := range excludePIDs(participants, []uint32{excludePID}) {
+			messageDeliveryProtocol.sendToPID(pid, message, queue)
+		}
+	}
+}
+
+// Pending returns the number of messages queued for pid
+func (messageDeliveryProtocol *MessageDeliveryProtocol) Pending(pid uint32) int {
+	messageDeliveryProtocol.mutex.Lock()
+	defer messageDeliveryProtocol.mutex.Unlock()
+
+	return len(messageDeliveryProtocol.pending[pid])
+}
+
+// Discard drops the messages queued for pid
+func (messageDeliveryProtocol *MessageDeliveryProtocol) Discard(pid uint32) {
+	messageDeliveryProtocol.mutex.Lock()
+	defer messageDeliveryProtocol.mutex.Unlock()
+
+	delete(messageDeliveryProtocol.pending, pid)
+}
+
+// UseGatheringManager lets SendToRecipient resolve the participants of gathering recipients from manager
+func (messageDeliveryProtocol *MessageDeliveryProtocol) UseGatheringManager(manager *GatheringManager) {
+	messageDeliveryProtocol.gatheringManager = manager
+}
+
+// UseMailbox pushes every message delivered to mailbox to its connected recipients, and delivers messages sent
+// through this protocol to mailbox. Messages are not queued for offline recipients, which find them in mailbox.
+// Handlers already set on mailbox keep being called
+func (messageDeliveryProtocol *MessageDeliveryProtocol) UseMailbox(mailbox *Mailbox) {
+	messageDeliveryProtocol.mailbox = mailbox
+
+	messageDeliveredHandler := mailbox.MessageDeliveredHandler
+	mailbox.OnMessageDelivered(func(message *MailboxMessage) {
+		recipient := &MessageRecipient{
+			ID:   message.RecipientID,
+			Type: message.RecipientType,
+		}
+
+		messageDeliveryProtocol.sendToRecipient(recipient, message.DataHolder(), message.SenderPID, false)
+
+		if messageDeliveredHandler != nil {
+			messageDeliveredHandler(message)
+		}
+	})
+
+	messageDeliveryProtocol.DeliverMessage(messageDeliveryProtocol.handleMailboxDelivery)
+}
+
+// UseModerator runs the text of messages delivered through this protocol through moderator before they reach the handler
+func (messageDeliveryProtocol *MessageDeliveryProtocol) UseModerator(moderator *Moderator) {
+	messageDeliveryProtocol.moderator = moderator
+}
+
+// handleMailboxDelivery is the DeliverMessage handler installed by UseMailbox
+func (messageDeliveryProtocol *MessageDeliveryProtocol) handleMailboxDelivery(err error, client *nex.Client, callID uint32, message *DataHolder) {
+	if err != nil {
+		respondError(client, MessageDeliveryProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	userMessage, err := DecodeUserMessage(message, client.Server())
+	if err != nil {
+		respondError(client, MessageDeliveryProtocolID, callID, ResultCoreInvalidArgument)
+		return
+	}
+
+	_, err = messageDeliveryProtocol.mailbox.Deliver(client.PID(), []*MessageRecipient{userMessage.Header().Recipient}, userMessage)
+	if err != nil {
+		respondError(client, MessageDeliveryProtocolID, callID, mailboxResultCode(err))
+		return
+	}
+
+	respondSuccess(client, MessageDeliveryProtocolID, MessageDeliveryMethodDeliverMessage, callID, make([]byte, 0))
+}
+
+func (messageDeliveryProtocol *MessageDeliveryProtocol) handleDeliverMessage(packet nex.PacketInterface) {
+	if messageDeliveryProtocol.DeliverMessageHandler == nil {
+		log.Println("[Warning] MessageDeliveryProtocol::DeliverMessage not implemented")
+		go respondNotImplemented(packet, MessageDeliveryProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, messageDeliveryProtocol.server)
+
+	messageStructureInterface, err := parametersStream.ReadStructure(NewDataHolder())
+	if err != nil {
+		go messageDeliveryProtocol.DeliverMessageHandler(err, client, callID, nil)
+		return
+	}
+
+	message := messageStructureInterface.(*DataHolder)
+
+	if messageDeliveryProtocol.moderator != nil {
+		message, err = moderateMessageDataHolder(messageDeliveryProtocol.moderator, client.PID(), message, messageDeliveryProtocol.server)
+		if err != nil {
+			go respondError(client, MessageDeliveryProtocolID, callID, ResultCoreAccessDenied)
+			return
+		}
+	}
+
+	go messageDeliveryProtocol.DeliverMessageHandler(nil, client, callID, message)
+}
+
+// flush pushes the messages queued for the PID bound to client, if any
+func (messageDeliveryProtocol *MessageDeliveryProtocol) flush(client *nex.Client) {
+	pid := client.PID()
+	if pid == 0 {
+		return
+	}
+
+	messageDeliveryProtocol.mutex.Lock()
+
+	queued, ok := messageDeliveryProtocol.pending[pid]
+	if ok {
+		delete(messageDeliveryProtocol.pending, pid)
+	}
+
+	messageDeliveryProtocol.mutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	go func() {
+		for _, message := range queued {
+			messageDeliveryProtocol.Send(client, message)
+		}
+	}()
+}
+
+// NewMessageDeliveryProtocol returns a new MessageDeliveryProtocol
+func NewMessageDeliveryProtocol(server *nex.Server) *MessageDeliveryProtocol {
+	messageDeliveryProtocol := &MessageDeliveryProtocol{
+		server:            server,
+		pending:           make(map[uint32][]*DataHolder),
+		MaxQueuedMessages: 100,
+		MaxQueuedPIDs:     10000,
+	}
+
+	messageDeliveryProtocol.Setup()
+
+	return messageDeliveryProtocol
+}
@@ -1,6 +1,7 @@
 package nexproto
 
 import (
+	"errors"
 	"log"
 
 	nex "github.com/jnackmclain/nex-go"
@@ -10,24 +11,363 @@ const (
 	MessagingProtocolID = 0x17
 
 	GetMessageHeaders = 0x3
+
+	// MessagingMethodDeliverMessage is the method ID for method DeliverMessage
+	MessagingMethodDeliverMessage = 0x1
+
+	// MessagingMethodGetNumberOfMessages is the method ID for method GetNumberOfMessages
+	MessagingMethodGetNumberOfMessages = 0x2
+
+	// MessagingMethodGetMessagesHeaders is the method ID for method GetMessagesHeaders, handled as GetMessageHeaders
+	MessagingMethodGetMessagesHeaders = GetMessageHeaders
+
+	// MessagingMethodRetrieveAllMessagesWithinRange is the method ID for method RetrieveAllMessagesWithinRange
+	MessagingMethodRetrieveAllMessagesWithinRange = 0x4
+
+	// MessagingMethodRetrieveMessages is the method ID for method RetrieveMessages
+	MessagingMethodRetrieveMessages = 0x5
+
+	// MessagingMethodDeleteMessages is the method ID for method DeleteMessages
+	MessagingMethodDeleteMessages = 0x6
+
+	// MessagingMethodDeleteAllMessages is the method ID for method DeleteAllMessages
+	MessagingMethodDeleteAllMessages = 0x7
+
+	// MessagingMethodDeliverMessageMultiTarget is the method ID for method DeliverMessageMultiTarget
+	MessagingMethodDeliverMessageMultiTarget = 0x8
+)
+
+const (
+	// MessageRecipientTypePID addresses a message to a single PID
+	MessageRecipientTypePID = 1
+
+	// MessageRecipientTypeGathering addresses a message to every participant of a gathering
+	MessageRecipientTypeGathering = 2
 )
 
+// MessageRecipient is the mailbox a message is addressed to. ID is a PID or a gathering ID depending on Type
+type MessageRecipient struct {
+	ID   uint32
+	Type uint32
+
+	nex.Structure
+}
+
+// Bytes encodes the MessageRecipient and returns a byte array
+func (messageRecipient *MessageRecipient) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt32LE(messageRecipient.ID)
+	stream.WriteUInt32LE(messageRecipient.Type)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a MessageRecipient structure from a stream
+func (messageRecipient *MessageRecipient) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 8 {
+		// length check for the following fixed-size data
+		// id + type
+		return errors.New("[MessageRecipient::ExtractFromStream] Data size too small")
+	}
+
+	messageRecipient.ID = stream.ReadUInt32LE()
+	messageRecipient.Type = stream.ReadUInt32LE()
+
+	return nil
+}
+
+// NewMessageRecipient returns a new MessageRecipient
+func NewMessageRecipient() *MessageRecipient {
+	return &MessageRecipient{}
+}
+
+const (
+	// UserMessageClassName is the data holder class name of a UserMessage
+	UserMessageClassName = "UserMessage"
+
+	// TextMessageClassName is the data holder class name of a TextMessage
+	TextMessageClassName = "TextMessage"
+
+	// BinaryMessageClassName is the data holder class name of a BinaryMessage
+	BinaryMessageClassName = "BinaryMessage"
+)
+
+// UserMessageInterface is implemented by UserMessage and its subclasses
+type UserMessageInterface interface {
+	nex.StructureInterface
+	ClassName() string
+	Header() *UserMessage
+}
+
+// UserMessage holds the fields common to every message. On its own it is the message header returned by GetMessageHeaders
+type UserMessage struct {
+	ID            uint32
+	ParentID      uint32
+	SenderPID     uint32
+	ReceptionTime *nex.DateTime
+	LifeTime      uint32 // seconds, 0 keeps the message until it is deleted
+	Flags         uint32
+	Subject       string
+	Sender        string
+	Recipient     *MessageRecipient
+
+	nex.Structure
+}
+
+// Bytes encodes the UserMessage and returns a byte array
+func (userMessage *UserMessage) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt32LE(userMessage.ID)
+	stream.WriteUInt32LE(userMessage.ParentID)
+	stream.WriteUInt32LE(userMessage.SenderPID)
+	stream.WriteUInt64LE(userMessage.ReceptionTime.Value())
+	stream.WriteUInt32LE(userMessage.LifeTime)
+	stream.WriteUInt32LE(userMessage.Flags)
+	write4ByteString(stream, userMessage.Subject)
+	write4ByteString(stream, userMessage.Sender)
+	userMessage.Recipient.Bytes(stream)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a UserMessage structure from a stream
+func (userMessage *UserMessage) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 28 {
+		// length check for the following fixed-size data
+		// id + parentID + senderPID + receptionTime + lifeTime + flags
+		return errors.New("[UserMessage::ExtractFromStream] Data size too small")
+	}
+
+	id := stream.ReadUInt32LE()
+	parentID := stream.ReadUInt32LE()
+	senderPID := stream.ReadUInt32LE()
+	receptionTime := nex.NewDateTime(stream.ReadUInt64LE())
+	lifeTime := stream.ReadUInt32LE()
+	flags := stream.ReadUInt32LE()
+	subject, err := stream.Read4ByteString()
+	if err != nil {
+		return err
+	}
+
+	sender, err := stream.Read4ByteString()
+	if err != nil {
+		return err
+	}
+
+	recipient := NewMessageRecipient()
+
+	err = recipient.ExtractFromStream(stream)
+	if err != nil {
+		return err
+	}
+
+	userMessage.ID = id
+	userMessage.ParentID = parentID
+	userMessage.SenderPID = senderPID
+	userMessage.ReceptionTime = receptionTime
+	userMessage.LifeTime = lifeTime
+	userMessage.Flags = flags
+	userMessage.Subject = subject
+	userMessage.Sender = sender
+	userMessage.Recipient = recipient
+
+	return nil
+}
+
+// ClassName returns the data holder class name of the UserMessage
+func (userMessage *UserMessage) ClassName() string {
+	return UserMessageClassName
+}
+
+// Header returns the UserMessage fields of the message. Changes to it are made to the message itself
+func (userMessage *UserMessage) Header() *UserMessage {
+	return userMessage
+}
+
+// Copy returns a copy of the UserMessage fields, without any subclass data
+func (userMessage *UserMessage) Copy() *UserMessage {
+	return &UserMessage{
+		ID:            userMessage.ID,
+		ParentID:      userMessage.ParentID,
+		SenderPID:     userMessage.SenderPID,
+		ReceptionTime: nex.NewDateTime(userMessage.ReceptionTime.Value()),
+		LifeTime:      userMessage.LifeTime,
+		Flags:         userMessage.Flags,
+		Subject:       userMessage.Subject,
+		Sender:        userMessage.Sender,
+		Recipient: &MessageRecipient{
+			ID:   userMessage.Recipient.ID,
+			Type: userMessage.Recipient.Type,
+		},
+	}
+}
+
+// NewUserMessage returns a new UserMessage
+func NewUserMessage() *UserMessage {
+	return &UserMessage{
+		ReceptionTime: nex.NewDateTime(0),
+		Recipient:     NewMessageRecipient(),
+	}
+}
+
+// TextMessage is a UserMessage with a text body
+type TextMessage struct {
+	UserMessage
+	TextBody string
+}
+
+// Bytes encodes the TextMessage and returns a byte array
+func (textMessage *TextMessage) Bytes(stream *nex.StreamOut) []byte {
+	textMessage.UserMessage.Bytes(stream)
+	write4ByteString(stream, textMessage.TextBody)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a TextMessage structure from a stream
+func (textMessage *TextMessage) ExtractFromStream(stream *nex.StreamIn) error {
+	err := textMessage.UserMessage.ExtractFromStream(stream)
+	if err != nil {
+		return err
+	}
+
+	textBody, err := stream.Read4ByteString()
+	if err != nil {
+		return err
+	}
+
+	textMessage.TextBody = textBody
+
+	return nil
+}
+
+// ClassName returns the data holder class name of the TextMessage
+func (textMessage *TextMessage) ClassName() string {
+	return TextMessageClassName
+}
+
+// NewTextMessage returns a new TextMessage
+func NewTextMessage() *TextMessage {
+	return &TextMessage{
+		UserMessage: *NewUserMessage(),
+	}
+}
+
+// BinaryMessage is a UserMessage with a binary body
+type BinaryMessage struct {
+	UserMessage
+	BinaryBody []byte
+}
+
+// Bytes encodes the BinaryMessage and returns a byte array
+func (binaryMessage *BinaryMessage) Bytes(stream *nex.StreamOut) []byte {
+	binaryMessage.UserMessage.Bytes(stream)
+	stream.WriteBuffer(binaryMessage.BinaryBody)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a BinaryMessage structure from a stream
+func (binaryMessage *BinaryMessage) ExtractFromStream(stream *nex.StreamIn) error {
+	err := binaryMessage.UserMessage.ExtractFromStream(stream)
+	if err != nil {
+		return err
+	}
+
+	binaryBody, err := stream.ReadBuffer()
+	if err != nil {
+		return err
+	}
+
+	binaryMessage.BinaryBody = binaryBody
+
+	return nil
+}
+
+// ClassName returns the data holder class name of the BinaryMessage
+func (binaryMessage *BinaryMessage) ClassName() string {
+	return BinaryMessageClassName
+}
+
+// NewBinaryMessage returns a new BinaryMessage
+func NewBinaryMessage() *BinaryMessage {
+	return &BinaryMessage{
+		UserMessage: *NewUserMessage(),
+		BinaryBody:  make([]byte, 0),
+	}
+}
+
+// DecodeUserMessage decodes the UserMessage, TextMessage or BinaryMessage carried by a data holder
+func DecodeUserMessage(dataHolder *DataHolder, server *nex.Server) (UserMessageInterface, error) {
+	var userMessage UserMessageInterface
+
+	switch dataHolder.ClassName {
+	case UserMessageClassName:
+		userMessage = NewUserMessage()
+	case TextMessageClassName:
+		userMessage = NewTextMessage()
+	case BinaryMessageClassName:
+		userMessage = NewBinaryMessage()
+	default:
+		return nil, errors.New("[DecodeUserMessage] Unknown message class " + dataHolder.ClassName)
+	}
+
+	err := userMessage.ExtractFromStream(nex.NewStreamIn(dataHolder.Data, server))
+	if err != nil {
+		return nil, err
+	}
+
+	return userMessage, nil
+}
+
+// NewUserMessageDataHolder wraps a message in a data holder of its class
+func NewUserMessageDataHolder(userMessage UserMessageInterface, server *nex.Server) *DataHolder {
+	return &DataHolder{
+		ClassName: userMessage.ClassName(),
+		Data:      userMessage.Bytes(nex.NewStreamOut(server)),
+	}
+}
+
 type MessagingProtocol struct {
-	server                   *nex.Server
-	ConnectionIDCounter      *nex.Counter
-	GetMessageHeadersHandler func(err error, client *nex.Client, callID uint32, pid uint32, recipientType uint32, rangeOffset uint32, rangeSize uint32)
+	server                                *nex.Server
+	moderator                             *Moderator
+	ConnectionIDCounter                   *nex.Counter
+	GetMessageHeadersHandler              func(err error, client *nex.Client, callID uint32, pid uint32, recipientType uint32, rangeOffset uint32, rangeSize uint32)
+	DeliverMessageHandler                 func(err error, client *nex.Client, callID uint32, message *DataHolder)
+	GetNumberOfMessagesHandler            func(err error, client *nex.Client, callID uint32, recipient *MessageRecipient)
+	RetrieveAllMessagesWithinRangeHandler func(err error, client *nex.Client, callID uint32, recipient *MessageRecipient, resultRange *ResultRange)
+	RetrieveMessagesHandler               func(err error, client *nex.Client, callID uint32, recipient *MessageRecipient, messageIDs []uint32, leaveOnServer bool)
+	DeleteMessagesHandler                 func(err error, client *nex.Client, callID uint32, recipient *MessageRecipient, messageIDs []uint32)
+	DeleteAllMessagesHandler              func(err error, client *nex.Client, callID uint32, recipient *MessageRecipient)
+	DeliverMessageMultiTargetHandler      func(err error, client *nex.Client, callID uint32, recipients []*MessageRecipient, message *DataHolder)
 }
 
-func (unknownProtocol *MessagingProtocol) Setup() {
-	nexServer := unknownProtocol.server
+func (messagingProtocol *MessagingProtocol) Setup() {
+	nexServer := messagingProtocol.server
+
+	trackPacketRoutes(nexServer)
 
 	nexServer.On("Data", func(packet nex.PacketInterface) {
 		request := packet.RMCRequest()
 
 		if MessagingProtocolID == request.ProtocolID() {
+			rememberPacketRoute(packet)
+
 			switch request.MethodID() {
 			case GetMessageHeaders:
-				go unknownProtocol.handleGetMessageHeaders(packet)
+				go messagingProtocol.handleGetMessageHeaders(packet)
+			case MessagingMethodDeliverMessage:
+				go messagingProtocol.handleDeliverMessage(packet)
+			case MessagingMethodGetNumberOfMessages:
+				go messagingProtocol.handleGetNumberOfMessages(packet)
+			case MessagingMethodRetrieveAllMessagesWithinRange:
+				go messagingProtocol.handleRetrieveAllMessagesWithinRange(packet)
+			case MessagingMethodRetrieveMessages:
+				go messagingProtocol.handleRetrieveMessages(packet)
+			case MessagingMethodDeleteMessages:
+				go messagingProtocol.handleDeleteMessages(packet)
+			case MessagingMethodDeleteAllMessages:
+				go messagingProtocol.handleDeleteAllMessages(packet)
+			case MessagingMethodDeliverMessageMultiTarget:
+				go messagingProtocol.handleDeliverMessageMultiTarget(packet)
 			default:
 				log.Printf("Unsupported Messaging method ID: %#v\n", request.MethodID())
 			}
@@ -39,6 +379,139 @@ func (messagingProtocol *MessagingProtocol) GetMessageHeaders(handler func(err e
 	messagingProtocol.GetMessageHeadersHandler = handler
 }
 
+// DeliverMessage sets the DeliverMessage handler function
+func (messagingProtocol *MessagingProtocol) DeliverMessage(handler func(err error, client *nex.Client, callID uint32, message *DataHolder)) {
+	messagingProtocol.DeliverMessageHandler = handler
+}
+
+// GetNumberOfMessages sets the GetNumberOfMessages handler function
+func (messagingProtocol *MessagingProtocol) GetNumberOfMessages(handler func(err error, client *nex.Client, callID uint32, recipient *MessageRecipient)) {
+	messagingProtocol.GetNumberOfMessagesHandler = handler
+}
+
+// RetrieveAllMessagesWithinRange sets the RetrieveAllMessagesWithinRange handler function
+func (messagingProtocol *MessagingProtocol) RetrieveAllMessagesWithinRange(handler func(err error, client *nex.Client, callID uint32, recipient *MessageRecipient, resultRange *ResultRange)) {
+	messagingProtocol.RetrieveAllMessagesWithinRangeHandler = handler
+}
+
+// RetrieveMessages sets the RetrieveMessages handler function
+func (messagingProtocol *MessagingProtocol) RetrieveMessages(handler func(err error, client *nex.Client, callID uint32, recipient *MessageRecipient, messageIDs []uint32, leaveOnServer bool)) {
+	messagingProtocol.RetrieveMessagesHandler = handler
+}
+
+// DeleteMessages sets the DeleteMessages handler function
+func (messagingProtocol *MessagingProtocol) DeleteMessages(handler func(err error, client *nex.Client, callID uint32, recipient *MessageRecipient, messageIDs []uint32)) {
+	messagingProtocol.DeleteMessagesHandler = handler
+}
+
+// DeleteAllMessages sets the DeleteAllMessages handler function
+func (messagingProtocol *MessagingProtocol) DeleteAllMessages(handler func(err error, client *nex.Client, callID uint32, recipient *MessageRecipient)) {
+	messagingProtocol.DeleteAllMessagesHandler = handler
+}
+
+// DeliverMessageMultiTarget sets the DeliverMessageMultiTarget handler function
+func (messagingProtocol *MessagingProtocol) DeliverMessageMultiTarget(handler func(err error, client *nex.Client, callID uint32, recipients []*MessageRecipient, message *DataHolder)) {
+	messagingProtocol.DeliverMessageMultiTargetHandler = handler
+}
+
+// UseMailbox installs the default handlers of mailbox for every method
+func (messagingProtocol *MessagingProtocol) UseMailbox(mailbox *Mailbox) {
+	messagingProtocol.GetMessageHeaders(mailbox.HandleGetMessageHeaders)
+	messagingProtocol.DeliverMessage(mailbox.HandleDeliverMessage)
+	messagingProtocol.GetNumberOfMessages(mailbox.HandleGetNumberOfMessages)
+	messagingProtocol.RetrieveAllMessagesWithinRange(mailbox.HandleRetrieveAllMessagesWithinRange)
+	messagingProtocol.RetrieveMessages(mailbox.HandleRetrieveMessages)
+	messagingProtocol.DeleteMessages(mailbox.HandleDeleteMessages)
+	messagingProtocol.DeleteAllMessages(mailbox.HandleDeleteAllMessages)
+	messagingProtocol.DeliverMessageMultiTarget(mailbox.HandleDeliverMessageMultiTarget)
+}
+
+// UseModerator runs the text of delivered messages through moderator before they reach their handlers
+func (messagingProtocol *MessagingProtocol) UseModerator(moderator *Moderator) {
+	messagingProtocol.moderator = moderator
+}
+
+// moderate runs a message sent by client through the moderator, if one is set
+func (messagingProtocol *MessagingProtocol) moderate(client *nex.Client, message *DataHolder) (*DataHolder, error) {
+	if messagingProtocol.moderator == nil {
+		return message, nil
+	}
+
+	return moderateMessageDataHolder(messagingProtocol.moderator, client.PID(), message, messagingProtocol.server)
+}
+
+// RespondDeliverMessage answers a DeliverMessage call
+func (messagingProtocol *MessagingProtocol) RespondDeliverMessage(client *nex.Client, callID uint32) {
+	respondSuccess(client, MessagingProtocolID, MessagingMethodDeliverMessage, callID, make([]byte, 0))
+}
+
+// RespondGetNumberOfMessages answers a GetNumberOfMessages call with the number of messages in a mailbox
+func (messagingProtocol *MessagingProtocol) RespondGetNumberOfMessages(client *nex.Client, callID uint32, count uint32) {
+	respondMessageCount(client, callID, MessagingMethodGetNumberOfMessages, count)
+}
+
+// RespondGetMessageHeaders answers a GetMessageHeaders call with a list of message headers
+func (messagingProtocol *MessagingProtocol) RespondGetMessageHeaders(client *nex.Client, callID uint32, headers []*UserMessage) {
+	respondMessageHeaders(client, callID, headers)
+}
+
+// RespondRetrieveAllMessagesWithinRange answers a RetrieveAllMessagesWithinRange call with a list of messages
+func (messagingProtocol *MessagingProtocol) RespondRetrieveAllMessagesWithinRange(client *nex.Client, callID uint32, messages []*DataHolder) {
+	respondMessages(client, callID, MessagingMethodRetrieveAllMessagesWithinRange, messages)
+}
+
+// RespondRetrieveMessages answers a RetrieveMessages call with a list of messages
+func (messagingProtocol *MessagingProtocol) RespondRetrieveMessages(client *nex.Client, callID uint32, messages []*DataHolder) {
+	respondMessages(client, callID, MessagingMethodRetrieveMessages, messages)
+}
+
+// RespondDeleteMessages answers a DeleteMessages call
+func (messagingProtocol *MessagingProtocol) RespondDeleteMessages(client *nex.Client, callID uint32) {
+	respondSuccess(client, MessagingProtocolID, MessagingMethodDeleteMessages, callID, make([]byte, 0))
+}
+
+// RespondDeleteAllMessages answers a DeleteAllMessages call with the number of messages deleted
+func (messagingProtocol *MessagingProtocol) RespondDeleteAllMessages(client *nex.Client, callID uint32, count uint32) {
+	respondMessageCount(client, callID, MessagingMethodDeleteAllMessages, count)
+}
+
+// RespondDeliverMessageMultiTarget answers a DeliverMessageMultiTarget call
+func (messagingProtocol *MessagingProtocol) RespondDeliverMessageMultiTarget(client *nex.Client, callID uint32) {
+	respondSuccess(client, MessagingProtocolID, MessagingMethodDeliverMessageMultiTarget, callID, make([]byte, 0))
+}
+
+// respondMessageCount answers a messaging method returning a number of messages
+func respondMessageCount(client *nex.Client, callID uint32, methodID uint32, count uint32) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt32LE(count)
+
+	respondSuccess(client, MessagingProtocolID, methodID, callID, responseStream.Bytes())
+}
+
+// respondMessageHeaders answers GetMessageHeaders with a list of message headers
+func respondMessageHeaders(client *nex.Client, callID uint32, headers []*UserMessage) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt32LE(uint32(len(headers)))
+
+	for _, header := range headers {
+		responseStream.WriteStructure(header)
+	}
+
+	respondSuccess(client, MessagingProtocolID, GetMessageHeaders, callID, responseStream.Bytes())
+}
+
+// respondMessages answers a retrieve method with a list of messages in data holders
+func respondMessages(client *nex.Client, callID uint32, methodID uint32, messages []*DataHolder) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt32LE(uint32(len(messages)))
+
+	for _, message := range messages {
+		responseStream.WriteStructure(message)
+	}
+
+	respondSuccess(client, MessagingProtocolID, methodID, callID, responseStream.Bytes())
+}
+
 func (messagingProtocol *MessagingProtocol) handleGetMessageHeaders(packet nex.PacketInterface) {
 	if messagingProtocol.GetMessageHeadersHandler == nil {
 		log.Println("[Warning] MessagingProtocol::GetMessageHeadersHandler not implemented")
@@ -62,6 +535,233 @@ func (messagingProtocol *MessagingProtocol) handleGetMessageHeaders(packet nex.P
 	go messagingProtocol.GetMessageHeadersHandler(nil, client, callID, pid, recipientType, rangeOffset, rangeSize)
 }
 
+func (messagingProtocol *MessagingProtocol) handleDeliverMessage(packet nex.PacketInterface) {
+	if messagingProtocol.DeliverMessageHandler == nil {
+		log.Println("[Warning] MessagingProtocol::DeliverMessage not implemented")
+		go respondNotImplemented(packet, MessagingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, messagingProtocol.server)
+
+	messageStructureInterface, err := parametersStream.ReadStructure(NewDataHolder())
+	if err != nil {
+		go messagingProtocol.DeliverMessageHandler(err, client, callID, nil)
+		return
+	}
+
+	message, err := messagingProtocol.moderate(client, messageStructureInterface.(*DataHolder))
+	if err != nil {
+		go respondError(client, MessagingProtocolID, callID, ResultCoreAccessDenied)
+		return
+	}
+
+	go messagingProtocol.DeliverMessageHandler(nil, client, callID, message)
+}
+
+func (messagingProtocol *MessagingProtocol) handleGetNumberOfMessages(packet nex.PacketInterface) {
+	if messagingProtocol.GetNumberOfMessagesHandler == nil {
+		log.Println("[Warning] MessagingProtocol::GetNumberOfMessages not implemented")
+		go respondNotImplemented(packet, MessagingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, messagingProtocol.server)
+
+	recipientStructureInterface, err := parametersStream.ReadStructure(NewMessageRecipient())
+	if err != nil {
+		go messagingProtocol.GetNumberOfMessagesHandler(err, client, callID, nil)
+		return
+	}
+
+	recipient := recipientStructureInterface.(*MessageRecipient)
+
+	go messagingProtocol.GetNumberOfMessagesHandler(nil, client, callID, recipient)
+}
+
+func (messagingProtocol *MessagingProtocol) handleRetrieveAllMessagesWithinRange(packet nex.PacketInterface) {
+	if messagingProtocol.RetrieveAllMessagesWithinRangeHandler == nil {
+		log.Println("[Warning] MessagingProtocol::RetrieveAllMessagesWithinRange not implemented")
+		go respondNotImplemented(packet, MessagingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, messagingProtocol.server)
+
+	recipientStructureInterface, err := parametersStream.ReadStructure(NewMessageRecipient())
+	if err != nil {
+		go messagingProtocol.RetrieveAllMessagesWithinRangeHandler(err, client, callID, nil, nil)
+		return
+	}
+
+	resultRangeStructureInterface, err := parametersStream.ReadStructure(NewResultRange())
+	if err != nil {
+		go messagingProtocol.RetrieveAllMessagesWithinRangeHandler(err, client, callID, nil, nil)
+		return
+	}
+
+	recipient := recipientStructureInterface.(*MessageRecipient)
+	resultRange := resultRangeStructureInterface.(*ResultRange)
+
+	go messagingProtocol.RetrieveAllMessagesWithinRangeHandler(nil, client, callID, recipient, resultRange)
+}
+
+func (messagingProtocol *MessagingProtocol) handleRetrieveMessages(packet nex.PacketInterface) {
+	if messagingProtocol.RetrieveMessagesHandler == nil {
+		log.Println("[Warning] MessagingProtocol::RetrieveMessages not implemented")
+		go respondNotImplemented(packet, MessagingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, messagingProtocol.server)
+
+	recipientStructureInterface, err := parametersStream.ReadStructure(NewMessageRecipient())
+	if err != nil {
+		go messagingProtocol.RetrieveMessagesHandler(err, client, callID, nil, nil, false)
+		return
+	}
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[MessagingProtocol::RetrieveMessages] Data missing message ID list length")
+		go messagingProtocol.RetrieveMessagesHandler(err, client, callID, nil, nil, false)
+		return
+	}
+
+	messageIDs := parametersStream.ReadListUInt32LE()
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 1 {
+		err := errors.New("[MessagingProtocol::RetrieveMessages] Data missing leave on server flag")
+		go messagingProtocol.RetrieveMessagesHandler(err, client, callID, nil, nil, false)
+		return
+	}
+
+	leaveOnServer := parametersStream.ReadUInt8() == 1
+
+	recipient := recipientStructureInterface.(*MessageRecipient)
+
+	go messagingProtocol.RetrieveMessagesHandler(nil, client, callID, recipient, messageIDs, leaveOnServer)
+}
+
+func (messagingProtocol *MessagingProtocol) handleDeleteMessages(packet nex.PacketInterface) {
+	if messagingProtocol.DeleteMessagesHandler == nil {
+		log.Println("[Warning] MessagingProtocol::DeleteMessages not implemented")
+		go respondNotImplemented(packet, MessagingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, messagingProtocol.server)
+
+	recipientStructureInterface, err := parametersStream.ReadStructure(NewMessageRecipient())
+	if err != nil {
+		go messagingProtocol.DeleteMessagesHandler(err, client, callID, nil, nil)
+		return
+	}
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[MessagingProtocol::DeleteMessages] Data missing message ID list length")
+		go messagingProtocol.DeleteMessagesHandler(err, client, callID, nil, nil)
+		return
+	}
+
+	messageIDs := parametersStream.ReadListUInt32LE()
+
+	recipient := recipientStructureInterface.(*MessageRecipient)
+
+	go messagingProtocol.DeleteMessagesHandler(nil, client, callID, recipient, messageIDs)
+}
+
+func (messagingProtocol *MessagingProtocol) handleDeleteAllMessages(packet nex.PacketInterface) {
+	if messagingProtocol.DeleteAllMessagesHandler == nil {
+		log.Println("[Warning] MessagingProtocol::DeleteAllMessages not implemented")
+		go respondNotImplemented(packet, MessagingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, messagingProtocol.server)
+
+	recipientStructureInterface, err := parametersStream.ReadStructure(NewMessageRecipient())
+	if err != nil {
+		go messagingProtocol.DeleteAllMessagesHandler(err, client, callID, nil)
+		return
+	}
+
+	recipient := recipientStructureInterface.(*MessageRecipient)
+
+	go messagingProtocol.DeleteAllMessagesHandler(nil, client, callID, recipient)
+}
+
+func (messagingProtocol *MessagingProtocol) handleDeliverMessageMultiTarget(packet nex.PacketInterface) {
+	if messagingProtocol.DeliverMessageMultiTargetHandler == nil {
+		log.Println("[Warning] MessagingProtocol::DeliverMessageMultiTarget not implemented")
+		go respondNotImplemented(packet, MessagingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, messagingProtocol.server)
+
+	recipients, err := parametersStream.ReadListMessageRecipient()
+	if err != nil {
+		go messagingProtocol.DeliverMessageMultiTargetHandler(err, client, callID, nil, nil)
+		return
+	}
+
+	messageStructureInterface, err := parametersStream.ReadStructure(NewDataHolder())
+	if err != nil {
+		go messagingProtocol.DeliverMessageMultiTargetHandler(err, client, callID, nil, nil)
+		return
+	}
+
+	message, err := messagingProtocol.moderate(client, messageStructureInterface.(*DataHolder))
+	if err != nil {
+		go respondError(client, MessagingProtocolID, callID, ResultCoreAccessDenied)
+		return
+	}
+
+	go messagingProtocol.DeliverMessageMultiTargetHandler(nil, client, callID, recipients, message)
+}
+
 // NewSecureProtocol returns a new SecureProtocol
 func NewMessagingProtocol(server *nex.Server) *MessagingProtocol {
 	messagingProtocol := &MessagingProtocol{
@@ -0,0 +1,338 @@
+package nexproto
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+// Sources of the text checked by a Moderator
+const (
+	ContentSourceMessage  = "message"
+	ContentSourceComment  = "comment"
+	ContentSourcePresence = "presence"
+)
+
+var (
+	// ErrContentRejected is returned by a ContentFilter which refuses a text
+	ErrContentRejected = errors.New("content rejected")
+
+	// ErrPIDMuted is returned when a muted PID sends text
+	ErrPIDMuted = errors.New("PID is muted")
+
+	// ErrMessageUndecodable is returned for a message whose class is not known, so its text can not be checked
+	ErrMessageUndecodable = errors.New("message can not be checked")
+)
+
+// ContentFilter checks text sent by pid from source before it is stored or forwarded.
+// It returns the text to keep, which may be rewritten, or an error if the text is refused
+type ContentFilter func(pid uint32, source string, text string) (string, error)
+
+// ModerationAuditEntry records a text refused by a Moderator
+type ModerationAuditEntry struct {
+	Time   time.Time
+	PID    uint32
+	Source string
+	Text   string
+	Reason string
+}
+
+// ModerationAuditLog keeps the texts refused by a Moderator. Implementations must be safe for concurrent use
+type ModerationAuditLog interface {
+	Record(entry *ModerationAuditEntry)
+}
+
+// MemoryModerationAuditLog keeps the most recent refused texts in memory
+type MemoryModerationAuditLog struct {
+	mutex    sync.Mutex
+	entries  []*ModerationAuditEntry
+	capacity int
+}
+
+// Record adds entry to the log, dropping the oldest entry once the log is full
+func (auditLog *MemoryModerationAuditLog) Record(entry *ModerationAuditEntry) {
+	auditLog.mutex.Lock()
+	defer auditLog.mutex.Unlock()
+
+	auditLog.entries = append(auditLog.entries, entry)
+
+	if auditLog.capacity != 0 && len(auditLog.entries) > auditLog.capacity {
+		auditLog.entries = auditLog.entries[len(auditLog.entries)-auditLog.capacity:]
+	}
+}
+
+// Entries returns the logged entries, oldest first
+func (auditLog *MemoryModerationAuditLog) Entries() []*ModerationAuditEntry {
+	auditLog.mutex.Lock()
+	defer auditLog.mutex.Unlock()
+
+	return append([]*ModerationAuditEntry(nil), auditLog.entries...)
+}
+
+// NewMemoryModerationAuditLog returns a new MemoryModerationAuditLog keeping up to capacity entries, 0 for no limit
+func NewMemoryModerationAuditLog(capacity int) *MemoryModerationAuditLog {
+	return &MemoryModerationAuditLog{
+		entries:  make([]*ModerationAuditEntry, 0),
+		capacity: capacity,
+	}
+}
+
+// WriterModerationAuditLog writes refused texts to an io.Writer, one JSON object per line
+type WriterModerationAuditLog struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+// Record writes entry to the log
+func (auditLog *WriterModerationAuditLog) Record(entry *ModerationAuditEntry) {
+	auditLog.mutex.Lock()
+	defer auditLog.mutex.Unlock()
+
+	_ = auditLog.encoder.Encode(entry)
+}
+
+// NewWriterModerationAuditLog returns a new WriterModerationAuditLog writing to writer
+func NewWriterModerationAuditLog(writer io.Writer) *WriterModerationAuditLog {
+	return &WriterModerationAuditLog{
+		encoder: json.NewEncoder(writer),
+	}
+}
+
+// Moderator runs user text through content filters and a mute list before it is stored or forwarded,
+// recording refused texts in an audit log
+type Moderator struct {
+	mutex                  sync.RWMutex
+	filters                []ContentFilter
+	muted                  map[uint32]time.Time
+	auditLog               ModerationAuditLog
+	clock                  func() time.Time
+	AllowUndecodedMessages bool // forward messages of unknown classes unchecked instead of refusing them
+}
+
+// AddFilter appends filter to the filters run by Check. Filters run in the order they were added,
+// each receiving the text returned by the previous one
+func (moderator *Moderator) AddFilter(filter ContentFilter) {
+	moderator.mutex.Lock()
+	defer moderator.mutex.Unlock()
+
+	moderator.filters = append(moderator.filters, filter)
+}
+
+// Mute refuses all text from pid for duration, or until Unmute is called if duration is 0
+func (moderator *Moderator) Mute(pid uint32, duration time.Duration) {
+	moderator.mutex.Lock()
+	defer moderator.mutex.Unlock()
+
+	var until time.Time
+	if duration != 0 {
+		until = moderator.clock().Add(duration)
+	}
+
+	moderator.muted[pid] = until
+}
+
+// Unmute lifts the mute of pid
+func (moderator *Moderator) Unmute(pid uint32) {
+	moderator.mutex.Lock()
+	defer moderator.mutex.Unlock()
+
+	delete(moderator.muted, pid)
+}
+
+// IsMuted returns whether pid is muted
+func (moderator *Moderator) IsMuted(pid uint32) bool {
+	moderator.mutex.RLock()
+	defer moderator.mutex.RUnlock()
+
+	until, ok := moderator.muted[pid]
+
+	return ok && (until.IsZero() || moderator.clock().Before(until))
+}
+
+// SetAuditLog replaces the log refused texts are recorded in
+func (moderator *Moderator) SetAuditLog(auditLog ModerationAuditLog) {
+	moderator.mutex.Lock()
+	defer moderator.mutex.Unlock()
+
+	moderator.auditLog = auditLog
+}
+
+// AuditLog returns the log refused texts are recorded in
+func (moderator *Moderator) AuditLog() ModerationAuditLog {
+	moderator.mutex.RLock()
+	defer moderator.mutex.RUnlock()
+
+	return moderator.auditLog
+}
+
+// Check runs text sent by pid from source through the mute list and every filter, returning the text to keep.
+// Refused texts are recorded in the audit log. Muted PIDs are refused even for empty texts,
+// which are otherwise always accepted
+func (moderator *Moderator) Check(pid uint32, source string, text string) (string, error) {
+	if moderator.IsMuted(pid) {
+		moderator.record(pid, source, text, ErrPIDMuted)
+		return "", ErrPIDMuted
+	}
+
+	if text == "" {
+		return text, nil
+	}
+
+	moderator.mutex.RLock()
+	filters := append([]ContentFilter(nil), moderator.filters...)
+	moderator.mutex.RUnlock()
+
+	checked := text
+
+	for _, filter := range filters {
+		var err error
+
+		checked, err = filter(pid, source, checked)
+		if err != nil {
+			moderator.record(pid, source, text, err)
+			return "", err
+		}
+	}
+
+	return checked, nil
+}
+
+// CheckMessage checks the subject and text body of a message sent by pid, rewriting them in place.
+// Every message of a muted PID is refused, whatever its class
+func (moderator *Moderator) CheckMessage(pid uint32, userMessage UserMessageInterface) error {
+	header := userMessage.Header()
+
+	if moderator.IsMuted(pid) {
+		moderator.record(pid, ContentSourceMessage, header.Subject, ErrPIDMuted)
+		return ErrPIDMuted
+	}
+
+	subject, err := moderator.Check(pid, ContentSourceMessage, header.Subject)
+	if err != nil {
+		return err
+	}
+
+	header.Subject = subject
+
+	if textMessage, ok := userMessage.(*TextMessage); ok {
+		textBody, err := moderator.Check(pid, ContentSourceMessage, textMessage.TextBody)
+		if err != nil {
+			return err
+		}
+
+		textMessage.TextBody = textBody
+	}
+
+	return nil
+}
+
+// record adds a refused text to the audit log
+func (moderator *Moderator) record(pid uint32, source string, text string, reason error) {
+	auditLog := moderator.AuditLog()
+	if auditLog == nil {
+		return
+	}
+
+	auditLog.Record(&ModerationAuditEntry{
+		Time:   moderator.clock(),
+		PID:    pid,
+		Source: source,
+		Text:   text,
+		Reason: reason.Error(),
+	})
+}
+
+// NewModerator returns a new Moderator with no filters, recording up to 1000 refused texts in memory
+func NewModerator() *Moderator {
+	return &Moderator{
+		filters:  make([]ContentFilter, 0),
+		muted:    make(map[uint32]time.Time),
+		auditLog: NewMemoryModerationAuditLog(1000),
+		clock:    time.Now,
+	}
+}
+
+// NewWordListFilter returns a ContentFilter matching the words of a list as whole words, ignoring case.
+// Matched words are replaced with asterisks when mask is set, otherwise the whole text is refused
+func NewWordListFilter(words []string, mask bool) ContentFilter {
+	blocked := make(map[string]bool, len(words))
+
+	for _, word := range words {
+		blocked[strings.ToLower(word)] = true
+	}
+
+	return func(pid uint32, source string, text string) (string, error) {
+		runes := []rune(text)
+		matched := false
+
+		for start := 0; start < len(runes); {
+			if !isWordRune(runes[start]) {
+				start++
+				continue
+			}
+
+			end := start
+			for end < len(runes) && isWordRune(runes[end]) {
+				end++
+			}
+
+			if blocked[strings.ToLower(string(runes[start:end]))] {
+				if !mask {
+					return "", ErrContentRejected
+				}
+
+				matched = true
+
+				for i := start; i < end; i++ {
+					runes[i] = '*'
+				}
+			}
+
+			start = end
+		}
+
+		if !matched {
+			return text, nil
+		}
+
+		return string(runes), nil
+	}
+}
+
+// isWordRune returns whether r is part of a word for NewWordListFilter
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\''
+}
+
+// moderateMessageDataHolder checks the message carried by a data holder, returning the holder to forward with any
+// rewritten text. Data holders which can not be decoded as messages are refused, unless the moderator allows
+// undecoded messages, in which case they are forwarded unchecked for PIDs which are not muted
+func moderateMessageDataHolder(moderator *Moderator, pid uint32, message *DataHolder, server *nex.Server) (*DataHolder, error) {
+	userMessage, err := DecodeUserMessage(message, server)
+	if err != nil {
+		if moderator.IsMuted(pid) {
+			moderator.record(pid, ContentSourceMessage, "", ErrPIDMuted)
+			return nil, ErrPIDMuted
+		}
+
+		if !moderator.AllowUndecodedMessages {
+			moderator.record(pid, ContentSourceMessage, "", ErrMessageUndecodable)
+			return nil, ErrMessageUndecodable
+		}
+
+		return message, nil
+	}
+
+	err = moderator.CheckMessage(pid, userMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewUserMessageDataHolder(userMessage, server), nil
+}
@@ -0,0 +1,214 @@
+package nexproto
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+// newTestModerator returns a Moderator reading the time from now
+func newTestModerator(now *time.Time) *Moderator {
+	moderator := NewModerator()
+	moderator.clock = func() time.Time {
+		return *now
+	}
+
+	return moderator
+}
+
+func TestWordListFilter(t *testing.T) {
+	masking := NewWordListFilter([]string{"Darn", "can't"}, true)
+	refusing := NewWordListFilter([]string{"heck"}, false)
+
+	tests := []struct {
+		name   string
+		filter ContentFilter
+		text   string
+		want   string
+		err    error
+	}{
+		{"masked word", masking, "darn it", "**** it", nil},
+		{"masked regardless of case", masking, "DARN, Darn", "****, ****", nil},
+		{"word inside another word", masking, "darning needle", "darning needle", nil},
+		{"word with an apostrophe", masking, "I can't", "I *****", nil},
+		{"non ASCII neighbours", masking, "ädarn darné darn!", "ädarn darné ****!", nil},
+		{"refused word", refusing, "what the HECK", "", ErrContentRejected},
+		{"clean text", refusing, "hello", "hello", nil},
+	}
+
+	for _, test := range tests {
+		got, err := test.filter(1, ContentSourceComment, test.text)
+		if got != test.want || err != test.err {
+			t.Errorf("%s: returned %q, %v, want %q, %v", test.name, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestModeratorCheck(t *testing.T) {
+	now := time.Now()
+	moderator := newTestModerator(&now)
+
+	var checked []string
+
+	moderator.AddFilter(NewWordListFilter([]string{"darn"}, true))
+	moderator.AddFilter(func(pid uint32, source string, text string) (string, error) {
+		checked = append(checked, text)
+		return text, nil
+	})
+	moderator.AddFilter(NewWordListFilter([]string{"heck"}, false))
+
+	if text, err := moderator.Check(1, ContentSourceComment, "darn it"); text != "**** it" || err != nil {
+		t.Fatalf("Check returned %q, %v", text, err)
+	}
+
+	if len(checked) != 1 || checked[0] != "**** it" {
+		t.Fatalf("filters after a rewrite received %q", checked)
+	}
+
+	if _, err := moderator.Check(1, ContentSourcePresence, "oh darn, heck"); err != ErrContentRejected {
+		t.Fatalf("refused text returned %v", err)
+	}
+
+	if text, err := moderator.Check(1, ContentSourceComment, ""); text != "" || err != nil {
+		t.Fatalf("empty text returned %q, %v", text, err)
+	}
+
+	entries := moderator.AuditLog().(*MemoryModerationAuditLog).Entries()
+	if len(entries) != 1 {
+		t.Fatalf("%d audit entries recorded", len(entries))
+	}
+
+	// The audit log keeps the text as sent, before any filter rewrote it
+	if entry := entries[0]; entry.PID != 1 || entry.Source != ContentSourcePresence || entry.Text != "oh darn, heck" ||
+		entry.Reason != ErrContentRejected.Error() || !entry.Time.Equal(now) {
+		t.Fatalf("audit entry is %+v", entry)
+	}
+}
+
+func TestModeratorMute(t *testing.T) {
+	now := time.Now()
+	moderator := newTestModerator(&now)
+
+	moderator.Mute(1, 0)
+	moderator.Mute(2, time.Hour)
+
+	for _, pid := range []uint32{1, 2} {
+		if _, err := moderator.Check(pid, ContentSourceComment, ""); err != ErrPIDMuted {
+			t.Fatalf("empty text of muted PID %d returned %v", pid, err)
+		}
+	}
+
+	now = now.Add(time.Hour)
+
+	if moderator.IsMuted(2) {
+		t.Fatal("timed mute did not expire")
+	}
+
+	if !moderator.IsMuted(1) {
+		t.Fatal("mute without a duration expired")
+	}
+
+	moderator.Unmute(1)
+
+	if _, err := moderator.Check(1, ContentSourceComment, "hello"); err != nil {
+		t.Fatalf("unmuted PID was refused with %v", err)
+	}
+
+	if entries := moderator.AuditLog().(*MemoryModerationAuditLog).Entries(); len(entries) != 2 || entries[1].Reason != ErrPIDMuted.Error() {
+		t.Fatalf("audit log holds %v", entries)
+	}
+}
+
+func TestModeratorCheckMessage(t *testing.T) {
+	now := time.Now()
+	moderator := newTestModerator(&now)
+	moderator.AddFilter(NewWordListFilter([]string{"darn"}, true))
+
+	message := NewTextMessage()
+	message.Subject = "darn"
+	message.TextBody = "darn it"
+
+	if err := moderator.CheckMessage(1, message); err != nil {
+		t.Fatal(err)
+	}
+
+	if message.Subject != "****" || message.TextBody != "**** it" {
+		t.Fatalf("message rewritten to %q, %q", message.Subject, message.TextBody)
+	}
+
+	moderator.Mute(2, 0)
+
+	if err := moderator.CheckMessage(2, NewBinaryMessage()); err != ErrPIDMuted {
+		t.Fatalf("binary message of a muted PID returned %v", err)
+	}
+}
+
+func TestModerateMessageDataHolder(t *testing.T) {
+	server := nex.NewServer()
+
+	now := time.Now()
+	moderator := newTestModerator(&now)
+	moderator.AddFilter(NewWordListFilter([]string{"darn"}, true))
+	moderator.Mute(2, 0)
+
+	message := newTestTextMessage("darn")
+
+	moderated, err := moderateMessageDataHolder(moderator, 1, NewUserMessageDataHolder(message, server), server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := DecodeUserMessage(moderated, server)
+	if err != nil || decoded.(*TextMessage).TextBody != "****" {
+		t.Fatalf("moderated message decoded as %+v, %v", decoded, err)
+	}
+
+	unknown := &DataHolder{ClassName: "Unknown", Data: []byte{1, 2}}
+
+	if _, err := moderateMessageDataHolder(moderator, 1, unknown, server); err != ErrMessageUndecodable {
+		t.Fatalf("undecodable message returned %v", err)
+	}
+
+	moderator.AllowUndecodedMessages = true
+
+	if forwarded, err := moderateMessageDataHolder(moderator, 1, unknown, server); forwarded != unknown || err != nil {
+		t.Fatalf("allowed undecodable message returned %v, %v", forwarded, err)
+	}
+
+	if _, err := moderateMessageDataHolder(moderator, 2, unknown, server); err != ErrPIDMuted {
+		t.Fatalf("undecodable message of a muted PID returned %v", err)
+	}
+}
+
+func TestModerationAuditLogs(t *testing.T) {
+	memory := NewMemoryModerationAuditLog(2)
+
+	for pid := uint32(1); pid <= 3; pid++ {
+		memory.Record(&ModerationAuditEntry{PID: pid})
+	}
+
+	if entries := memory.Entries(); len(entries) != 2 || entries[0].PID != 2 || entries[1].PID != 3 {
+		t.Fatalf("full memory log holds %v", entries)
+	}
+
+	var buffer bytes.Buffer
+
+	writer := NewWriterModerationAuditLog(&buffer)
+	writer.Record(&ModerationAuditEntry{PID: 1, Source: ContentSourceComment, Text: "heck", Reason: ErrContentRejected.Error()})
+	writer.Record(&ModerationAuditEntry{PID: 2})
+
+	decoder := json.NewDecoder(&buffer)
+
+	var entry ModerationAuditEntry
+
+	if err := decoder.Decode(&entry); err != nil || entry.PID != 1 || entry.Text != "heck" || entry.Reason != ErrContentRejected.Error() {
+		t.Fatalf("first written entry decoded as %+v, %v", entry, err)
+	}
+
+	if err := decoder.Decode(&entry); err != nil || entry.PID != 2 {
+		t.Fatalf("second written entry decoded as %+v, %v", entry, err)
+	}
+}
@@ -23,10 +23,14 @@ type NATTraversalProtocol struct {
 func (natTraversalProtocol *NATTraversalProtocol) Setup() {
 	nexServer := natTraversalProtocol.server
 
+	trackPacketRoutes(nexServer)
+
 	nexServer.On("Data", func(packet nex.PacketInterface) {
 		request := packet.RMCRequest()
 
 		if NATTraversalProtocolID == request.ProtocolID() {
+			rememberPacketRoute(packet)
+
 			switch request.MethodID() {
 			case RegisterGathering:
 				go natTraversalProtocol.handleRequestProbeInitiation(packet)
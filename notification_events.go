@@ -0,0 +1,299 @@
+package nexproto
+
+import (
+	"errors"
+	"log"
+	"sync/atomic"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+const (
+	// NotificationEventsProtocolID is the protocol ID for the NotificationEvents protocol
+	NotificationEventsProtocolID = 0xE
+
+	// NotificationEventsMethodProcessNotificationEvent is the method ID for method ProcessNotificationEvent
+	NotificationEventsMethodProcessNotificationEvent = 0x1
+)
+
+// NotificationEventType is the type of a NotificationEvent, made of a category multiplied by 1000 plus a subtype.
+// The catalogue below covers gatherings, invitations being pushed as RequestJoinGathering. It has no friend presence
+// types: their category is not known for RB3, so this package sends no presence pushes. A server can send them
+// with NewNotificationEventType once the category is established
+type NotificationEventType uint32
+
+const (
+	// NotificationCategoryParticipation is sent when a PID joins or leaves a gathering
+	NotificationCategoryParticipation = 3
+
+	// NotificationCategoryOwnershipChanged is sent when a gathering changes owner
+	NotificationCategoryOwnershipChanged = 4
+
+	// NotificationCategoryRequestJoinGathering is sent when a PID is asked to join a gathering
+	NotificationCategoryRequestJoinGathering = 101
+
+	// NotificationCategoryEndGathering is sent when a gathering session ends
+	NotificationCategoryEndGathering = 102
+
+	// NotificationCategoryGatheringUnregistered is sent when a gathering is removed
+	NotificationCategoryGatheringUnregistered = 109
+
+	// NotificationCategoryHostChanged is sent when a gathering changes host
+	NotificationCategoryHostChanged = 110
+
+	// NotificationCategorySwitchGathering is sent when participants are moved to another gathering
+	NotificationCategorySwitchGathering = 122
+)
+
+const (
+	// NotificationSubtypeParticipate is the Participation subtype for a PID joining a gathering
+	NotificationSubtypeParticipate = 1
+
+	// NotificationSubtypeCancelParticipation is the Participation subtype for a PID leaving a gathering
+	NotificationSubtypeCancelParticipation = 2
+
+	// NotificationSubtypeDisconnect is the Participation subtype for a PID dropped from a gathering when its connection was lost
+	NotificationSubtypeDisconnect = 7
+)
+
+// Notification event types sent by this package
+const (
+	NotificationEventParticipate           = NotificationEventType(NotificationCategoryParticipation*1000 + NotificationSubtypeParticipate)
+	NotificationEventCancelParticipation   = NotificationEventType(NotificationCategoryParticipation*1000 + NotificationSubtypeCancelParticipation)
+	NotificationEventParticipantDisconnect = NotificationEventType(NotificationCategoryParticipation*1000 + NotificationSubtypeDisconnect)
+	NotificationEventOwnershipChanged      = NotificationEventType(NotificationCategoryOwnershipChanged * 1000)
+	NotificationEventRequestJoinGathering  = NotificationEventType(NotificationCategoryRequestJoinGathering * 1000)
+	NotificationEventEndGathering          = NotificationEventType(NotificationCategoryEndGathering * 1000)
+	NotificationEventGatheringUnregistered = NotificationEventType(NotificationCategoryGatheringUnregistered * 1000)
+	NotificationEventHostChanged           = NotificationEventType(NotificationCategoryHostChanged * 1000)
+	NotificationEventSwitchGathering       = NotificationEventType(NotificationCategorySwitchGathering * 1000)
+)
+
+// NewNotificationEventType returns the NotificationEventType for a category and subtype
+func NewNotificationEventType(category uint32, subtype uint32) NotificationEventType {
+	return NotificationEventType(category*1000 + subtype)
+}
+
+// Category returns the category of the event type
+func (eventType NotificationEventType) Category() uint32 {
+	return uint32(eventType) / 1000
+}
+
+// Subtype returns the subtype of the event type
+func (eventType NotificationEventType) Subtype() uint32 {
+	return uint32(eventType) % 1000
+}
+
+// NotificationEvent is an event pushed from the server to a client
+type NotificationEvent struct {
+	PIDSource   uint32
+	Type        NotificationEventType
+	Param1      uint32
+	Param2      uint32
+	StringParam string
+
+	nex.Structure
+}
+
+// Bytes encodes the NotificationEvent and returns a byte array
+func (event *NotificationEvent) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt32LE(event.PIDSource)
+	stream.WriteUInt32LE(uint32(event.Type))
+	stream.WriteUInt32LE(event.Param1)
+	stream.WriteUInt32LE(event.Param2)
+	write4ByteString(stream, event.StringParam)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a NotificationEvent structure from a stream
+func (event *NotificationEvent) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 16 {
+		// length check for the following fixed-size data
+		// pidSource + type + param1 + param2
+		return errors.New("[NotificationEvent::ExtractFromStream] Data size too small")
+	}
+
+	pidSource := stream.ReadUInt32LE()
+	eventType := stream.ReadUInt32LE()
+	param1 := stream.ReadUInt32LE()
+	param2 := stream.ReadUInt32LE()
+	stringParam, err := stream.Read4ByteString()
+	if err != nil {
+		return err
+	}
+
+	event.PIDSource = pidSource
+	event.Type = NotificationEventType(eventType)
+	event.Param1 = param1
+	event.Param2 = param2
+	event.StringParam = stringParam
+
+	return nil
+}
+
+// NewNotificationEvent returns a new NotificationEvent
+func NewNotificationEvent() *NotificationEvent {
+	return &NotificationEvent{}
+}
+
+// NotificationEventsProtocol sends NotificationEvents to connected clients
+type NotificationEventsProtocol struct {
+	server           *nex.Server
+	lastCallID       uint32
+	gatheringManager *GatheringManager
+}
+
+// Send pushes event to client with a ProcessNotificationEvent request
+func (notificationEventsProtocol *NotificationEventsProtocol) Send(client *nex.Client, event *NotificationEvent) {
+	request := nex.NewRMCRequest()
+	request.SetProtocolID(NotificationEventsProtocolID)
+	request.SetCallID(atomic.AddUint32(&notificationEventsProtocol.lastCallID, 1))
+	request.SetMethodID(NotificationEventsMethodProcessNotificationEvent)
+	request.SetParameters(event.Bytes(nex.NewStreamOut(notificationEventsProtocol.server)))
+
+	sendRMCPayload(client, request.Bytes())
+}
+
+// SendToPIDs pushes event to every connected client among pids, returning the PIDs that were not connected
+func (notificationEventsProtocol *NotificationEventsProtocol) SendToPIDs(pids []uint32, event *NotificationEvent) []uint32 {
+	offline := make([]uint32, 0)
+
+	for _, pid := range pids {
+		client := notificationEventsProtocol.server.FindClientFromPID(pid)
+		if client == nil {
+			offline = append(offline, pid)
+			continue
+		}
+
+		notificationEventsProtocol.Send(client, event)
+	}
+
+	return offline
+}
+
+// SendToGathering pushes event to every participant of a gathering except the PIDs in exclude
+func (notificationEventsProtocol *NotificationEventsProtocol) SendToGathering(gatheringID uint32, event *NotificationEvent, exclude ...uint32) {
+	if notificationEventsProtocol.gatheringManager == nil {
+		log.Println("[Warning] NotificationEventsProtocol::SendToGathering called without a GatheringManager")
+		return
+	}
+
+	participants := notificationEventsProtocol.gatheringManager.Participants(gatheringID)
+
+	notificationEventsProtocol.SendToPIDs(excludePIDs(participants, exclude), event)
+}
+
+// UseGatheringManager lets SendToGathering resolve participants from manager and notifies participants
+// when a gathering gains or loses a participant, changes host or owner, or is terminated, and invitees when invited.
+// Handlers already set on manager keep being called
+func (notificationEventsProtocol *NotificationEventsProtocol) UseGatheringManager(manager *GatheringManager) {
+	notificationEventsProtocol.gatheringManager = manager
+
+	participantJoinedHandler := manager.ParticipantJoinedHandler
+	manager.OnParticipantJoined(func(gathering *Gathering, pid uint32, participants []uint32) {
+		notificationEventsProtocol.SendToPIDs(excludePIDs(participants, []uint32{pid}), &NotificationEvent{
+			PIDSource: pid,
+			Type:      NotificationEventParticipate,
+			Param1:    gathering.ID,
+			Param2:    pid,
+		})
+
+		if participantJoinedHandler != nil {
+			participantJoinedHandler(gathering, pid, participants)
+		}
+	})
+
+	participantLeftHandler := manager.ParticipantLeftHandler
+	manager.OnParticipantLeft(func(gathering *Gathering, pid uint32, disconnected bool, participants []uint32) {
+		eventType := NotificationEventCancelParticipation
+		if disconnected {
+			eventType = NotificationEventParticipantDisconnect
+		}
+
+		notificationEventsProtocol.SendToPIDs(participants, &NotificationEvent{
+			PIDSource: pid,
+			Type:      eventType,
+			Param1:    gathering.ID,
+			Param2:    pid,
+		})
+
+		if participantLeftHandler != nil {
+			participantLeftHandler(gathering, pid, disconnected, participants)
+		}
+	})
+
+	hostMigrationHandler := manager.HostMigrationHandler
+	manager.OnHostMigration(func(gathering *Gathering, oldHostPID uint32, participants []uint32) {
+		notificationEventsProtocol.SendToPIDs(participants, &NotificationEvent{
+			PIDSource: oldHostPID,
+			Type:      NotificationEventHostChanged,
+			Param1:    gathering.ID,
+			Param2:    gathering.HostPID,
+		})
+
+		if hostMigrationHandler != nil {
+			hostMigrationHandler(gathering, oldHostPID, participants)
+		}
+	})
+
+	ownershipChangedHandler := manager.OwnershipChangedHandler
+	manager.OnOwnershipChanged(func(gathering *Gathering, oldOwnerPID uint32, participants []uint32) {
+		notificationEventsProtocol.SendToPIDs(participants, &NotificationEvent{
+			PIDSource: oldOwnerPID,
+			Type:      NotificationEventOwnershipChanged,
+			Param1:    gathering.ID,
+			Param2:    gathering.OwnerPID,
+		})
+
+		if ownershipChangedHandler != nil {
+			ownershipChangedHandler(gathering, oldOwnerPID, participants)
+		}
+	})
+
+	invitationSentHandler := manager.InvitationSentHandler
+	manager.OnInvitationSent(func(invitation *Invitation, inviterPID uint32) {
+		notificationEventsProtocol.SendToPIDs([]uint32{invitation.GuestPID}, &NotificationEvent{
+			PIDSource:   inviterPID,
+			Type:        NotificationEventRequestJoinGathering,
+			Param1:      invitation.GatheringID,
+			Param2:      invitation.GuestPID,
+			StringParam: invitation.Message,
+		})
+
+		if invitationSentHandler != nil {
+			invitationSentHandler(invitation, inviterPID)
+		}
+	})
+
+	gatheringTerminatedHandler := manager.GatheringTerminatedHandler
+	manager.OnGatheringTerminated(func(gathering *Gathering, participants []uint32) {
+		notificationEventsProtocol.SendToPIDs(excludePIDs(participants, []uint32{gathering.OwnerPID}), &NotificationEvent{
+			PIDSource: gathering.OwnerPID,
+			Type:      NotificationEventGatheringUnregistered,
+			Param1:    gathering.ID,
+		})
+
+		if gatheringTerminatedHandler != nil {
+			gatheringTerminatedHandler(gathering, participants)
+		}
+	})
+}
+
+// excludePIDs returns the PIDs in pids which are not in exclude
+func excludePIDs(pids []uint32, exclude []uint32) []uint32 {
+	filtered := make([]uint32, 0, len(pids))
+
+	for _, pid := range pids {
+		if indexOfPID(exclude, pid) == -1 {
+			filtered = append(filtered, pid)
+		}
+	}
+
+	return filtered
+}
+
+// NewNotificationEventsProtocol returns a new NotificationEventsProtocol
+func NewNotificationEventsProtocol(server *nex.Server) *NotificationEventsProtocol {
+	return &NotificationEventsProtocol{server: server}
+}
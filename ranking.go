@@ -1,7 +1,9 @@
 package nexproto
 
 import (
+	"errors"
 	"log"
+	"math"
 
 	nex "github.com/jnackmclain/nex-go"
 )
@@ -56,23 +58,584 @@ const (
 	RankingMethodGetCachedTopXRankings = 0xF
 )
 
+// Values of the rankingMode parameter of GetRanking and the ranking list methods
+const (
+	// RankingModeGlobal pages through the whole ranking from the offset of the RankingOrderParam
+	RankingModeGlobal = 0
+
+	// RankingModeGlobalAroundSelf returns the part of the whole ranking centered on the caller
+	RankingModeGlobalAroundSelf = 1
+
+	// RankingModeFriends pages through the ranking of the caller and their friends
+	RankingModeFriends = 2
+
+	// RankingModeFriendsAroundSelf returns the part of the ranking of the caller and their friends centered on the caller
+	RankingModeFriendsAroundSelf = 3
+
+	// RankingModeSelf returns the score of the caller only
+	RankingModeSelf = 4
+)
+
+// Values of RankingOrderParam.OrderCalculation
+const (
+	// RankingOrderCalculationStandard gives tied scores the same order and skips the following orders (1224)
+	RankingOrderCalculationStandard = 0
+
+	// RankingOrderCalculationOrdinal gives every score its own order, tied scores being ordered by upload (1234)
+	RankingOrderCalculationOrdinal = 1
+
+	// RankingOrderCalculationDense gives tied scores the same order without skipping any (1223).
+	// It is a server extension, Quazal clients only send the two calculations above
+	RankingOrderCalculationDense = 2
+)
+
+// RankingGroupIndexNone disables the group filter of a RankingOrderParam
+const RankingGroupIndexNone = 0xFF
+
+// rankingGroupCount is the number of groups of a RankingScoreData a RankingOrderParam can filter on, 0 and 1
+const rankingGroupCount = 2
+
+// Values of RankingScoreData.OrderBy
+const (
+	// RankingOrderByDescending ranks higher scores first
+	RankingOrderByDescending = 0
+
+	// RankingOrderByAscending ranks lower scores first
+	RankingOrderByAscending = 1
+)
+
+// Values of RankingScoreData.UpdateMode
+const (
+	// RankingUpdateModeNormal keeps the best of the stored and uploaded scores
+	RankingUpdateModeNormal = 0
+
+	// RankingUpdateModeDeleteOld replaces the stored score with the uploaded one
+	RankingUpdateModeDeleteOld = 1
+)
+
+// Bits of RankingChangeAttributesParam.ModificationFlag
+const (
+	// RankingModificationFlagGroup0 changes the first group
+	RankingModificationFlagGroup0 = 0x1
+
+	// RankingModificationFlagGroup1 changes the second group
+	RankingModificationFlagGroup1 = 0x2
+
+	// RankingModificationFlagParam changes the param
+	RankingModificationFlagParam = 0x4
+)
+
+// Bits of the GetStats flags, selecting the values of RankingStats.StatsList in this order
+const (
+	// RankingStatsFlagCount selects the number of scores
+	RankingStatsFlagCount = 0x1
+
+	// RankingStatsFlagTotal selects the sum of the scores
+	RankingStatsFlagTotal = 0x2
+
+	// RankingStatsFlagMin selects the lowest score
+	RankingStatsFlagMin = 0x4
+
+	// RankingStatsFlagMax selects the highest score
+	RankingStatsFlagMax = 0x8
+
+	// RankingStatsFlagAverage selects the average score
+	RankingStatsFlagAverage = 0x10
+
+	// RankingStatsFlagAll selects every value
+	RankingStatsFlagAll = 0x1F
+)
+
+// RankingOrderParam selects how a ranking is ordered, filtered and paged
+type RankingOrderParam struct {
+	OrderCalculation uint8
+	GroupIndex       uint8
+	GroupNum         uint8
+	TimeScope        uint8
+	Offset           uint32
+	Length           uint8
+
+	nex.Structure
+}
+
+// Bytes encodes the RankingOrderParam and returns a byte array
+func (orderParam *RankingOrderParam) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt8(orderParam.OrderCalculation)
+	stream.WriteUInt8(orderParam.GroupIndex)
+	stream.WriteUInt8(orderParam.GroupNum)
+	stream.WriteUInt8(orderParam.TimeScope)
+	stream.WriteUInt32LE(orderParam.Offset)
+	stream.WriteUInt8(orderParam.Length)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a RankingOrderParam structure from a stream
+func (orderParam *RankingOrderParam) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 9 {
+		// length check for the following fixed-size data
+		// orderCalculation + groupIndex + groupNum + timeScope + offset + length
+		return errors.New("[RankingOrderParam::ExtractFromStream] Data size too small")
+	}
+
+	orderParam.OrderCalculation = stream.ReadUInt8()
+	orderParam.GroupIndex = stream.ReadUInt8()
+	orderParam.GroupNum = stream.ReadUInt8()
+	orderParam.TimeScope = stream.ReadUInt8()
+	orderParam.Offset = stream.ReadUInt32LE()
+	orderParam.Length = stream.ReadUInt8()
+
+	return nil
+}
+
+// MatchesGroups returns whether a score uploaded with groups passes the group filter
+func (orderParam *RankingOrderParam) MatchesGroups(groups []byte) bool {
+	if orderParam.GroupIndex == RankingGroupIndexNone {
+		return true
+	}
+
+	index := int(orderParam.GroupIndex)
+
+	return index < rankingGroupCount && index < len(groups) && groups[index] == orderParam.GroupNum
+}
+
+// NewRankingOrderParam returns a new RankingOrderParam with no group filter
+func NewRankingOrderParam() *RankingOrderParam {
+	return &RankingOrderParam{
+		GroupIndex: RankingGroupIndexNone,
+	}
+}
+
+// RankingScoreData is a score uploaded to a ranking category
+type RankingScoreData struct {
+	Category   uint32
+	Score      uint32
+	OrderBy    uint8
+	UpdateMode uint8
+	Groups     []byte
+	Param      uint64
+
+	nex.Structure
+}
+
+// Bytes encodes the RankingScoreData and returns a byte array
+func (scoreData *RankingScoreData) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt32LE(scoreData.Category)
+	stream.WriteUInt32LE(scoreData.Score)
+	stream.WriteUInt8(scoreData.OrderBy)
+	stream.WriteUInt8(scoreData.UpdateMode)
+	stream.WriteBuffer(scoreData.Groups)
+	stream.WriteUInt64LE(scoreData.Param)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a RankingScoreData structure from a stream
+func (scoreData *RankingScoreData) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 10 {
+		// length check for the following fixed-size data
+		// category + score + orderBy + updateMode
+		return errors.New("[RankingScoreData::ExtractFromStream] Data size too small")
+	}
+
+	category := stream.ReadUInt32LE()
+	score := stream.ReadUInt32LE()
+	orderBy := stream.ReadUInt8()
+	updateMode := stream.ReadUInt8()
+	groups, err := stream.ReadBuffer()
+	if err != nil {
+		return err
+	}
+
+	if len(stream.Bytes()[stream.ByteOffset():]) < 8 {
+		return errors.New("[RankingScoreData::ExtractFromStream] Data missing param")
+	}
+
+	param := stream.ReadUInt64LE()
+
+	scoreData.Category = category
+	scoreData.Score = score
+	scoreData.OrderBy = orderBy
+	scoreData.UpdateMode = updateMode
+	scoreData.Groups = groups
+	scoreData.Param = param
+
+	return nil
+}
+
+// Copy returns a deep copy of the RankingScoreData
+func (scoreData *RankingScoreData) Copy() *RankingScoreData {
+	copied := *scoreData
+	copied.Groups = append([]byte(nil), scoreData.Groups...)
+
+	return &copied
+}
+
+// NewRankingScoreData returns a new RankingScoreData
+func NewRankingScoreData() *RankingScoreData {
+	return &RankingScoreData{
+		Groups: make([]byte, 0),
+	}
+}
+
+// RankingChangeAttributesParam holds the groups and param set by ChangeAttributes and ChangeAllAttributes.
+// ModificationFlag selects which of them are changed
+type RankingChangeAttributesParam struct {
+	ModificationFlag uint8
+	Groups           []byte
+	Param            uint64
+
+	nex.Structure
+}
+
+// Bytes encodes the RankingChangeAttributesParam and returns a byte array
+func (changeParam *RankingChangeAttributesParam) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt8(changeParam.ModificationFlag)
+	stream.WriteBuffer(changeParam.Groups)
+	stream.WriteUInt64LE(changeParam.Param)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a RankingChangeAttributesParam structure from a stream
+func (changeParam *RankingChangeAttributesParam) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 1 {
+		return errors.New("[RankingChangeAttributesParam::ExtractFromStream] Data missing modification flag")
+	}
+
+	modificationFlag := stream.ReadUInt8()
+	groups, err := stream.ReadBuffer()
+	if err != nil {
+		return err
+	}
+
+	if len(stream.Bytes()[stream.ByteOffset():]) < 8 {
+		return errors.New("[RankingChangeAttributesParam::ExtractFromStream] Data missing param")
+	}
+
+	changeParam.ModificationFlag = modificationFlag
+	changeParam.Groups = groups
+	changeParam.Param = stream.ReadUInt64LE()
+
+	return nil
+}
+
+// NewRankingChangeAttributesParam returns a new RankingChangeAttributesParam
+func NewRankingChangeAttributesParam() *RankingChangeAttributesParam {
+	return &RankingChangeAttributesParam{
+		Groups: make([]byte, 0),
+	}
+}
+
+// RankingRankData is a score as it appears in a ranking, with its order and the common data of its owner
+type RankingRankData struct {
+	PrincipalID uint32
+	UniqueID    uint64
+	Order       uint32
+	Category    uint32
+	Score       uint32
+	Groups      []byte
+	Param       uint64
+	CommonData  []byte
+
+	nex.Structure
+}
+
+// Bytes encodes the RankingRankData and returns a byte array
+func (rankData *RankingRankData) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt32LE(rankData.PrincipalID)
+	stream.WriteUInt64LE(rankData.UniqueID)
+	stream.WriteUInt32LE(rankData.Order)
+	stream.WriteUInt32LE(rankData.Category)
+	stream.WriteUInt32LE(rankData.Score)
+	stream.WriteBuffer(rankData.Groups)
+	stream.WriteUInt64LE(rankData.Param)
+	stream.WriteBuffer(rankData.CommonData)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a RankingRankData structure from a stream
+func (rankData *RankingRankData) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 24 {
+		// length check for the following fixed-size data
+		// principalID + uniqueID + order + category + score
+		return errors.New("[RankingRankData::ExtractFromStream] Data size too small")
+	}
+
+	principalID := stream.ReadUInt32LE()
+	uniqueID := stream.ReadUInt64LE()
+	order := stream.ReadUInt32LE()
+	category := stream.ReadUInt32LE()
+	score := stream.ReadUInt32LE()
+	groups, err := stream.ReadBuffer()
+	if err != nil {
+		return err
+	}
+
+	if len(stream.Bytes()[stream.ByteOffset():]) < 8 {
+		return errors.New("[RankingRankData::ExtractFromStream] Data missing param")
+	}
+
+	param := stream.ReadUInt64LE()
+	commonData, err := stream.ReadBuffer()
+	if err != nil {
+		return err
+	}
+
+	rankData.PrincipalID = principalID
+	rankData.UniqueID = uniqueID
+	rankData.Order = order
+	rankData.Category = category
+	rankData.Score = score
+	rankData.Groups = groups
+	rankData.Param = param
+	rankData.CommonData = commonData
+
+	return nil
+}
+
+// Copy returns a deep copy of the RankingRankData
+func (rankData *RankingRankData) Copy() *RankingRankData {
+	copied := *rankData
+	copied.Groups = append([]byte(nil), rankData.Groups...)
+	copied.CommonData = append([]byte(nil), rankData.CommonData...)
+
+	return &copied
+}
+
+// NewRankingRankData returns a new RankingRankData
+func NewRankingRankData() *RankingRankData {
+	return &RankingRankData{
+		Groups:     make([]byte, 0),
+		CommonData: make([]byte, 0),
+	}
+}
+
+// RankingResult is a page of a ranking. TotalCount is the number of scores in the whole ranking,
+// and SinceTime the start of the period the ranking covers
+type RankingResult struct {
+	RankData   []*RankingRankData
+	TotalCount uint32
+	SinceTime  *nex.DateTime
+
+	nex.Structure
+}
+
+// Bytes encodes the RankingResult and returns a byte array
+func (result *RankingResult) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt32LE(uint32(len(result.RankData)))
+
+	for _, rankData := range result.RankData {
+		stream.WriteStructure(rankData)
+	}
+
+	stream.WriteUInt32LE(result.TotalCount)
+	stream.WriteUInt64LE(result.SinceTime.Value())
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a RankingResult structure from a stream
+func (result *RankingResult) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 4 {
+		return errors.New("[RankingResult::ExtractFromStream] Data missing rank data list length")
+	}
+
+	length := stream.ReadUInt32LE()
+	rankDataList := make([]*RankingRankData, 0)
+
+	for i := 0; i < int(length); i++ {
+		rankData := NewRankingRankData()
+
+		err := rankData.ExtractFromStream(stream)
+		if err != nil {
+			return err
+		}
+
+		rankDataList = append(rankDataList, rankData)
+	}
+
+	if len(stream.Bytes()[stream.ByteOffset():]) < 12 {
+		// length check for the following fixed-size data
+		// totalCount + sinceTime
+		return errors.New("[RankingResult::ExtractFromStream] Data size too small")
+	}
+
+	result.RankData = rankDataList
+	result.TotalCount = stream.ReadUInt32LE()
+	result.SinceTime = nex.NewDateTime(stream.ReadUInt64LE())
+
+	return nil
+}
+
+// NewRankingResult returns a new RankingResult
+func NewRankingResult() *RankingResult {
+	return &RankingResult{
+		RankData:  make([]*RankingRankData, 0),
+		SinceTime: nex.NewDateTime(0),
+	}
+}
+
+// RankingCachedResult is a RankingResult snapshot returned by GetCachedTopXRanking(s),
+// valid from CreatedTime until ExpiredTime and holding at most MaxLength scores
+type RankingCachedResult struct {
+	RankingResult
+	CreatedTime *nex.DateTime
+	ExpiredTime *nex.DateTime
+	MaxLength   uint8
+}
+
+// Bytes encodes the RankingCachedResult and returns a byte array
+func (cachedResult *RankingCachedResult) Bytes(stream *nex.StreamOut) []byte {
+	cachedResult.RankingResult.Bytes(stream)
+	stream.WriteUInt64LE(cachedResult.CreatedTime.Value())
+	stream.WriteUInt64LE(cachedResult.ExpiredTime.Value())
+	stream.WriteUInt8(cachedResult.MaxLength)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a RankingCachedResult structure from a stream
+func (cachedResult *RankingCachedResult) ExtractFromStream(stream *nex.StreamIn) error {
+	err := cachedResult.RankingResult.ExtractFromStream(stream)
+	if err != nil {
+		return err
+	}
+
+	if len(stream.Bytes()[stream.ByteOffset():]) < 17 {
+		// length check for the following fixed-size data
+		// createdTime + expiredTime + maxLength
+		return errors.New("[RankingCachedResult::ExtractFromStream] Data size too small")
+	}
+
+	cachedResult.CreatedTime = nex.NewDateTime(stream.ReadUInt64LE())
+	cachedResult.ExpiredTime = nex.NewDateTime(stream.ReadUInt64LE())
+	cachedResult.MaxLength = stream.ReadUInt8()
+
+	return nil
+}
+
+// NewRankingCachedResult returns a new RankingCachedResult
+func NewRankingCachedResult() *RankingCachedResult {
+	return &RankingCachedResult{
+		RankingResult: *NewRankingResult(),
+		CreatedTime:   nex.NewDateTime(0),
+		ExpiredTime:   nex.NewDateTime(0),
+	}
+}
+
+// RankingStats holds the values selected by the GetStats flags, in the order of the RankingStatsFlag bits
+type RankingStats struct {
+	StatsList []float64
+
+	nex.Structure
+}
+
+// Bytes encodes the RankingStats and returns a byte array
+func (stats *RankingStats) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt32LE(uint32(len(stats.StatsList)))
+
+	for _, value := range stats.StatsList {
+		stream.WriteUInt64LE(math.Float64bits(value))
+	}
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a RankingStats structure from a stream
+func (stats *RankingStats) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 4 {
+		return errors.New("[RankingStats::ExtractFromStream] Data missing stats list length")
+	}
+
+	length := stream.ReadUInt32LE()
+
+	if len(stream.Bytes()[stream.ByteOffset():]) < int(length)*8 {
+		return errors.New("[RankingStats::ExtractFromStream] Data size too small")
+	}
+
+	statsList := make([]float64, 0, length)
+
+	for i := 0; i < int(length); i++ {
+		statsList = append(statsList, math.Float64frombits(stream.ReadUInt64LE()))
+	}
+
+	stats.StatsList = statsList
+
+	return nil
+}
+
+// NewRankingStats returns a new RankingStats
+func NewRankingStats() *RankingStats {
+	return &RankingStats{
+		StatsList: make([]float64, 0),
+	}
+}
+
 // RankingProtocol handles the Ranking nex protocol
 type RankingProtocol struct {
-	server                  *nex.Server
-	UploadCommonDataHandler func(err error, client *nex.Client, callID uint32, commonData []byte, uniqueId uint64)
+	server                          *nex.Server
+	UploadScoreHandler              func(err error, client *nex.Client, callID uint32, scoreData *RankingScoreData, uniqueID uint64)
+	DeleteScoreHandler              func(err error, client *nex.Client, callID uint32, category uint32, uniqueID uint64)
+	DeleteAllScoresHandler          func(err error, client *nex.Client, callID uint32, uniqueID uint64)
+	UploadCommonDataHandler         func(err error, client *nex.Client, callID uint32, commonData []byte, uniqueId uint64)
+	DeleteCommonDataHandler         func(err error, client *nex.Client, callID uint32, uniqueID uint64)
+	GetCommonDataHandler            func(err error, client *nex.Client, callID uint32, uniqueID uint64)
+	ChangeAttributesHandler         func(err error, client *nex.Client, callID uint32, category uint32, changeParam *RankingChangeAttributesParam, uniqueID uint64)
+	ChangeAllAttributesHandler      func(err error, client *nex.Client, callID uint32, changeParam *RankingChangeAttributesParam, uniqueID uint64)
+	GetRankingHandler               func(err error, client *nex.Client, callID uint32, rankingMode uint8, category uint32, orderParam *RankingOrderParam, uniqueID uint64, principalID uint32)
+	GetApproxOrderHandler           func(err error, client *nex.Client, callID uint32, category uint32, orderParam *RankingOrderParam, score uint32, uniqueID uint64, principalID uint32)
+	GetStatsHandler                 func(err error, client *nex.Client, callID uint32, category uint32, orderParam *RankingOrderParam, flags uint32)
+	GetRankingByPIDListHandler      func(err error, client *nex.Client, callID uint32, principalIDs []uint32, rankingMode uint8, category uint32, orderParam *RankingOrderParam, uniqueID uint64)
+	GetRankingByUniqueIDListHandler func(err error, client *nex.Client, callID uint32, uniqueIDs []uint64, rankingMode uint8, category uint32, orderParam *RankingOrderParam, uniqueID uint64)
+	GetCachedTopXRankingHandler     func(err error, client *nex.Client, callID uint32, category uint32, orderParam *RankingOrderParam)
+	GetCachedTopXRankingsHandler    func(err error, client *nex.Client, callID uint32, categories []uint32, orderParams []*RankingOrderParam)
 }
 
 // Setup initializes the protocol
 func (rankingProtocol *RankingProtocol) Setup() {
 	nexServer := rankingProtocol.server
 
+	trackPacketRoutes(nexServer)
+
 	nexServer.On("Data", func(packet nex.PacketInterface) {
 		request := packet.RMCRequest()
 
 		if RankingProtocolID == request.ProtocolID() {
+			rememberPacketRoute(packet)
+
 			switch request.MethodID() {
+			case RankingMethodUploadScore:
+				go rankingProtocol.handleUploadScore(packet)
+			case RankingMethodDeleteScore:
+				go rankingProtocol.handleDeleteScore(packet)
+			case RankingMethodDeleteAllScores:
+				go rankingProtocol.handleDeleteAllScores(packet)
 			case RankingMethodUploadCommonData:
 				go rankingProtocol.handleUploadCommonData(packet)
+			case RankingMethodDeleteCommonData:
+				go rankingProtocol.handleDeleteCommonData(packet)
+			case RankingMethodGetCommonData:
+				go rankingProtocol.handleGetCommonData(packet)
+			case RankingMethodChangeAttributes:
+				go rankingProtocol.handleChangeAttributes(packet)
+			case RankingMethodChangeAllAttributes:
+				go rankingProtocol.handleChangeAllAttributes(packet)
+			case RankingMethodGetRanking:
+				go rankingProtocol.handleGetRanking(packet)
+			case RankingMethodGetApproxOrder:
+				go rankingProtocol.handleGetApproxOrder(packet)
+			case RankingMethodGetStats:
+				go rankingProtocol.handleGetStats(packet)
+			case RankingMethodGetRankingByPIDList:
+				go rankingProtocol.handleGetRankingByPIDList(packet)
+			case RankingMethodGetRankingByUniqueIDList:
+				go rankingProtocol.handleGetRankingByUniqueIDList(packet)
+			case RankingMethodGetCachedTopXRanking:
+				go rankingProtocol.handleGetCachedTopXRanking(packet)
+			case RankingMethodGetCachedTopXRankings:
+				go rankingProtocol.handleGetCachedTopXRankings(packet)
 			default:
 				log.Printf("Unsupported Ranking method ID: %#v\n", request.MethodID())
 			}
@@ -80,35 +643,791 @@ func (rankingProtocol *RankingProtocol) Setup() {
 	})
 }
 
+// UploadScore sets the UploadScore handler function
+func (rankingProtocol *RankingProtocol) UploadScore(handler func(err error, client *nex.Client, callID uint32, scoreData *RankingScoreData, uniqueID uint64)) {
+	rankingProtocol.UploadScoreHandler = handler
+}
+
+// DeleteScore sets the DeleteScore handler function
+func (rankingProtocol *RankingProtocol) DeleteScore(handler func(err error, client *nex.Client, callID uint32, category uint32, uniqueID uint64)) {
+	rankingProtocol.DeleteScoreHandler = handler
+}
+
+// DeleteAllScores sets the DeleteAllScores handler function
+func (rankingProtocol *RankingProtocol) DeleteAllScores(handler func(err error, client *nex.Client, callID uint32, uniqueID uint64)) {
+	rankingProtocol.DeleteAllScoresHandler = handler
+}
+
 // UploadCommonData sets the UploadCommonData handler function
 func (rankingProtocol *RankingProtocol) UploadCommonData(handler func(err error, client *nex.Client, callID uint32, commonData []byte, uniqueID uint64)) {
 	rankingProtocol.UploadCommonDataHandler = handler
 }
 
-func (rankingProtocol *RankingProtocol) handleUploadCommonData(packet nex.PacketInterface) {
-	if rankingProtocol.UploadCommonDataHandler == nil {
-		log.Println("[Warning] RankingProtocol::UploadCommonData not implemented")
-		go respondNotImplemented(packet, RankingProtocolID)
-		return
-	}
+// DeleteCommonData sets the DeleteCommonData handler function
+func (rankingProtocol *RankingProtocol) DeleteCommonData(handler func(err error, client *nex.Client, callID uint32, uniqueID uint64)) {
+	rankingProtocol.DeleteCommonDataHandler = handler
+}
 
-	client := packet.Sender()
-	request := packet.RMCRequest()
+// GetCommonData sets the GetCommonData handler function
+func (rankingProtocol *RankingProtocol) GetCommonData(handler func(err error, client *nex.Client, callID uint32, uniqueID uint64)) {
+	rankingProtocol.GetCommonDataHandler = handler
+}
 
-	callID := request.CallID()
-	parameters := request.Parameters()
+// ChangeAttributes sets the ChangeAttributes handler function
+func (rankingProtocol *RankingProtocol) ChangeAttributes(handler func(err error, client *nex.Client, callID uint32, category uint32, changeParam *RankingChangeAttributesParam, uniqueID uint64)) {
+	rankingProtocol.ChangeAttributesHandler = handler
+}
 
-	parametersStream := nex.NewStreamIn(parameters, rankingProtocol.server)
+// ChangeAllAttributes sets the ChangeAllAttributes handler function
+func (rankingProtocol *RankingProtocol) ChangeAllAttributes(handler func(err error, client *nex.Client, callID uint32, changeParam *RankingChangeAttributesParam, uniqueID uint64)) {
+	rankingProtocol.ChangeAllAttributesHandler = handler
+}
 
-	commonData, err := parametersStream.ReadBuffer()
-	if err != nil {
-		go rankingProtocol.UploadCommonDataHandler(err, client, callID, nil, 0)
-		return
-	}
+// GetRanking sets the GetRanking handler function
+func (rankingProtocol *RankingProtocol) GetRanking(handler func(err error, client *nex.Client, callID uint32, rankingMode uint8, category uint32, orderParam *RankingOrderParam, uniqueID uint64, principalID uint32)) {
+	rankingProtocol.GetRankingHandler = handler
+}
 
-	uniqueID := parametersStream.ReadUInt64LE()
+// GetApproxOrder sets the GetApproxOrder handler function
+func (rankingProtocol *RankingProtocol) GetApproxOrder(handler func(err error, client *nex.Client, callID uint32, category uint32, orderParam *RankingOrderParam, score uint32, uniqueID uint64, principalID uint32)) {
+	rankingProtocol.GetApproxOrderHandler = handler
+}
 
-	go rankingProtocol.UploadCommonDataHandler(nil, client, callID, commonData, uniqueID)
+// GetStats sets the GetStats handler function
+func (rankingProtocol *RankingProtocol) GetStats(handler func(err error, client *nex.Client, callID uint32, category uint32, orderParam *RankingOrderParam, flags uint32)) {
+	rankingProtocol.GetStatsHandler = handler
+}
+
+// GetRankingByPIDList sets the GetRankingByPIDList handler function
+func (rankingProtocol *RankingProtocol) GetRankingByPIDList(handler func(err error, client *nex.Client, callID uint32, principalIDs []uint32, rankingMode uint8, category uint32, orderParam *RankingOrderParam, uniqueID uint64)) {
+	rankingProtocol.GetRankingByPIDListHandler = handler
+}
+
+// GetRankingByUniqueIDList sets the GetRankingByUniqueIDList handler function
+func (rankingProtocol *RankingProtocol) GetRankingByUniqueIDList(handler func(err error, client *nex.Client, callID uint32, uniqueIDs []uint64, rankingMode uint8, category uint32, orderParam *RankingOrderParam, uniqueID uint64)) {
+	rankingProtocol.GetRankingByUniqueIDListHandler = handler
+}
+
+// GetCachedTopXRanking sets the GetCachedTopXRanking handler function
+func (rankingProtocol *RankingProtocol) GetCachedTopXRanking(handler func(err error, client *nex.Client, callID uint32, category uint32, orderParam *RankingOrderParam)) {
+	rankingProtocol.GetCachedTopXRankingHandler = handler
+}
+
+// GetCachedTopXRankings sets the GetCachedTopXRankings handler function
+func (rankingProtocol *RankingProtocol) GetCachedTopXRankings(handler func(err error, client *nex.Client, callID uint32, categories []uint32, orderParams []*RankingOrderParam)) {
+	rankingProtocol.GetCachedTopXRankingsHandler = handler
+}
+
+// UseLeaderboard installs the handlers of leaderboard for the score, common data and ranking methods
+func (rankingProtocol *RankingProtocol) UseLeaderboard(leaderboard *Leaderboard) {
+	rankingProtocol.UploadScore(leaderboard.HandleUploadScore)
+	rankingProtocol.DeleteScore(leaderboard.HandleDeleteScore)
+	rankingProtocol.DeleteAllScores(leaderboard.HandleDeleteAllScores)
+	rankingProtocol.UploadCommonData(leaderboard.HandleUploadCommonData)
+	rankingProtocol.DeleteCommonData(leaderboard.HandleDeleteCommonData)
+	rankingProtocol.GetCommonData(leaderboard.HandleGetCommonData)
+	rankingProtocol.ChangeAttributes(leaderboard.HandleChangeAttributes)
+	rankingProtocol.ChangeAllAttributes(leaderboard.HandleChangeAllAttributes)
+	rankingProtocol.GetRanking(leaderboard.HandleGetRanking)
+	rankingProtocol.GetApproxOrder(leaderboard.HandleGetApproxOrder)
+	rankingProtocol.GetStats(leaderboard.HandleGetStats)
+	rankingProtocol.GetRankingByPIDList(leaderboard.HandleGetRankingByPIDList)
+	rankingProtocol.GetRankingByUniqueIDList(leaderboard.HandleGetRankingByUniqueIDList)
+}
+
+// UseScoreValidator sends uploaded scores through validator before they are ranked. It must be called after UseLeaderboard
+func (rankingProtocol *RankingProtocol) UseScoreValidator(validator *ScoreValidator) {
+	rankingProtocol.UploadScore(validator.HandleUploadScore)
+}
+
+// UseRankingCache installs the handlers of cache for the cached top ranking methods
+func (rankingProtocol *RankingProtocol) UseRankingCache(cache *RankingCache) {
+	rankingProtocol.GetCachedTopXRanking(cache.HandleGetCachedTopXRanking)
+	rankingProtocol.GetCachedTopXRankings(cache.HandleGetCachedTopXRankings)
+}
+
+// RespondUploadScore answers a UploadScore call
+func (rankingProtocol *RankingProtocol) RespondUploadScore(client *nex.Client, callID uint32) {
+	respondSuccess(client, RankingProtocolID, RankingMethodUploadScore, callID, make([]byte, 0))
+}
+
+// RespondDeleteScore answers a DeleteScore call
+func (rankingProtocol *RankingProtocol) RespondDeleteScore(client *nex.Client, callID uint32) {
+	respondSuccess(client, RankingProtocolID, RankingMethodDeleteScore, callID, make([]byte, 0))
+}
+
+// RespondDeleteAllScores answers a DeleteAllScores call
+func (rankingProtocol *RankingProtocol) RespondDeleteAllScores(client *nex.Client, callID uint32) {
+	respondSuccess(client, RankingProtocolID, RankingMethodDeleteAllScores, callID, make([]byte, 0))
+}
+
+// RespondUploadCommonData answers a UploadCommonData call
+func (rankingProtocol *RankingProtocol) RespondUploadCommonData(client *nex.Client, callID uint32) {
+	respondSuccess(client, RankingProtocolID, RankingMethodUploadCommonData, callID, make([]byte, 0))
+}
+
+// RespondDeleteCommonData answers a DeleteCommonData call
+func (rankingProtocol *RankingProtocol) RespondDeleteCommonData(client *nex.Client, callID uint32) {
+	respondSuccess(client, RankingProtocolID, RankingMethodDeleteCommonData, callID, make([]byte, 0))
+}
+
+// RespondChangeAttributes answers a ChangeAttributes call
+func (rankingProtocol *RankingProtocol) RespondChangeAttributes(client *nex.Client, callID uint32) {
+	respondSuccess(client, RankingProtocolID, RankingMethodChangeAttributes, callID, make([]byte, 0))
+}
+
+// RespondChangeAllAttributes answers a ChangeAllAttributes call
+func (rankingProtocol *RankingProtocol) RespondChangeAllAttributes(client *nex.Client, callID uint32) {
+	respondSuccess(client, RankingProtocolID, RankingMethodChangeAllAttributes, callID, make([]byte, 0))
+}
+
+// RespondGetCommonData answers a GetCommonData call with the common data of the caller
+func (rankingProtocol *RankingProtocol) RespondGetCommonData(client *nex.Client, callID uint32, commonData []byte) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteBuffer(commonData)
+
+	respondSuccess(client, RankingProtocolID, RankingMethodGetCommonData, callID, responseStream.Bytes())
+}
+
+// RespondGetRanking answers a GetRanking call with a page of a ranking
+func (rankingProtocol *RankingProtocol) RespondGetRanking(client *nex.Client, callID uint32, result *RankingResult) {
+	respondRankingStructure(client, callID, RankingMethodGetRanking, result)
+}
+
+// RespondGetApproxOrder answers a GetApproxOrder call with the order a score would have
+func (rankingProtocol *RankingProtocol) RespondGetApproxOrder(client *nex.Client, callID uint32, order uint32) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt32LE(order)
+
+	respondSuccess(client, RankingProtocolID, RankingMethodGetApproxOrder, callID, responseStream.Bytes())
+}
+
+// RespondGetStats answers a GetStats call with the statistics of a category
+func (rankingProtocol *RankingProtocol) RespondGetStats(client *nex.Client, callID uint32, stats *RankingStats) {
+	respondRankingStructure(client, callID, RankingMethodGetStats, stats)
+}
+
+// RespondGetRankingByPIDList answers a GetRankingByPIDList call with the scores of the requested PIDs
+func (rankingProtocol *RankingProtocol) RespondGetRankingByPIDList(client *nex.Client, callID uint32, result *RankingResult) {
+	respondRankingStructure(client, callID, RankingMethodGetRankingByPIDList, result)
+}
+
+// RespondGetRankingByUniqueIDList answers a GetRankingByUniqueIDList call with the scores of the requested unique IDs
+func (rankingProtocol *RankingProtocol) RespondGetRankingByUniqueIDList(client *nex.Client, callID uint32, result *RankingResult) {
+	respondRankingStructure(client, callID, RankingMethodGetRankingByUniqueIDList, result)
+}
+
+// RespondGetCachedTopXRanking answers a GetCachedTopXRanking call with a ranking snapshot
+func (rankingProtocol *RankingProtocol) RespondGetCachedTopXRanking(client *nex.Client, callID uint32, cachedResult *RankingCachedResult) {
+	respondRankingStructure(client, callID, RankingMethodGetCachedTopXRanking, cachedResult)
+}
+
+// RespondGetCachedTopXRankings answers a GetCachedTopXRankings call with one ranking snapshot per requested category
+func (rankingProtocol *RankingProtocol) RespondGetCachedTopXRankings(client *nex.Client, callID uint32, cachedResults []*RankingCachedResult) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt32LE(uint32(len(cachedResults)))
+
+	for _, cachedResult := range cachedResults {
+		responseStream.WriteStructure(cachedResult)
+	}
+
+	respondSuccess(client, RankingProtocolID, RankingMethodGetCachedTopXRankings, callID, responseStream.Bytes())
+}
+
+// respondRankingStructure answers a ranking method returning a single structure
+func respondRankingStructure(client *nex.Client, callID uint32, methodID uint32, structure nex.StructureInterface) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteStructure(structure)
+
+	respondSuccess(client, RankingProtocolID, methodID, callID, responseStream.Bytes())
+}
+
+func (rankingProtocol *RankingProtocol) handleUploadScore(packet nex.PacketInterface) {
+	if rankingProtocol.UploadScoreHandler == nil {
+		log.Println("[Warning] RankingProtocol::UploadScore not implemented")
+		go respondNotImplemented(packet, RankingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, rankingProtocol.server)
+
+	scoreDataStructureInterface, err := parametersStream.ReadStructure(NewRankingScoreData())
+	if err != nil {
+		go rankingProtocol.UploadScoreHandler(err, client, callID, nil, 0)
+		return
+	}
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 8 {
+		err := errors.New("[RankingProtocol::UploadScore] Data missing unique ID")
+		go rankingProtocol.UploadScoreHandler(err, client, callID, nil, 0)
+		return
+	}
+
+	uniqueID := parametersStream.ReadUInt64LE()
+
+	scoreData := scoreDataStructureInterface.(*RankingScoreData)
+
+	go rankingProtocol.UploadScoreHandler(nil, client, callID, scoreData, uniqueID)
+}
+
+func (rankingProtocol *RankingProtocol) handleDeleteScore(packet nex.PacketInterface) {
+	if rankingProtocol.DeleteScoreHandler == nil {
+		log.Println("[Warning] RankingProtocol::DeleteScore not implemented")
+		go respondNotImplemented(packet, RankingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, rankingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[RankingProtocol::DeleteScore] Data missing category")
+		go rankingProtocol.DeleteScoreHandler(err, client, callID, 0, 0)
+		return
+	}
+
+	category := parametersStream.ReadUInt32LE()
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 8 {
+		err := errors.New("[RankingProtocol::DeleteScore] Data missing unique ID")
+		go rankingProtocol.DeleteScoreHandler(err, client, callID, 0, 0)
+		return
+	}
+
+	uniqueID := parametersStream.ReadUInt64LE()
+
+	go rankingProtocol.DeleteScoreHandler(nil, client, callID, category, uniqueID)
+}
+
+func (rankingProtocol *RankingProtocol) handleDeleteAllScores(packet nex.PacketInterface) {
+	if rankingProtocol.DeleteAllScoresHandler == nil {
+		log.Println("[Warning] RankingProtocol::DeleteAllScores not implemented")
+		go respondNotImplemented(packet, RankingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, rankingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 8 {
+		err := errors.New("[RankingProtocol::DeleteAllScores] Data missing unique ID")
+		go rankingProtocol.DeleteAllScoresHandler(err, client, callID, 0)
+		return
+	}
+
+	uniqueID := parametersStream.ReadUInt64LE()
+
+	go rankingProtocol.DeleteAllScoresHandler(nil, client, callID, uniqueID)
+}
+
+func (rankingProtocol *RankingProtocol) handleUploadCommonData(packet nex.PacketInterface) {
+	if rankingProtocol.UploadCommonDataHandler == nil {
+		log.Println("[Warning] RankingProtocol::UploadCommonData not implemented")
+		go respondNotImplemented(packet, RankingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := nex.NewStreamIn(parameters, rankingProtocol.server)
+
+	commonData, err := parametersStream.ReadBuffer()
+	if err != nil {
+		go rankingProtocol.UploadCommonDataHandler(err, client, callID, nil, 0)
+		return
+	}
+
+	uniqueID := parametersStream.ReadUInt64LE()
+
+	go rankingProtocol.UploadCommonDataHandler(nil, client, callID, commonData, uniqueID)
+}
+
+func (rankingProtocol *RankingProtocol) handleDeleteCommonData(packet nex.PacketInterface) {
+	if rankingProtocol.DeleteCommonDataHandler == nil {
+		log.Println("[Warning] RankingProtocol::DeleteCommonData not implemented")
+		go respondNotImplemented(packet, RankingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, rankingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 8 {
+		err := errors.New("[RankingProtocol::DeleteCommonData] Data missing unique ID")
+		go rankingProtocol.DeleteCommonDataHandler(err, client, callID, 0)
+		return
+	}
+
+	uniqueID := parametersStream.ReadUInt64LE()
+
+	go rankingProtocol.DeleteCommonDataHandler(nil, client, callID, uniqueID)
+}
+
+func (rankingProtocol *RankingProtocol) handleGetCommonData(packet nex.PacketInterface) {
+	if rankingProtocol.GetCommonDataHandler == nil {
+		log.Println("[Warning] RankingProtocol::GetCommonData not implemented")
+		go respondNotImplemented(packet, RankingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, rankingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 8 {
+		err := errors.New("[RankingProtocol::GetCommonData] Data missing unique ID")
+		go rankingProtocol.GetCommonDataHandler(err, client, callID, 0)
+		return
+	}
+
+	uniqueID := parametersStream.ReadUInt64LE()
+
+	go rankingProtocol.GetCommonDataHandler(nil, client, callID, uniqueID)
+}
+
+func (rankingProtocol *RankingProtocol) handleChangeAttributes(packet nex.PacketInterface) {
+	if rankingProtocol.ChangeAttributesHandler == nil {
+		log.Println("[Warning] RankingProtocol::ChangeAttributes not implemented")
+		go respondNotImplemented(packet, RankingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, rankingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[RankingProtocol::ChangeAttributes] Data missing category")
+		go rankingProtocol.ChangeAttributesHandler(err, client, callID, 0, nil, 0)
+		return
+	}
+
+	category := parametersStream.ReadUInt32LE()
+
+	changeParamStructureInterface, err := parametersStream.ReadStructure(NewRankingChangeAttributesParam())
+	if err != nil {
+		go rankingProtocol.ChangeAttributesHandler(err, client, callID, 0, nil, 0)
+		return
+	}
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 8 {
+		err := errors.New("[RankingProtocol::ChangeAttributes] Data missing unique ID")
+		go rankingProtocol.ChangeAttributesHandler(err, client, callID, 0, nil, 0)
+		return
+	}
+
+	uniqueID := parametersStream.ReadUInt64LE()
+
+	changeParam := changeParamStructureInterface.(*RankingChangeAttributesParam)
+
+	go rankingProtocol.ChangeAttributesHandler(nil, client, callID, category, changeParam, uniqueID)
+}
+
+func (rankingProtocol *RankingProtocol) handleChangeAllAttributes(packet nex.PacketInterface) {
+	if rankingProtocol.ChangeAllAttributesHandler == nil {
+		log.Println("[Warning] RankingProtocol::ChangeAllAttributes not implemented")
+		go respondNotImplemented(packet, RankingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, rankingProtocol.server)
+
+	changeParamStructureInterface, err := parametersStream.ReadStructure(NewRankingChangeAttributesParam())
+	if err != nil {
+		go rankingProtocol.ChangeAllAttributesHandler(err, client, callID, nil, 0)
+		return
+	}
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 8 {
+		err := errors.New("[RankingProtocol::ChangeAllAttributes] Data missing unique ID")
+		go rankingProtocol.ChangeAllAttributesHandler(err, client, callID, nil, 0)
+		return
+	}
+
+	uniqueID := parametersStream.ReadUInt64LE()
+
+	changeParam := changeParamStructureInterface.(*RankingChangeAttributesParam)
+
+	go rankingProtocol.ChangeAllAttributesHandler(nil, client, callID, changeParam, uniqueID)
+}
+
+func (rankingProtocol *RankingProtocol) handleGetRanking(packet nex.PacketInterface) {
+	if rankingProtocol.GetRankingHandler == nil {
+		log.Println("[Warning] RankingProtocol::GetRanking not implemented")
+		go respondNotImplemented(packet, RankingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, rankingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 1 {
+		err := errors.New("[RankingProtocol::GetRanking] Data missing ranking mode")
+		go rankingProtocol.GetRankingHandler(err, client, callID, 0, 0, nil, 0, 0)
+		return
+	}
+
+	rankingMode := parametersStream.ReadUInt8()
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[RankingProtocol::GetRanking] Data missing category")
+		go rankingProtocol.GetRankingHandler(err, client, callID, 0, 0, nil, 0, 0)
+		return
+	}
+
+	category := parametersStream.ReadUInt32LE()
+
+	orderParamStructureInterface, err := parametersStream.ReadStructure(NewRankingOrderParam())
+	if err != nil {
+		go rankingProtocol.GetRankingHandler(err, client, callID, 0, 0, nil, 0, 0)
+		return
+	}
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 8 {
+		err := errors.New("[RankingProtocol::GetRanking] Data missing unique ID")
+		go rankingProtocol.GetRankingHandler(err, client, callID, 0, 0, nil, 0, 0)
+		return
+	}
+
+	uniqueID := parametersStream.ReadUInt64LE()
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[RankingProtocol::GetRanking] Data missing principal ID")
+		go rankingProtocol.GetRankingHandler(err, client, callID, 0, 0, nil, 0, 0)
+		return
+	}
+
+	principalID := parametersStream.ReadUInt32LE()
+
+	orderParam := orderParamStructureInterface.(*RankingOrderParam)
+
+	go rankingProtocol.GetRankingHandler(nil, client, callID, rankingMode, category, orderParam, uniqueID, principalID)
+}
+
+func (rankingProtocol *RankingProtocol) handleGetApproxOrder(packet nex.PacketInterface) {
+	if rankingProtocol.GetApproxOrderHandler == nil {
+		log.Println("[Warning] RankingProtocol::GetApproxOrder not implemented")
+		go respondNotImplemented(packet, RankingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, rankingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[RankingProtocol::GetApproxOrder] Data missing category")
+		go rankingProtocol.GetApproxOrderHandler(err, client, callID, 0, nil, 0, 0, 0)
+		return
+	}
+
+	category := parametersStream.ReadUInt32LE()
+
+	orderParamStructureInterface, err := parametersStream.ReadStructure(NewRankingOrderParam())
+	if err != nil {
+		go rankingProtocol.GetApproxOrderHandler(err, client, callID, 0, nil, 0, 0, 0)
+		return
+	}
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[RankingProtocol::GetApproxOrder] Data missing score")
+		go rankingProtocol.GetApproxOrderHandler(err, client, callID, 0, nil, 0, 0, 0)
+		return
+	}
+
+	score := parametersStream.ReadUInt32LE()
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 8 {
+		err := errors.New("[RankingProtocol::GetApproxOrder] Data missing unique ID")
+		go rankingProtocol.GetApproxOrderHandler(err, client, callID, 0, nil, 0, 0, 0)
+		return
+	}
+
+	uniqueID := parametersStream.ReadUInt64LE()
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[RankingProtocol::GetApproxOrder] Data missing principal ID")
+		go rankingProtocol.GetApproxOrderHandler(err, client, callID, 0, nil, 0, 0, 0)
+		return
+	}
+
+	principalID := parametersStream.ReadUInt32LE()
+
+	orderParam := orderParamStructureInterface.(*RankingOrderParam)
+
+	go rankingProtocol.GetApproxOrderHandler(nil, client, callID, category, orderParam, score, uniqueID, principalID)
+}
+
+func (rankingProtocol *RankingProtocol) handleGetStats(packet nex.PacketInterface) {
+	if rankingProtocol.GetStatsHandler == nil {
+		log.Println("[Warning] RankingProtocol::GetStats not implemented")
+		go respondNotImplemented(packet, RankingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, rankingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[RankingProtocol::GetStats] Data missing category")
+		go rankingProtocol.GetStatsHandler(err, client, callID, 0, nil, 0)
+		return
+	}
+
+	category := parametersStream.ReadUInt32LE()
+
+	orderParamStructureInterface, err := parametersStream.ReadStructure(NewRankingOrderParam())
+	if err != nil {
+		go rankingProtocol.GetStatsHandler(err, client, callID, 0, nil, 0)
+		return
+	}
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[RankingProtocol::GetStats] Data missing flags")
+		go rankingProtocol.GetStatsHandler(err, client, callID, 0, nil, 0)
+		return
+	}
+
+	flags := parametersStream.ReadUInt32LE()
+
+	orderParam := orderParamStructureInterface.(*RankingOrderParam)
+
+	go rankingProtocol.GetStatsHandler(nil, client, callID, category, orderParam, flags)
+}
+
+func (rankingProtocol *RankingProtocol) handleGetRankingByPIDList(packet nex.PacketInterface) {
+	if rankingProtocol.GetRankingByPIDListHandler == nil {
+		log.Println("[Warning] RankingProtocol::GetRankingByPIDList not implemented")
+		go respondNotImplemented(packet, RankingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, rankingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[RankingProtocol::GetRankingByPIDList] Data missing principal ID list length")
+		go rankingProtocol.GetRankingByPIDListHandler(err, client, callID, nil, 0, 0, nil, 0)
+		return
+	}
+
+	principalIDs := parametersStream.ReadListUInt32LE()
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 1 {
+		err := errors.New("[RankingProtocol::GetRankingByPIDList] Data missing ranking mode")
+		go rankingProtocol.GetRankingByPIDListHandler(err, client, callID, nil, 0, 0, nil, 0)
+		return
+	}
+
+	rankingMode := parametersStream.ReadUInt8()
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[RankingProtocol::GetRankingByPIDList] Data missing category")
+		go rankingProtocol.GetRankingByPIDListHandler(err, client, callID, nil, 0, 0, nil, 0)
+		return
+	}
+
+	category := parametersStream.ReadUInt32LE()
+
+	orderParamStructureInterface, err := parametersStream.ReadStructure(NewRankingOrderParam())
+	if err != nil {
+		go rankingProtocol.GetRankingByPIDListHandler(err, client, callID, nil, 0, 0, nil, 0)
+		return
+	}
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 8 {
+		err := errors.New("[RankingProtocol::GetRankingByPIDList] Data missing unique ID")
+		go rankingProtocol.GetRankingByPIDListHandler(err, client, callID, nil, 0, 0, nil, 0)
+		return
+	}
+
+	uniqueID := parametersStream.ReadUInt64LE()
+
+	orderParam := orderParamStructureInterface.(*RankingOrderParam)
+
+	go rankingProtocol.GetRankingByPIDListHandler(nil, client, callID, principalIDs, rankingMode, category, orderParam, uniqueID)
+}
+
+func (rankingProtocol *RankingProtocol) handleGetRankingByUniqueIDList(packet nex.PacketInterface) {
+	if rankingProtocol.GetRankingByUniqueIDListHandler == nil {
+		log.Println("[Warning] RankingProtocol::GetRankingByUniqueIDList not implemented")
+		go respondNotImplemented(packet, RankingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, rankingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[RankingProtocol::GetRankingByUniqueIDList] Data missing unique ID list length")
+		go rankingProtocol.GetRankingByUniqueIDListHandler(err, client, callID, nil, 0, 0, nil, 0)
+		return
+	}
+
+	uniqueIDs := parametersStream.ReadListUInt64LE()
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 1 {
+		err := errors.New("[RankingProtocol::GetRankingByUniqueIDList] Data missing ranking mode")
+		go rankingProtocol.GetRankingByUniqueIDListHandler(err, client, callID, nil, 0, 0, nil, 0)
+		return
+	}
+
+	rankingMode := parametersStream.ReadUInt8()
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[RankingProtocol::GetRankingByUniqueIDList] Data missing category")
+		go rankingProtocol.GetRankingByUniqueIDListHandler(err, client, callID, nil, 0, 0, nil, 0)
+		return
+	}
+
+	category := parametersStream.ReadUInt32LE()
+
+	orderParamStructureInterface, err := parametersStream.ReadStructure(NewRankingOrderParam())
+	if err != nil {
+		go rankingProtocol.GetRankingByUniqueIDListHandler(err, client, callID, nil, 0, 0, nil, 0)
+		return
+	}
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 8 {
+		err := errors.New("[RankingProtocol::GetRankingByUniqueIDList] Data missing unique ID")
+		go rankingProtocol.GetRankingByUniqueIDListHandler(err, client, callID, nil, 0, 0, nil, 0)
+		return
+	}
+
+	uniqueID := parametersStream.ReadUInt64LE()
+
+	orderParam := orderParamStructureInterface.(*RankingOrderParam)
+
+	go rankingProtocol.GetRankingByUniqueIDListHandler(nil, client, callID, uniqueIDs, rankingMode, category, orderParam, uniqueID)
+}
+
+func (rankingProtocol *RankingProtocol) handleGetCachedTopXRanking(packet nex.PacketInterface) {
+	if rankingProtocol.GetCachedTopXRankingHandler == nil {
+		log.Println("[Warning] RankingProtocol::GetCachedTopXRanking not implemented")
+		go respondNotImplemented(packet, RankingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, rankingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[RankingProtocol::GetCachedTopXRanking] Data missing category")
+		go rankingProtocol.GetCachedTopXRankingHandler(err, client, callID, 0, nil)
+		return
+	}
+
+	category := parametersStream.ReadUInt32LE()
+
+	orderParamStructureInterface, err := parametersStream.ReadStructure(NewRankingOrderParam())
+	if err != nil {
+		go rankingProtocol.GetCachedTopXRankingHandler(err, client, callID, 0, nil)
+		return
+	}
+
+	orderParam := orderParamStructureInterface.(*RankingOrderParam)
+
+	go rankingProtocol.GetCachedTopXRankingHandler(nil, client, callID, category, orderParam)
+}
+
+func (rankingProtocol *RankingProtocol) handleGetCachedTopXRankings(packet nex.PacketInterface) {
+	if rankingProtocol.GetCachedTopXRankingsHandler == nil {
+		log.Println("[Warning] RankingProtocol::GetCachedTopXRankings not implemented")
+		go respondNotImplemented(packet, RankingProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, rankingProtocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[RankingProtocol::GetCachedTopXRankings] Data missing category list length")
+		go rankingProtocol.GetCachedTopXRankingsHandler(err, client, callID, nil, nil)
+		return
+	}
+
+	categories := parametersStream.ReadListUInt32LE()
+
+	orderParams, err := parametersStream.ReadListRankingOrderParam()
+	if err != nil {
+		go rankingProtocol.GetCachedTopXRankingsHandler(err, client, callID, nil, nil)
+		return
+	}
+
+	go rankingProtocol.GetCachedTopXRankingsHandler(nil, client, callID, categories, orderParams)
 }
 
 // NewRankingProtocol returns a new RankingProtocol
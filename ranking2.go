@@ -0,0 +1,751 @@
+package nexproto
+
+import (
+	"errors"
+	"log"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+const (
+	// Ranking2ProtocolID is the protocol ID for the Ranking2 protocol
+	Ranking2ProtocolID = 0x7A
+
+	// Ranking2MethodPutScore is the method ID for method PutScore
+	Ranking2MethodPutScore = 0x1
+
+	// Ranking2MethodGetCommonData is the method ID for method GetCommonData
+	Ranking2MethodGetCommonData = 0x2
+
+	// Ranking2MethodPutCommonData is the method ID for method PutCommonData
+	Ranking2MethodPutCommonData = 0x3
+
+	// Ranking2MethodDeleteCommonData is the method ID for method DeleteCommonData
+	Ranking2MethodDeleteCommonData = 0x4
+
+	// Ranking2MethodGetRanking is the method ID for method GetRanking
+	Ranking2MethodGetRanking = 0x5
+
+	// Ranking2MethodGetRankingByPrincipalID is the method ID for method GetRankingByPrincipalId
+	Ranking2MethodGetRankingByPrincipalID = 0x6
+
+	// Ranking2MethodGetCategorySetting is the method ID for method GetCategorySetting
+	Ranking2MethodGetCategorySetting = 0x7
+)
+
+// Values of Ranking2GetParam.Mode. They are taken to number the modes like the rankingMode of Ranking GetRanking,
+// which no Ranking2 capture has confirmed yet. Friend rankings go through GetRankingByPrincipalId instead
+const (
+	// Ranking2ModeGlobal pages through the whole ranking from the offset of the Ranking2GetParam
+	Ranking2ModeGlobal = 0
+
+	// Ranking2ModeGlobalAroundSelf returns the part of the whole ranking centered on the caller
+	Ranking2ModeGlobalAroundSelf = 1
+)
+
+// Values of Ranking2CategorySetting.ResetMode. They are taken to follow RankingResetSchedule.Period,
+// which no Ranking2 capture has confirmed yet
+const (
+	// Ranking2ResetModeNever keeps the scores of a category until they are deleted
+	Ranking2ResetModeNever = 0
+
+	// Ranking2ResetModeDaily starts a category over every day at ResetHour
+	Ranking2ResetModeDaily = 1
+
+	// Ranking2ResetModeWeekly starts a category over every week on weekday ResetDay at ResetHour
+	Ranking2ResetModeWeekly = 2
+
+	// Ranking2ResetModeMonthly starts a category over every month on day ResetDay at ResetHour
+	Ranking2ResetModeMonthly = 3
+)
+
+// Ranking2ScoreData is a score uploaded to a Ranking2 category. Misc is stored as the param of the score
+type Ranking2ScoreData struct {
+	Misc     uint64
+	Category uint32
+	Score    uint32
+
+	nex.Structure
+}
+
+// Bytes encodes the Ranking2ScoreData and returns a byte array
+func (scoreData *Ranking2ScoreData) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt64LE(scoreData.Misc)
+	stream.WriteUInt32LE(scoreData.Category)
+	stream.WriteUInt32LE(scoreData.Score)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a Ranking2ScoreData structure from a stream
+func (scoreData *Ranking2ScoreData) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 16 {
+		// length check for the following fixed-size data
+		// misc + category + score
+		return errors.New("[Ranking2ScoreData::ExtractFromStream] Data size too small")
+	}
+
+	scoreData.Misc = stream.ReadUInt64LE()
+	scoreData.Category = stream.ReadUInt32LE()
+	scoreData.Score = stream.ReadUInt32LE()
+
+	return nil
+}
+
+// NewRanking2ScoreData returns a new Ranking2ScoreData
+func NewRanking2ScoreData() *Ranking2ScoreData {
+	return &Ranking2ScoreData{}
+}
+
+// Ranking2GetParam selects the part of a Ranking2 ranking returned by GetRanking and GetRankingByPrincipalId.
+// Mode takes the Ranking2Mode values and NumSeasonsToGoBack selects a past period, 0 being the current one
+type Ranking2GetParam struct {
+	NexUniqueID        uint64
+	PrincipalID        uint32
+	Category           uint32
+	Offset             uint32
+	Length             uint32
+	SortFlags          uint32
+	OptionFlags        uint32
+	Mode               uint8
+	NumSeasonsToGoBack uint8
+
+	nex.Structure
+}
+
+// Bytes encodes the Ranking2GetParam and returns a byte array
+func (getParam *Ranking2GetParam) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt64LE(getParam.NexUniqueID)
+	stream.WriteUInt32LE(getParam.PrincipalID)
+	stream.WriteUInt32LE(getParam.Category)
+	stream.WriteUInt32LE(getParam.Offset)
+	stream.WriteUInt32LE(getParam.Length)
+	stream.WriteUInt32LE(getParam.SortFlags)
+	stream.WriteUInt32LE(getParam.OptionFlags)
+	stream.WriteUInt8(getParam.Mode)
+	stream.WriteUInt8(getParam.NumSeasonsToGoBack)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a Ranking2GetParam structure from a stream
+func (getParam *Ranking2GetParam) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 34 {
+		// length check for the following fixed-size data
+		// nexUniqueID + principalID + category + offset + length + sortFlags + optionFlags + mode + numSeasonsToGoBack
+		return errors.New("[Ranking2GetParam::ExtractFromStream] Data size too small")
+	}
+
+	getParam.NexUniqueID = stream.ReadUInt64LE()
+	getParam.PrincipalID = stream.ReadUInt32LE()
+	getParam.Category = stream.ReadUInt32LE()
+	getParam.Offset = stream.ReadUInt32LE()
+	getParam.Length = stream.ReadUInt32LE()
+	getParam.SortFlags = stream.ReadUInt32LE()
+	getParam.OptionFlags = stream.ReadUInt32LE()
+	getParam.Mode = stream.ReadUInt8()
+	getParam.NumSeasonsToGoBack = stream.ReadUInt8()
+
+	return nil
+}
+
+// NewRanking2GetParam returns a new Ranking2GetParam
+func NewRanking2GetParam() *Ranking2GetParam {
+	return &Ranking2GetParam{}
+}
+
+// Ranking2CommonData is the profile a Ranking2 client shows next to its scores
+type Ranking2CommonData struct {
+	UserName   string
+	Mii        []byte
+	BinaryData []byte
+
+	nex.Structure
+}
+
+// Bytes encodes the Ranking2CommonData and returns a byte array
+func (commonData *Ranking2CommonData) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteString(commonData.UserName)
+	stream.WriteQBuffer(commonData.Mii)
+	stream.WriteQBuffer(commonData.BinaryData)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a Ranking2CommonData structure from a stream
+func (commonData *Ranking2CommonData) ExtractFromStream(stream *nex.StreamIn) error {
+	userName, err := stream.ReadString()
+	if err != nil {
+		return err
+	}
+
+	mii, err := stream.ReadQBuffer()
+	if err != nil {
+		return err
+	}
+
+	binaryData, err := stream.ReadQBuffer()
+	if err != nil {
+		return err
+	}
+
+	commonData.UserName = userName
+	commonData.Mii = mii
+	commonData.BinaryData = binaryData
+
+	return nil
+}
+
+// Copy returns a deep copy of the Ranking2CommonData
+func (commonData *Ranking2CommonData) Copy() *Ranking2CommonData {
+	return &Ranking2CommonData{
+		UserName:   commonData.UserName,
+		Mii:        append(make([]byte, 0), commonData.Mii...),
+		BinaryData: append(make([]byte, 0), commonData.BinaryData...),
+	}
+}
+
+// NewRanking2CommonData returns a new Ranking2CommonData
+func NewRanking2CommonData() *Ranking2CommonData {
+	return &Ranking2CommonData{
+		Mii:        make([]byte, 0),
+		BinaryData: make([]byte, 0),
+	}
+}
+
+// Ranking2RankData is a score as it appears in a Ranking2 ranking, with its rank and the common data of its owner
+type Ranking2RankData struct {
+	Misc        uint64
+	NexUniqueID uint64
+	PrincipalID uint32
+	Rank        uint32
+	Score       uint32
+	CommonData  *Ranking2CommonData
+
+	nex.Structure
+}
+
+// Bytes encodes the Ranking2RankData and returns a byte array
+func (rankData *Ranking2RankData) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt64LE(rankData.Misc)
+	stream.WriteUInt64LE(rankData.NexUniqueID)
+	stream.WriteUInt32LE(rankData.PrincipalID)
+	stream.WriteUInt32LE(rankData.Rank)
+	stream.WriteUInt32LE(rankData.Score)
+	stream.WriteStructure(rankData.CommonData)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a Ranking2RankData structure from a stream
+func (rankData *Ranking2RankData) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 28 {
+		// length check for the following fixed-size data
+		// misc + nexUniqueID + principalID + rank + score
+		return errors.New("[Ranking2RankData::ExtractFromStream] Data size too small")
+	}
+
+	misc := stream.ReadUInt64LE()
+	nexUniqueID := stream.ReadUInt64LE()
+	principalID := stream.ReadUInt32LE()
+	rank := stream.ReadUInt32LE()
+	score := stream.ReadUInt32LE()
+
+	commonDataStructureInterface, err := stream.ReadStructure(NewRanking2CommonData())
+	if err != nil {
+		return err
+	}
+
+	rankData.Misc = misc
+	rankData.NexUniqueID = nexUniqueID
+	rankData.PrincipalID = principalID
+	rankData.Rank = rank
+	rankData.Score = score
+	rankData.CommonData = commonDataStructureInterface.(*Ranking2CommonData)
+
+	return nil
+}
+
+// NewRanking2RankData returns a new Ranking2RankData
+func NewRanking2RankData() *Ranking2RankData {
+	return &Ranking2RankData{
+		CommonData: NewRanking2CommonData(),
+	}
+}
+
+// Ranking2Info is a page of a Ranking2 ranking. NumRankedIn is the number of scores in the whole ranking,
+// LowestRank the last rank clients show and Season the number of periods of the category ended before this one
+type Ranking2Info struct {
+	RankDataList []*Ranking2RankData
+	LowestRank   uint32
+	NumRankedIn  uint32
+	Season       int32
+
+	nex.Structure
+}
+
+// Bytes encodes the Ranking2Info and returns a byte array
+func (info *Ranking2Info) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt32LE(uint32(len(info.RankDataList)))
+
+	for _, rankData := range info.RankDataList {
+		stream.WriteStructure(rankData)
+	}
+
+	stream.WriteUInt32LE(info.LowestRank)
+	stream.WriteUInt32LE(info.NumRankedIn)
+	stream.WriteInt32LE(info.Season)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a Ranking2Info structure from a stream
+func (info *Ranking2Info) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 4 {
+		return errors.New("[Ranking2Info::ExtractFromStream] Data missing rank data list length")
+	}
+
+	length := stream.ReadUInt32LE()
+	rankDataList := make([]*Ranking2RankData, 0)
+
+	for i := 0; i < int(length); i++ {
+		rankDataStructureInterface, err := stream.ReadStructure(NewRanking2RankData())
+		if err != nil {
+			return err
+		}
+
+		rankDataList = append(rankDataList, rankDataStructureInterface.(*Ranking2RankData))
+	}
+
+	if len(stream.Bytes()[stream.ByteOffset():]) < 12 {
+		// length check for the following fixed-size data
+		// lowestRank + numRankedIn + season
+		return errors.New("[Ranking2Info::ExtractFromStream] Data size too small")
+	}
+
+	info.RankDataList = rankDataList
+	info.LowestRank = stream.ReadUInt32LE()
+	info.NumRankedIn = stream.ReadUInt32LE()
+	info.Season = stream.ReadInt32LE()
+
+	return nil
+}
+
+// NewRanking2Info returns a new Ranking2Info
+func NewRanking2Info() *Ranking2Info {
+	return &Ranking2Info{
+		RankDataList: make([]*Ranking2RankData, 0),
+	}
+}
+
+// Ranking2CategorySetting configures a Ranking2 category. Scores outside MinScore and MaxScore are refused,
+// ScoreOrder ranks lower scores first when true, and ResetMode takes the Ranking2ResetMode values, any other one
+// never resetting. Weekly resets happen on weekday ResetDay (0 being Sunday) and monthly resets on day ResetDay
+// of the month, both at ResetHour UTC. ResetMonth is only passed on to clients
+type Ranking2CategorySetting struct {
+	MinScore           uint32
+	MaxScore           uint32
+	LowestRank         uint32
+	ResetMonth         uint16
+	ResetDay           uint8
+	ResetHour          uint8
+	ResetMode          uint8
+	MaxSeasonsToGoBack uint8
+	ScoreOrder         bool
+
+	nex.Structure
+}
+
+// Bytes encodes the Ranking2CategorySetting and returns a byte array
+func (setting *Ranking2CategorySetting) Bytes(stream *nex.StreamOut) []byte {
+	stream.WriteUInt32LE(setting.MinScore)
+	stream.WriteUInt32LE(setting.MaxScore)
+	stream.WriteUInt32LE(setting.LowestRank)
+	stream.WriteUInt16LE(setting.ResetMonth)
+	stream.WriteUInt8(setting.ResetDay)
+	stream.WriteUInt8(setting.ResetHour)
+	stream.WriteUInt8(setting.ResetMode)
+	stream.WriteUInt8(setting.MaxSeasonsToGoBack)
+	writeBool(stream, setting.ScoreOrder)
+
+	return stream.Bytes()
+}
+
+// ExtractFromStream extracts a Ranking2CategorySetting structure from a stream
+func (setting *Ranking2CategorySetting) ExtractFromStream(stream *nex.StreamIn) error {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 19 {
+		// length check for the following fixed-size data
+		// minScore + maxScore + lowestRank + resetMonth + resetDay + resetHour + resetMode + maxSeasonsToGoBack + scoreOrder
+		return errors.New("[Ranking2CategorySetting::ExtractFromStream] Data size too small")
+	}
+
+	setting.MinScore = stream.ReadUInt32LE()
+	setting.MaxScore = stream.ReadUInt32LE()
+	setting.LowestRank = stream.ReadUInt32LE()
+	setting.ResetMonth = stream.ReadUInt16LE()
+	setting.ResetDay = stream.ReadUInt8()
+	setting.ResetHour = stream.ReadUInt8()
+	setting.ResetMode = stream.ReadUInt8()
+	setting.MaxSeasonsToGoBack = stream.ReadUInt8()
+	setting.ScoreOrder = stream.ReadUInt8() == 1
+
+	return nil
+}
+
+// NewRanking2CategorySetting returns a new Ranking2CategorySetting
+func NewRanking2CategorySetting() *Ranking2CategorySetting {
+	return &Ranking2CategorySetting{}
+}
+
+// Ranking2Protocol handles the Ranking2 nex protocol. GetRankingChart, GetRankingCharts and GetEstimateScoreRank
+// (methods 0x8 to 0xA) are out of scope and logged as unsupported
+type Ranking2Protocol struct {
+	server                         *nex.Server
+	PutScoreHandler                func(err error, client *nex.Client, callID uint32, scoreDataList []*Ranking2ScoreData, nexUniqueID uint64)
+	GetCommonDataHandler           func(err error, client *nex.Client, callID uint32, optionFlags uint32, principalID uint32, nexUniqueID uint64)
+	PutCommonDataHandler           func(err error, client *nex.Client, callID uint32, commonData *Ranking2CommonData, nexUniqueID uint64)
+	DeleteCommonDataHandler        func(err error, client *nex.Client, callID uint32, nexUniqueID uint64)
+	GetRankingHandler              func(err error, client *nex.Client, callID uint32, getParam *Ranking2GetParam)
+	GetRankingByPrincipalIDHandler func(err error, client *nex.Client, callID uint32, getParam *Ranking2GetParam, principalIDs []uint32)
+	GetCategorySettingHandler      func(err error, client *nex.Client, callID uint32, category uint32)
+}
+
+// Setup initializes the protocol
+func (ranking2Protocol *Ranking2Protocol) Setup() {
+	nexServer := ranking2Protocol.server
+
+	trackPacketRoutes(nexServer)
+
+	nexServer.On("Data", func(packet nex.PacketInterface) {
+		request := packet.RMCRequest()
+
+		if Ranking2ProtocolID == request.ProtocolID() {
+			rememberPacketRoute(packet)
+
+			switch request.MethodID() {
+			case Ranking2MethodPutScore:
+				go ranking2Protocol.handlePutScore(packet)
+			case Ranking2MethodGetCommonData:
+				go ranking2Protocol.handleGetCommonData(packet)
+			case Ranking2MethodPutCommonData:
+				go ranking2Protocol.handlePutCommonData(packet)
+			case Ranking2MethodDeleteCommonData:
+				go ranking2Protocol.handleDeleteCommonData(packet)
+			case Ranking2MethodGetRanking:
+				go ranking2Protocol.handleGetRanking(packet)
+			case Ranking2MethodGetRankingByPrincipalID:
+				go ranking2Protocol.handleGetRankingByPrincipalID(packet)
+			case Ranking2MethodGetCategorySetting:
+				go ranking2Protocol.handleGetCategorySetting(packet)
+			default:
+				log.Printf("Unsupported Ranking2 method ID: %#v\n", request.MethodID())
+			}
+		}
+	})
+}
+
+// PutScore sets the PutScore handler function
+func (ranking2Protocol *Ranking2Protocol) PutScore(handler func(err error, client *nex.Client, callID uint32, scoreDataList []*Ranking2ScoreData, nexUniqueID uint64)) {
+	ranking2Protocol.PutScoreHandler = handler
+}
+
+// GetCommonData sets the GetCommonData handler function
+func (ranking2Protocol *Ranking2Protocol) GetCommonData(handler func(err error, client *nex.Client, callID uint32, optionFlags uint32, principalID uint32, nexUniqueID uint64)) {
+	ranking2Protocol.GetCommonDataHandler = handler
+}
+
+// PutCommonData sets the PutCommonData handler function
+func (ranking2Protocol *Ranking2Protocol) PutCommonData(handler func(err error, client *nex.Client, callID uint32, commonData *Ranking2CommonData, nexUniqueID uint64)) {
+	ranking2Protocol.PutCommonDataHandler = handler
+}
+
+// DeleteCommonData sets the DeleteCommonData handler function
+func (ranking2Protocol *Ranking2Protocol) DeleteCommonData(handler func(err error, client *nex.Client, callID uint32, nexUniqueID uint64)) {
+	ranking2Protocol.DeleteCommonDataHandler = handler
+}
+
+// GetRanking sets the GetRanking handler function
+func (ranking2Protocol *Ranking2Protocol) GetRanking(handler func(err error, client *nex.Client, callID uint32, getParam *Ranking2GetParam)) {
+	ranking2Protocol.GetRankingHandler = handler
+}
+
+// GetRankingByPrincipalID sets the GetRankingByPrincipalID handler function
+func (ranking2Protocol *Ranking2Protocol) GetRankingByPrincipalID(handler func(err error, client *nex.Client, callID uint32, getParam *Ranking2GetParam, principalIDs []uint32)) {
+	ranking2Protocol.GetRankingByPrincipalIDHandler = handler
+}
+
+// GetCategorySetting sets the GetCategorySetting handler function
+func (ranking2Protocol *Ranking2Protocol) GetCategorySetting(handler func(err error, client *nex.Client, callID uint32, category uint32)) {
+	ranking2Protocol.GetCategorySettingHandler = handler
+}
+
+// UseLeaderboard installs the handlers of leaderboard for every method
+func (ranking2Protocol *Ranking2Protocol) UseLeaderboard(leaderboard *Ranking2Leaderboard) {
+	ranking2Protocol.PutScore(leaderboard.HandlePutScore)
+	ranking2Protocol.GetCommonData(leaderboard.HandleGetCommonData)
+	ranking2Protocol.PutCommonData(leaderboard.HandlePutCommonData)
+	ranking2Protocol.DeleteCommonData(leaderboard.HandleDeleteCommonData)
+	ranking2Protocol.GetRanking(leaderboard.HandleGetRanking)
+	ranking2Protocol.GetRankingByPrincipalID(leaderboard.HandleGetRankingByPrincipalID)
+	ranking2Protocol.GetCategorySetting(leaderboard.HandleGetCategorySetting)
+}
+
+// RespondPutScore answers a PutScore call
+func (ranking2Protocol *Ranking2Protocol) RespondPutScore(client *nex.Client, callID uint32) {
+	respondSuccess(client, Ranking2ProtocolID, Ranking2MethodPutScore, callID, make([]byte, 0))
+}
+
+// RespondGetCommonData answers a GetCommonData call with the common data of the requested PID
+func (ranking2Protocol *Ranking2Protocol) RespondGetCommonData(client *nex.Client, callID uint32, commonData *Ranking2CommonData) {
+	respondRanking2Structure(client, callID, Ranking2MethodGetCommonData, commonData)
+}
+
+// RespondPutCommonData answers a PutCommonData call
+func (ranking2Protocol *Ranking2Protocol) RespondPutCommonData(client *nex.Client, callID uint32) {
+	respondSuccess(client, Ranking2ProtocolID, Ranking2MethodPutCommonData, callID, make([]byte, 0))
+}
+
+// RespondDeleteCommonData answers a DeleteCommonData call
+func (ranking2Protocol *Ranking2Protocol) RespondDeleteCommonData(client *nex.Client, callID uint32) {
+	respondSuccess(client, Ranking2ProtocolID, Ranking2MethodDeleteCommonData, callID, make([]byte, 0))
+}
+
+// RespondGetRanking answers a GetRanking call with a page of a ranking
+func (ranking2Protocol *Ranking2Protocol) RespondGetRanking(client *nex.Client, callID uint32, info *Ranking2Info) {
+	respondRanking2Structure(client, callID, Ranking2MethodGetRanking, info)
+}
+
+// RespondGetRankingByPrincipalID answers a GetRankingByPrincipalId call with the scores of the requested PIDs
+func (ranking2Protocol *Ranking2Protocol) RespondGetRankingByPrincipalID(client *nex.Client, callID uint32, info *Ranking2Info) {
+	respondRanking2Structure(client, callID, Ranking2MethodGetRankingByPrincipalID, info)
+}
+
+// RespondGetCategorySetting answers a GetCategorySetting call with the setting of a category
+func (ranking2Protocol *Ranking2Protocol) RespondGetCategorySetting(client *nex.Client, callID uint32, setting *Ranking2CategorySetting) {
+	respondRanking2Structure(client, callID, Ranking2MethodGetCategorySetting, setting)
+}
+
+// respondRanking2Structure answers a Ranking2 method returning a single structure
+func respondRanking2Structure(client *nex.Client, callID uint32, methodID uint32, structure nex.StructureInterface) {
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteStructure(structure)
+
+	respondSuccess(client, Ranking2ProtocolID, methodID, callID, responseStream.Bytes())
+}
+
+func (ranking2Protocol *Ranking2Protocol) handlePutScore(packet nex.PacketInterface) {
+	if ranking2Protocol.PutScoreHandler == nil {
+		log.Println("[Warning] Ranking2Protocol::PutScore not implemented")
+		go respondNotImplemented(packet, Ranking2ProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, ranking2Protocol.server)
+
+	scoreDataList, err := parametersStream.ReadListRanking2ScoreData()
+	if err != nil {
+		go ranking2Protocol.PutScoreHandler(err, client, callID, nil, 0)
+		return
+	}
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 8 {
+		err := errors.New("[Ranking2Protocol::PutScore] Data missing unique ID")
+		go ranking2Protocol.PutScoreHandler(err, client, callID, nil, 0)
+		return
+	}
+
+	nexUniqueID := parametersStream.ReadUInt64LE()
+
+	go ranking2Protocol.PutScoreHandler(nil, client, callID, scoreDataList, nexUniqueID)
+}
+
+func (ranking2Protocol *Ranking2Protocol) handleGetCommonData(packet nex.PacketInterface) {
+	if ranking2Protocol.GetCommonDataHandler == nil {
+		log.Println("[Warning] Ranking2Protocol::GetCommonData not implemented")
+		go respondNotImplemented(packet, Ranking2ProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, ranking2Protocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 16 {
+		// length check for the following fixed-size data
+		// optionFlags + principalID + nexUniqueID
+		err := errors.New("[Ranking2Protocol::GetCommonData] Data length too small")
+		go ranking2Protocol.GetCommonDataHandler(err, client, callID, 0, 0, 0)
+		return
+	}
+
+	optionFlags := parametersStream.ReadUInt32LE()
+	principalID := parametersStream.ReadUInt32LE()
+	nexUniqueID := parametersStream.ReadUInt64LE()
+
+	go ranking2Protocol.GetCommonDataHandler(nil, client, callID, optionFlags, principalID, nexUniqueID)
+}
+
+func (ranking2Protocol *Ranking2Protocol) handlePutCommonData(packet nex.PacketInterface) {
+	if ranking2Protocol.PutCommonDataHandler == nil {
+		log.Println("[Warning] Ranking2Protocol::PutCommonData not implemented")
+		go respondNotImplemented(packet, Ranking2ProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, ranking2Protocol.server)
+
+	commonDataStructureInterface, err := parametersStream.ReadStructure(NewRanking2CommonData())
+	if err != nil {
+		go ranking2Protocol.PutCommonDataHandler(err, client, callID, nil, 0)
+		return
+	}
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 8 {
+		err := errors.New("[Ranking2Protocol::PutCommonData] Data missing unique ID")
+		go ranking2Protocol.PutCommonDataHandler(err, client, callID, nil, 0)
+		return
+	}
+
+	nexUniqueID := parametersStream.ReadUInt64LE()
+
+	commonData := commonDataStructureInterface.(*Ranking2CommonData)
+
+	go ranking2Protocol.PutCommonDataHandler(nil, client, callID, commonData, nexUniqueID)
+}
+
+func (ranking2Protocol *Ranking2Protocol) handleDeleteCommonData(packet nex.PacketInterface) {
+	if ranking2Protocol.DeleteCommonDataHandler == nil {
+		log.Println("[Warning] Ranking2Protocol::DeleteCommonData not implemented")
+		go respondNotImplemented(packet, Ranking2ProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, ranking2Protocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 8 {
+		err := errors.New("[Ranking2Protocol::DeleteCommonData] Data missing unique ID")
+		go ranking2Protocol.DeleteCommonDataHandler(err, client, callID, 0)
+		return
+	}
+
+	nexUniqueID := parametersStream.ReadUInt64LE()
+
+	go ranking2Protocol.DeleteCommonDataHandler(nil, client, callID, nexUniqueID)
+}
+
+func (ranking2Protocol *Ranking2Protocol) handleGetRanking(packet nex.PacketInterface) {
+	if ranking2Protocol.GetRankingHandler == nil {
+		log.Println("[Warning] Ranking2Protocol::GetRanking not implemented")
+		go respondNotImplemented(packet, Ranking2ProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, ranking2Protocol.server)
+
+	getParamStructureInterface, err := parametersStream.ReadStructure(NewRanking2GetParam())
+	if err != nil {
+		go ranking2Protocol.GetRankingHandler(err, client, callID, nil)
+		return
+	}
+
+	getParam := getParamStructureInterface.(*Ranking2GetParam)
+
+	go ranking2Protocol.GetRankingHandler(nil, client, callID, getParam)
+}
+
+func (ranking2Protocol *Ranking2Protocol) handleGetRankingByPrincipalID(packet nex.PacketInterface) {
+	if ranking2Protocol.GetRankingByPrincipalIDHandler == nil {
+		log.Println("[Warning] Ranking2Protocol::GetRankingByPrincipalID not implemented")
+		go respondNotImplemented(packet, Ranking2ProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, ranking2Protocol.server)
+
+	getParamStructureInterface, err := parametersStream.ReadStructure(NewRanking2GetParam())
+	if err != nil {
+		go ranking2Protocol.GetRankingByPrincipalIDHandler(err, client, callID, nil, nil)
+		return
+	}
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[Ranking2Protocol::GetRankingByPrincipalID] Data missing principal ID list length")
+		go ranking2Protocol.GetRankingByPrincipalIDHandler(err, client, callID, nil, nil)
+		return
+	}
+
+	principalIDs := parametersStream.ReadListUInt32LE()
+
+	getParam := getParamStructureInterface.(*Ranking2GetParam)
+
+	go ranking2Protocol.GetRankingByPrincipalIDHandler(nil, client, callID, getParam, principalIDs)
+}
+
+func (ranking2Protocol *Ranking2Protocol) handleGetCategorySetting(packet nex.PacketInterface) {
+	if ranking2Protocol.GetCategorySettingHandler == nil {
+		log.Println("[Warning] Ranking2Protocol::GetCategorySetting not implemented")
+		go respondNotImplemented(packet, Ranking2ProtocolID)
+		return
+	}
+
+	client := packet.Sender()
+	request := packet.RMCRequest()
+
+	callID := request.CallID()
+	parameters := request.Parameters()
+
+	parametersStream := NewStreamIn(parameters, ranking2Protocol.server)
+
+	if len(parametersStream.Bytes()[parametersStream.ByteOffset():]) < 4 {
+		err := errors.New("[Ranking2Protocol::GetCategorySetting] Data missing category")
+		go ranking2Protocol.GetCategorySettingHandler(err, client, callID, 0)
+		return
+	}
+
+	category := parametersStream.ReadUInt32LE()
+
+	go ranking2Protocol.GetCategorySettingHandler(nil, client, callID, category)
+}
+
+// NewRanking2Protocol returns a new Ranking2Protocol
+func NewRanking2Protocol(server *nex.Server) *Ranking2Protocol {
+	ranking2Protocol := &Ranking2Protocol{server: server}
+
+	ranking2Protocol.Setup()
+
+	return ranking2Protocol
+}
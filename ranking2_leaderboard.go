@@ -0,0 +1,429 @@
+package nexproto
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+// ErrRanking2CategoryNotFound is returned when a Ranking2 category has no setting
+var ErrRanking2CategoryNotFound = errors.New("ranking2 category not found")
+
+// Ranking2Leaderboard serves the Ranking2 protocol from the scores of a Leaderboard, so titles using either
+// protocol share the same rankings. Ranking2 scores are stored with their Misc as param and no groups.
+// Ranking2 common data is kept apart from the Ranking common data, the two formats being unrelated.
+// Scores go through the ScoreValidator set with UseScoreValidator, if any, like Ranking uploads
+type Ranking2Leaderboard struct {
+	mutex            sync.RWMutex
+	leaderboard      *Leaderboard
+	validator        *ScoreValidator
+	settings         map[uint32]*Ranking2CategorySetting
+	commonData       map[rankingCommonDataKey]*Ranking2CommonData
+	commonDataCounts map[uint32]int // unique IDs each PID has common data under
+}
+
+// UseScoreValidator sends the scores put by clients through validator before they are ranked.
+// validator must upload to the Leaderboard shared by ranking2Leaderboard
+func (ranking2Leaderboard *Ranking2Leaderboard) UseScoreValidator(validator *ScoreValidator) {
+	ranking2Leaderboard.mutex.Lock()
+	defer ranking2Leaderboard.mutex.Unlock()
+
+	ranking2Leaderboard.validator = validator
+}
+
+// SetCategorySetting configures category, setting the order and reset schedule of its scores in the leaderboard
+func (ranking2Leaderboard *Ranking2Leaderboard) SetCategorySetting(category uint32, setting *Ranking2CategorySetting) {
+	copied := *setting
+
+	ranking2Leaderboard.mutex.Lock()
+	ranking2Leaderboard.settings[category] = &copied
+	ranking2Leaderboard.mutex.Unlock()
+
+	ranking2Leaderboard.leaderboard.SetCategoryOrder(category, ranking2OrderBy(&copied))
+	ranking2Leaderboard.leaderboard.SetCategoryResetSchedule(category, ranking2ResetSchedule(&copied))
+}
+
+// CategorySetting returns the setting of category
+func (ranking2Leaderboard *Ranking2Leaderboard) CategorySetting(category uint32) (*Ranking2CategorySetting, error) {
+	ranking2Leaderboard.mutex.RLock()
+	defer ranking2Leaderboard.mutex.RUnlock()
+
+	setting, ok := ranking2Leaderboard.settings[category]
+	if !ok {
+		return nil, ErrRanking2CategoryNotFound
+	}
+
+	copied := *setting
+
+	return &copied, nil
+}
+
+// PutScore stores the scores of scoreDataList for pid under nexUniqueID, each one only replacing a worse stored score.
+// Nothing is stored if one of the scores is outside the bounds of its category.
+// With a ScoreValidator the list is submitted to it as a single upload, and scores may be quarantined instead of being stored
+func (ranking2Leaderboard *Ranking2Leaderboard) PutScore(pid uint32, nexUniqueID uint64, scoreDataList []*Ranking2ScoreData) error {
+	ranking2Leaderboard.mutex.RLock()
+
+	validator := ranking2Leaderboard.validator
+	uploads := make([]*RankingScoreData, 0, len(scoreDataList))
+
+	for _, scoreData := range scoreDataList {
+		orderBy := uint8(RankingOrderByDescending)
+
+		if setting, ok := ranking2Leaderboard.settings[scoreData.Category]; ok {
+			if scoreData.Score < setting.MinScore || scoreData.Score > setting.MaxScore {
+				ranking2Leaderboard.mutex.RUnlock()
+				return ErrScoreOutOfBounds
+			}
+
+			orderBy = ranking2OrderBy(setting)
+		}
+
+		uploads = append(uploads, &RankingScoreData{
+			Category:   scoreData.Category,
+			Score:      scoreData.Score,
+			OrderBy:    orderBy,
+			UpdateMode: RankingUpdateModeNormal,
+			Groups:     make([]byte, 0),
+			Param:      scoreData.Misc,
+		})
+	}
+
+	ranking2Leaderboard.mutex.RUnlock()
+
+	if validator != nil {
+		_, err := validator.SubmitAll(pid, nexUniqueID, uploads)
+		return err
+	}
+
+	for _, scoreData := range uploads {
+		err := ranking2Leaderboard.leaderboard.UploadScore(pid, nexUniqueID, scoreData)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PutCommonData stores the common data of pid under nexUniqueID. The MaxCommonDataSize and MaxCommonDataEntries
+// of the leaderboard apply, the size being that of the whole encoded common data
+func (ranking2Leaderboard *Ranking2Leaderboard) PutCommonData(pid uint32, nexUniqueID uint64, commonData *Ranking2CommonData) error {
+	maxSize := ranking2Leaderboard.leaderboard.MaxCommonDataSize
+	if maxSize != 0 && len(commonData.Bytes(nex.NewStreamOut(nil))) > maxSize {
+		return ErrRankingCommonDataTooLarge
+	}
+
+	ranking2Leaderboard.mutex.Lock()
+	defer ranking2Leaderboard.mutex.Unlock()
+
+	key := rankingCommonDataKey{pid, nexUniqueID}
+
+	if _, ok := ranking2Leaderboard.commonData[key]; !ok {
+		if !commonDataEntryAllowed(ranking2Leaderboard.commonDataCounts, pid, ranking2Leaderboard.leaderboard.MaxCommonDataEntries) {
+			return ErrRankingCommonDataLimit
+		}
+
+		ranking2Leaderboard.commonDataCounts[pid]++
+	}
+
+	ranking2Leaderboard.commonData[key] = commonData.Copy()
+
+	return nil
+}
+
+// CommonData returns the common data of pid under nexUniqueID
+func (ranking2Leaderboard *Ranking2Leaderboard) CommonData(pid uint32, nexUniqueID uint64) (*Ranking2CommonData, error) {
+	ranking2Leaderboard.mutex.RLock()
+	defer ranking2Leaderboard.mutex.RUnlock()
+
+	commonData, ok := ranking2Leaderboard.commonData[rankingCommonDataKey{pid, nexUniqueID}]
+	if !ok {
+		return nil, ErrRankingCommonDataNotFound
+	}
+
+	return commonData.Copy(), nil
+}
+
+// DeleteCommonData deletes the common data of pid under nexUniqueID
+func (ranking2Leaderboard *Ranking2Leaderboard) DeleteCommonData(pid uint32, nexUniqueID uint64) error {
+	ranking2Leaderboard.mutex.Lock()
+	defer ranking2Leaderboard.mutex.Unlock()
+
+	key := rankingCommonDataKey{pid, nexUniqueID}
+
+	if _, ok := ranking2Leaderboard.commonData[key]; !ok {
+		return ErrRankingCommonDataNotFound
+	}
+
+	delete(ranking2Leaderboard.commonData, key)
+	forgetCommonDataEntry(ranking2Leaderboard.commonDataCounts, pid)
+
+	return nil
+}
+
+// Ranking returns the part of the ranking selected by getParam. pid identifies the caller for the modes centered on them
+func (ranking2Leaderboard *Ranking2Leaderboard) Ranking(getParam *Ranking2GetParam, pid uint32) (*Ranking2Info, error) {
+	rankingMode, err := ranking2RankingMode(getParam.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ranking2Leaderboard.leaderboard.PeriodRanking(getParam.Category, int(getParam.NumSeasonsToGoBack), rankingMode, ranking2OrderParam(getParam), pid, getParam.NexUniqueID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ranking2Leaderboard.info(getParam, result), nil
+}
+
+// RankingByPrincipalID returns the scores of the PIDs of pids in the category and period of getParam, ranked among themselves
+func (ranking2Leaderboard *Ranking2Leaderboard) RankingByPrincipalID(getParam *Ranking2GetParam, pids []uint32) (*Ranking2Info, error) {
+	result, err := ranking2Leaderboard.leaderboard.PeriodRankingByPIDList(pids, getParam.Category, int(getParam.NumSeasonsToGoBack), ranking2OrderParam(getParam), getParam.NexUniqueID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ranking2Leaderboard.info(getParam, result), nil
+}
+
+// HandlePutScore is the default Ranking2Protocol::PutScore handler
+func (ranking2Leaderboard *Ranking2Leaderboard) HandlePutScore(err error, client *nex.Client, callID uint32, scoreDataList []*Ranking2ScoreData, nexUniqueID uint64) {
+	if err != nil {
+		respondError(client, Ranking2ProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	err = ranking2Leaderboard.PutScore(client.PID(), nexUniqueID, scoreDataList)
+	if err != nil {
+		respondError(client, Ranking2ProtocolID, callID, scoreValidationResultCode(err))
+		return
+	}
+
+	respondSuccess(client, Ranking2ProtocolID, Ranking2MethodPutScore, callID, make([]byte, 0))
+}
+
+// HandleGetCommonData is the default Ranking2Protocol::GetCommonData handler. A principalID of 0 stands for the caller
+func (ranking2Leaderboard *Ranking2Leaderboard) HandleGetCommonData(err error, client *nex.Client, callID uint32, optionFlags uint32, principalID uint32, nexUniqueID uint64) {
+	if err != nil {
+		respondError(client, Ranking2ProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	if principalID == 0 {
+		principalID = client.PID()
+	}
+
+	commonData, err := ranking2Leaderboard.CommonData(principalID, nexUniqueID)
+	if err != nil {
+		respondError(client, Ranking2ProtocolID, callID, rankingResultCode(err))
+		return
+	}
+
+	respondRanking2Structure(client, callID, Ranking2MethodGetCommonData, commonData)
+}
+
+// HandlePutCommonData is the default Ranking2Protocol::PutCommonData handler
+func (ranking2Leaderboard *Ranking2Leaderboard) HandlePutCommonData(err error, client *nex.Client, callID uint32, commonData *Ranking2CommonData, nexUniqueID uint64) {
+	if err != nil {
+		respondError(client, Ranking2ProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	err = ranking2Leaderboard.PutCommonData(client.PID(), nexUniqueID, commonData)
+	if err != nil {
+		respondError(client, Ranking2ProtocolID, callID, rankingResultCode(err))
+		return
+	}
+
+	respondSuccess(client, Ranking2ProtocolID, Ranking2MethodPutCommonData, callID, make([]byte, 0))
+}
+
+// HandleDeleteCommonData is the default Ranking2Protocol::DeleteCommonData handler
+func (ranking2Leaderboard *Ranking2Leaderboard) HandleDeleteCommonData(err error, client *nex.Client, callID uint32, nexUniqueID uint64) {
+	if err != nil {
+		respondError(client, Ranking2ProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	err = ranking2Leaderboard.DeleteCommonData(client.PID(), nexUniqueID)
+	if err != nil {
+		respondError(client, Ranking2ProtocolID, callID, rankingResultCode(err))
+		return
+	}
+
+	respondSuccess(client, Ranking2ProtocolID, Ranking2MethodDeleteCommonData, callID, make([]byte, 0))
+}
+
+// HandleGetRanking is the default Ranking2Protocol::GetRanking handler. A principal ID of 0 in getParam stands for the caller
+func (ranking2Leaderboard *Ranking2Leaderboard) HandleGetRanking(err error, client *nex.Client, callID uint32, getParam *Ranking2GetParam) {
+	if err != nil {
+		respondError(client, Ranking2ProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	pid := getParam.PrincipalID
+	if pid == 0 {
+		pid = client.PID()
+	}
+
+	info, err := ranking2Leaderboard.Ranking(getParam, pid)
+	if err != nil {
+		respondError(client, Ranking2ProtocolID, callID, ranking2ResultCode(err))
+		return
+	}
+
+	respondRanking2Structure(client, callID, Ranking2MethodGetRanking, info)
+}
+
+// HandleGetRankingByPrincipalID is the default Ranking2Protocol::GetRankingByPrincipalId handler
+func (ranking2Leaderboard *Ranking2Leaderboard) HandleGetRankingByPrincipalID(err error, client *nex.Client, callID uint32, getParam *Ranking2GetParam, principalIDs []uint32) {
+	if err != nil {
+		respondError(client, Ranking2ProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	info, err := ranking2Leaderboard.RankingByPrincipalID(getParam, principalIDs)
+	if err != nil {
+		respondError(client, Ranking2ProtocolID, callID, ranking2ResultCode(err))
+		return
+	}
+
+	respondRanking2Structure(client, callID, Ranking2MethodGetRankingByPrincipalID, info)
+}
+
+// HandleGetCategorySetting is the default Ranking2Protocol::GetCategorySetting handler
+func (ranking2Leaderboard *Ranking2Leaderboard) HandleGetCategorySetting(err error, client *nex.Client, callID uint32, category uint32) {
+	if err != nil {
+		respondError(client, Ranking2ProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	setting, err := ranking2Leaderboard.CategorySetting(category)
+	if err != nil {
+		respondError(client, Ranking2ProtocolID, callID, ranking2ResultCode(err))
+		return
+	}
+
+	respondRanking2Structure(client, callID, Ranking2MethodGetCategorySetting, setting)
+}
+
+// info converts a page of the leaderboard to a Ranking2Info, adding the Ranking2 common data of each score
+func (ranking2Leaderboard *Ranking2Leaderboard) info(getParam *Ranking2GetParam, result *RankingResult) *Ranking2Info {
+	season := ranking2Leaderboard.leaderboard.PeriodCount(getParam.Category) - int(getParam.NumSeasonsToGoBack)
+
+	ranking2Leaderboard.mutex.RLock()
+	defer ranking2Leaderboard.mutex.RUnlock()
+
+	info := NewRanking2Info()
+	info.NumRankedIn = result.TotalCount
+	info.LowestRank = result.TotalCount
+	info.Season = int32(season)
+
+	if setting, ok := ranking2Leaderboard.settings[getParam.Category]; ok && setting.LowestRank != 0 {
+		info.LowestRank = setting.LowestRank
+	}
+
+	for _, rankData := range result.RankData {
+		commonData, ok := ranking2Leaderboard.commonData[rankingCommonDataKey{rankData.PrincipalID, rankData.UniqueID}]
+		if ok {
+			commonData = commonData.Copy()
+		} else {
+			commonData = NewRanking2CommonData()
+		}
+
+		info.RankDataList = append(info.RankDataList, &Ranking2RankData{
+			Misc:        rankData.Param,
+			NexUniqueID: rankData.UniqueID,
+			PrincipalID: rankData.PrincipalID,
+			Rank:        rankData.Order,
+			Score:       rankData.Score,
+			CommonData:  commonData,
+		})
+	}
+
+	return info
+}
+
+// ranking2OrderParam returns the RankingOrderParam paging the leaderboard as set by getParam.
+// Ranking2 ranks tied scores with the same rank, skipping the following ones
+func ranking2OrderParam(getParam *Ranking2GetParam) *RankingOrderParam {
+	orderParam := NewRankingOrderParam()
+	orderParam.OrderCalculation = RankingOrderCalculationStandard
+	orderParam.Offset = getParam.Offset
+	orderParam.Length = math.MaxUint8
+
+	if getParam.Length < math.MaxUint8 {
+		orderParam.Length = uint8(getParam.Length)
+	}
+
+	return orderParam
+}
+
+// ranking2RankingMode returns the Leaderboard ranking mode of a Ranking2GetParam.Mode
+func ranking2RankingMode(mode uint8) (uint8, error) {
+	switch mode {
+	case Ranking2ModeGlobal:
+		return RankingModeGlobal, nil
+	case Ranking2ModeGlobalAroundSelf:
+		return RankingModeGlobalAroundSelf, nil
+	default:
+		return 0, ErrInvalidRankingMode
+	}
+}
+
+// ranking2OrderBy returns the RankingScoreData.OrderBy of the scores of a category with setting
+func ranking2OrderBy(setting *Ranking2CategorySetting) uint8 {
+	if setting.ScoreOrder {
+		return RankingOrderByAscending
+	}
+
+	return RankingOrderByDescending
+}
+
+// ranking2ResetSchedule returns the reset schedule of a category with setting
+func ranking2ResetSchedule(setting *Ranking2CategorySetting) RankingResetSchedule {
+	hour := time.Duration(setting.ResetHour) * time.Hour
+
+	switch setting.ResetMode {
+	case Ranking2ResetModeDaily:
+		return RankingResetSchedule{Period: RankingResetDaily, Anchor: rankingResetAnchor.Add(hour)}
+	case Ranking2ResetModeWeekly:
+		// rankingResetAnchor is a Monday, weekday 1
+		anchor := rankingResetAnchor.AddDate(0, 0, int(setting.ResetDay%7)-1)
+
+		return RankingResetSchedule{Period: RankingResetWeekly, Anchor: anchor.Add(hour)}
+	case Ranking2ResetModeMonthly:
+		day := int(setting.ResetDay)
+		if day == 0 {
+			day = 1
+		}
+
+		return RankingResetSchedule{Period: RankingResetMonthly, Anchor: rankingResetAnchor.AddDate(0, 0, day-1).Add(hour)}
+	default:
+		return RankingResetSchedule{Period: RankingResetNever}
+	}
+}
+
+// ranking2ResultCode returns the result code sent for a Ranking2Leaderboard error
+func ranking2ResultCode(err error) uint32 {
+	switch err {
+	case ErrRanking2CategoryNotFound:
+		return ResultRankingNotFound
+	default:
+		return rankingResultCode(err)
+	}
+}
+
+// NewRanking2Leaderboard returns a new Ranking2Leaderboard sharing the scores of leaderboard
+func NewRanking2Leaderboard(leaderboard *Leaderboard) *Ranking2Leaderboard {
+	return &Ranking2Leaderboard{
+		leaderboard:      leaderboard,
+		settings:         make(map[uint32]*Ranking2CategorySetting),
+		commonData:       make(map[rankingCommonDataKey]*Ranking2CommonData),
+		commonDataCounts: make(map[uint32]int),
+	}
+}
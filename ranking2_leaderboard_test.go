@@ -0,0 +1,204 @@
+package nexproto
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+func TestRanking2StructureRoundTrips(t *testing.T) {
+	info := NewRanking2Info()
+	info.LowestRank = 7
+	info.NumRankedIn = 8
+	info.Season = -1
+	info.RankDataList = append(info.RankDataList, &Ranking2RankData{
+		Misc:        1,
+		NexUniqueID: 2,
+		PrincipalID: 3,
+		Rank:        4,
+		Score:       5,
+		CommonData:  &Ranking2CommonData{UserName: "player", Mii: []byte{1}, BinaryData: []byte{2, 3}},
+	})
+
+	decodedInfo := NewRanking2Info()
+	if err := decodedInfo.ExtractFromStream(nex.NewStreamIn(info.Bytes(nex.NewStreamOut(nil)), nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if decodedInfo.Season != -1 || decodedInfo.LowestRank != 7 || len(decodedInfo.RankDataList) != 1 {
+		t.Fatalf("Ranking2Info decoded as %+v", decodedInfo)
+	}
+
+	rankData := decodedInfo.RankDataList[0]
+	if rankData.Misc != 1 || rankData.Rank != 4 || rankData.CommonData.UserName != "player" || !bytes.Equal(rankData.CommonData.BinaryData, []byte{2, 3}) {
+		t.Fatalf("Ranking2RankData decoded as %+v", rankData)
+	}
+
+	setting := &Ranking2CategorySetting{MaxScore: 9, ResetMonth: 3, ResetMode: Ranking2ResetModeWeekly, MaxSeasonsToGoBack: 2, ScoreOrder: true}
+
+	decodedSetting := NewRanking2CategorySetting()
+	if err := decodedSetting.ExtractFromStream(nex.NewStreamIn(setting.Bytes(nex.NewStreamOut(nil)), nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if *decodedSetting != *setting {
+		t.Fatalf("Ranking2CategorySetting decoded as %+v", decodedSetting)
+	}
+
+	getParam := &Ranking2GetParam{NexUniqueID: 1, PrincipalID: 2, Category: 3, Offset: 4, Length: 5, Mode: Ranking2ModeGlobalAroundSelf, NumSeasonsToGoBack: 2}
+
+	decodedGetParam := NewRanking2GetParam()
+	if err := decodedGetParam.ExtractFromStream(nex.NewStreamIn(getParam.Bytes(nex.NewStreamOut(nil)), nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if *decodedGetParam != *getParam {
+		t.Fatalf("Ranking2GetParam decoded as %+v", decodedGetParam)
+	}
+}
+
+func TestRanking2LeaderboardSharedScores(t *testing.T) {
+	leaderboard := NewLeaderboard()
+	ranking2Leaderboard := NewRanking2Leaderboard(leaderboard)
+	ranking2Leaderboard.SetCategorySetting(5, &Ranking2CategorySetting{MaxScore: 1000, LowestRank: 500, ScoreOrder: true})
+
+	if err := ranking2Leaderboard.PutScore(1, 0, []*Ranking2ScoreData{{Category: 5, Score: 300, Misc: 9}}); err != nil {
+		t.Fatal(err)
+	}
+
+	outOfBounds := []*Ranking2ScoreData{{Category: 5, Score: 200}, {Category: 5, Score: 3000}}
+
+	if err := ranking2Leaderboard.PutScore(2, 0, outOfBounds); err != ErrScoreOutOfBounds {
+		t.Fatalf("score out of bounds returned %v", err)
+	}
+
+	if _, err := leaderboard.Score(2, 5, 0); err != ErrRankingScoreNotFound {
+		t.Fatal("a list with a score out of bounds was partly stored")
+	}
+
+	// Ranking uploads share the category, ranked lowest first from the setting
+	if err := leaderboard.UploadScore(2, 0, &RankingScoreData{Category: 5, Score: 100, OrderBy: RankingOrderByAscending}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ranking2Leaderboard.PutCommonData(2, 0, &Ranking2CommonData{UserName: "player"}); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := ranking2Leaderboard.Ranking(&Ranking2GetParam{Category: 5, Length: 10}, 1)
+	if err != nil || len(info.RankDataList) != 2 {
+		t.Fatalf("Ranking returned %v, %v", info, err)
+	}
+
+	first, second := info.RankDataList[0], info.RankDataList[1]
+	if first.PrincipalID != 2 || first.CommonData.UserName != "player" || second.Misc != 9 || info.LowestRank != 500 {
+		t.Fatalf("ranked %+v then %+v", first, second)
+	}
+
+	if _, err := ranking2Leaderboard.Ranking(&Ranking2GetParam{Category: 5, Length: 10, Mode: 7}, 1); err != ErrInvalidRankingMode {
+		t.Fatalf("unknown mode returned %v", err)
+	}
+
+	if _, err := ranking2Leaderboard.Ranking(&Ranking2GetParam{Category: 5, Length: 10, NumSeasonsToGoBack: 1}, 1); err != ErrRankingPeriodNotFound {
+		t.Fatalf("missing season returned %v", err)
+	}
+}
+
+func TestRanking2LeaderboardValidatedBatch(t *testing.T) {
+	leaderboard := NewLeaderboard()
+	ranking2Leaderboard := NewRanking2Leaderboard(leaderboard)
+
+	validator := NewScoreValidator(leaderboard)
+	validator.RateLimit = 1
+	validator.SetCategoryBounds(6, 0, 100)
+	ranking2Leaderboard.UseScoreValidator(validator)
+
+	scoreDataList := []*Ranking2ScoreData{{Category: 5, Score: 10}, {Category: 6, Score: 500}, {Category: 7, Score: 30}}
+
+	if err := ranking2Leaderboard.PutScore(1, 0, scoreDataList); err != nil {
+		t.Fatalf("a list counted as more than one upload: %v", err)
+	}
+
+	if quarantined := validator.Quarantined(); len(quarantined) != 1 || quarantined[0].ScoreData.Category != 6 {
+		t.Fatalf("quarantined %v", quarantined)
+	}
+
+	for _, category := range []uint32{5, 7} {
+		if _, err := leaderboard.Score(1, category, 0); err != nil {
+			t.Fatalf("score of category %d was not stored: %v", category, err)
+		}
+	}
+
+	if err := ranking2Leaderboard.PutScore(1, 0, scoreDataList[:1]); err != ErrScoreRateLimited {
+		t.Fatalf("upload over the rate limit returned %v", err)
+	}
+}
+
+func TestRanking2ResetSchedule(t *testing.T) {
+	// Wednesday 13 March 2024
+	now := time.Date(2024, time.March, 13, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		setting *Ranking2CategorySetting
+		start   time.Time
+	}{
+		{
+			"weekly on Sunday",
+			&Ranking2CategorySetting{ResetMode: Ranking2ResetModeWeekly, ResetDay: 0, ResetHour: 6},
+			time.Date(2024, time.March, 10, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			"monthly on the 15th",
+			&Ranking2CategorySetting{ResetMode: Ranking2ResetModeMonthly, ResetDay: 15},
+			time.Date(2024, time.February, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"unknown mode",
+			&Ranking2CategorySetting{ResetMode: 9},
+			time.Time{},
+		},
+	}
+
+	for _, test := range tests {
+		schedule := ranking2ResetSchedule(test.setting)
+
+		start, _ := schedule.bounds(now)
+		if !start.Equal(test.start) {
+			t.Errorf("%s: period starts %v, want %v", test.name, start, test.start)
+		}
+	}
+}
+
+func TestRanking2LeaderboardCommonDataLimits(t *testing.T) {
+	leaderboard := NewLeaderboard()
+	leaderboard.MaxCommonDataSize = 16
+	leaderboard.MaxCommonDataEntries = 1
+
+	ranking2Leaderboard := NewRanking2Leaderboard(leaderboard)
+
+	// The user name and Mii count towards the size as well as the binary data
+	oversized := &Ranking2CommonData{UserName: "player", Mii: make([]byte, 8), BinaryData: []byte{1}}
+
+	if err := ranking2Leaderboard.PutCommonData(1, 0, oversized); err != ErrRankingCommonDataTooLarge {
+		t.Fatalf("oversized common data returned %v", err)
+	}
+
+	if err := ranking2Leaderboard.PutCommonData(1, 0, &Ranking2CommonData{UserName: "player"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ranking2Leaderboard.PutCommonData(1, 1, &Ranking2CommonData{UserName: "player"}); err != ErrRankingCommonDataLimit {
+		t.Fatalf("common data over the entry limit returned %v", err)
+	}
+
+	if err := ranking2Leaderboard.DeleteCommonData(1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ranking2Leaderboard.PutCommonData(1, 1, &Ranking2CommonData{UserName: "player"}); err != nil {
+		t.Fatalf("common data after a deletion returned %v", err)
+	}
+}
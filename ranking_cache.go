@@ -0,0 +1,376 @@
+package nexproto
+
+import (
+	"sync"
+	"time"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+// rankingSnapshotKey identifies a cached top ranking. Offset and length are applied to the snapshot when it is served
+type rankingSnapshotKey struct {
+	category         uint32
+	orderCalculation uint8
+	groupIndex       uint8
+	groupNum         uint8
+}
+
+// rankingSnapshot is a cached top ranking
+type rankingSnapshot struct {
+	result    *RankingCachedResult
+	expiresAt time.Time
+	requested bool
+}
+
+// RankingCache serves GetCachedTopXRanking(s) from snapshots of the top scores of a Leaderboard. A snapshot is built
+// the first time a ranking is requested and kept until it expires. Expired snapshots are served until their replacement
+// is built in the background, so requests for many categories at once only wait on the ones never built before,
+// and at most four snapshots are built at the same time. A request is served by at most four workers and
+// GetCachedTopXRankings refuses requests for more than MaxCategories categories.
+// Categories without scores are answered without a snapshot, and the snapshots of a category are dropped
+// when its period is reset
+type RankingCache struct {
+	mutex         sync.Mutex
+	leaderboard   *Leaderboard
+	snapshots     map[rankingSnapshotKey]*rankingSnapshot
+	generations   map[uint32]uint64 // Invalidate calls per category, so snapshots built across one are not stored
+	building      map[rankingSnapshotKey]chan struct{}
+	buildSlots    chan struct{}
+	stopRefresh   chan struct{}
+	clock         func() time.Time
+	MaxLength     uint8         // scores kept per snapshot
+	Lifetime      time.Duration // time a snapshot is valid for
+	MaxCategories int           // categories a GetCachedTopXRankings call may request, 0 for no limit
+}
+
+// CachedTopX returns the snapshot of the top scores of category under the calculation and group filter of orderParam,
+// paged by its offset and length. The returned result shares its rank data with the cache and must not be modified
+func (cache *RankingCache) CachedTopX(category uint32, orderParam *RankingOrderParam) *RankingCachedResult {
+	key, ok := newRankingSnapshotKey(category, orderParam)
+	if !ok || !cache.leaderboard.hasScores(category) {
+		return cache.newEmptyResult()
+	}
+
+	snapshot := cache.snapshot(key)
+
+	return pageRankingCachedResult(snapshot.result, int(orderParam.Offset), int(orderParam.Length))
+}
+
+// CachedTopXList returns the snapshot of each category of categories under the matching RankingOrderParam of orderParams.
+// The categories are served by as many workers as snapshots can be built at the same time
+func (cache *RankingCache) CachedTopXList(categories []uint32, orderParams []*RankingOrderParam) []*RankingCachedResult {
+	results := make([]*RankingCachedResult, len(categories))
+
+	workers := cap(cache.buildSlots)
+	if workers > len(categories) {
+		workers = len(categories)
+	}
+
+	indexes := make(chan int)
+
+	var wait sync.WaitGroup
+
+	for worker := 0; worker < workers; worker++ {
+		wait.Add(1)
+
+		go func() {
+			defer wait.Done()
+
+			for i := range indexes {
+				results[i] = cache.CachedTopX(categories[i], orderParams[i])
+			}
+		}()
+	}
+
+	for i := range categories {
+		indexes <- i
+	}
+
+	close(indexes)
+	wait.Wait()
+
+	return results
+}
+
+// Refresh rebuilds the expired snapshots requested since they were built and drops the other expired snapshots
+func (cache *RankingCache) Refresh() {
+	now := cache.clock()
+	stale := make([]rankingSnapshotKey, 0)
+
+	cache.mutex.Lock()
+
+	for key, snapshot := range cache.snapshots {
+		if now.Before(snapshot.expiresAt) {
+			continue
+		}
+
+		if snapshot.requested {
+			stale = append(stale, key)
+		} else {
+			delete(cache.snapshots, key)
+		}
+	}
+
+	cache.mutex.Unlock()
+
+	for _, key := range stale {
+		<-cache.build(key)
+	}
+}
+
+// Invalidate drops every snapshot of category, so the next request sees the current scores
+func (cache *RankingCache) Invalidate(category uint32) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.generations[category]++
+
+	for key := range cache.snapshots {
+		if key.category == category {
+			delete(cache.snapshots, key)
+		}
+	}
+}
+
+// StartRefresh calls Refresh every interval until StopRefresh is called
+func (cache *RankingCache) StartRefresh(interval time.Duration) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.stopRefresh != nil {
+		return
+	}
+
+	stop := make(chan struct{})
+	cache.stopRefresh = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				cache.Refresh()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopRefresh stops the refresh started by StartRefresh
+func (cache *RankingCache) StopRefresh() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.stopRefresh != nil {
+		close(cache.stopRefresh)
+		cache.stopRefresh = nil
+	}
+}
+
+// HandleGetCachedTopXRanking is the default RankingProtocol::GetCachedTopXRanking handler
+func (cache *RankingCache) HandleGetCachedTopXRanking(err error, client *nex.Client, callID uint32, category uint32, orderParam *RankingOrderParam) {
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	respondRankingStructure(client, callID, RankingMethodGetCachedTopXRanking, cache.CachedTopX(category, orderParam))
+}
+
+// HandleGetCachedTopXRankings is the default RankingProtocol::GetCachedTopXRankings handler
+func (cache *RankingCache) HandleGetCachedTopXRankings(err error, client *nex.Client, callID uint32, categories []uint32, orderParams []*RankingOrderParam) {
+	if err != nil || len(categories) != len(orderParams) {
+		respondError(client, RankingProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	if cache.MaxCategories != 0 && len(categories) > cache.MaxCategories {
+		respondError(client, RankingProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	cachedResults := cache.CachedTopXList(categories, orderParams)
+
+	responseStream := nex.NewStreamOut(client.Server())
+	responseStream.WriteUInt32LE(uint32(len(cachedResults)))
+
+	for _, cachedResult := range cachedResults {
+		responseStream.WriteStructure(cachedResult)
+	}
+
+	respondSuccess(client, RankingProtocolID, RankingMethodGetCachedTopXRankings, callID, responseStream.Bytes())
+}
+
+// snapshot returns the snapshot of key, building it if missing and scheduling its rebuild if expired
+func (cache *RankingCache) snapshot(key rankingSnapshotKey) *rankingSnapshot {
+	cache.mutex.Lock()
+
+	snapshot, ok := cache.snapshots[key]
+	if ok {
+		snapshot.requested = true
+		expired := !cache.clock().Before(snapshot.expiresAt)
+
+		cache.mutex.Unlock()
+
+		if expired {
+			cache.build(key)
+		}
+
+		return snapshot
+	}
+
+	cache.mutex.Unlock()
+
+	<-cache.build(key)
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	snapshot, ok = cache.snapshots[key]
+	if !ok {
+		// dropped by Invalidate while it was being built, serve it anyway
+		return cache.newSnapshot(key)
+	}
+
+	return snapshot
+}
+
+// build starts building the snapshot of key unless it is already being built, and returns a channel closed once it is stored
+func (cache *RankingCache) build(key rankingSnapshotKey) chan struct{} {
+	cache.mutex.Lock()
+
+	done, ok := cache.building[key]
+	if ok {
+		cache.mutex.Unlock()
+		return done
+	}
+
+	done = make(chan struct{})
+	cache.building[key] = done
+	generation := cache.generations[key.category]
+
+	cache.mutex.Unlock()
+
+	go func() {
+		cache.buildSlots <- struct{}{}
+		snapshot := cache.newSnapshot(key)
+		<-cache.buildSlots
+
+		cache.mutex.Lock()
+		if cache.generations[key.category] == generation {
+			cache.snapshots[key] = snapshot
+		}
+		delete(cache.building, key)
+		cache.mutex.Unlock()
+
+		close(done)
+	}()
+
+	return done
+}
+
+// newSnapshot builds the snapshot of key from the leaderboard
+func (cache *RankingCache) newSnapshot(key rankingSnapshotKey) *rankingSnapshot {
+	orderParam := &RankingOrderParam{
+		OrderCalculation: key.orderCalculation,
+		GroupIndex:       key.groupIndex,
+		GroupNum:         key.groupNum,
+		Length:           cache.MaxLength,
+	}
+
+	result, err := cache.leaderboard.Ranking(RankingModeGlobal, key.category, orderParam, 0, 0)
+	if err != nil {
+		result = NewRankingResult()
+	}
+
+	now := cache.clock()
+	expiresAt := now.Add(cache.Lifetime)
+
+	return &rankingSnapshot{
+		result: &RankingCachedResult{
+			RankingResult: *result,
+			CreatedTime:   newDateTime(now),
+			ExpiredTime:   newDateTime(expiresAt),
+			MaxLength:     cache.MaxLength,
+		},
+		expiresAt: expiresAt,
+	}
+}
+
+// newEmptyResult returns the cached result of a ranking without scores
+func (cache *RankingCache) newEmptyResult() *RankingCachedResult {
+	now := cache.clock()
+
+	return &RankingCachedResult{
+		RankingResult: *NewRankingResult(),
+		CreatedTime:   newDateTime(now),
+		ExpiredTime:   newDateTime(now.Add(cache.Lifetime)),
+		MaxLength:     cache.MaxLength,
+	}
+}
+
+// newRankingSnapshotKey returns the key of the snapshot serving category under orderParam, or false if no score can
+// pass its group filter. Order parameters ranking the same scores the same way share a key: unknown calculations
+// are the standard one, the group number is ignored without a group filter and the time scope is not used
+func newRankingSnapshotKey(category uint32, orderParam *RankingOrderParam) (rankingSnapshotKey, bool) {
+	key := rankingSnapshotKey{
+		category:         category,
+		orderCalculation: orderParam.OrderCalculation,
+		groupIndex:       orderParam.GroupIndex,
+		groupNum:         orderParam.GroupNum,
+	}
+
+	if key.orderCalculation != RankingOrderCalculationOrdinal && key.orderCalculation != RankingOrderCalculationDense {
+		key.orderCalculation = RankingOrderCalculationStandard
+	}
+
+	if key.groupIndex == RankingGroupIndexNone {
+		key.groupNum = 0
+	} else if key.groupIndex >= rankingGroupCount {
+		return key, false
+	}
+
+	return key, true
+}
+
+// pageRankingCachedResult returns the part of cachedResult starting at the 0-based position offset, holding up to length scores
+func pageRankingCachedResult(cachedResult *RankingCachedResult, offset int, length int) *RankingCachedResult {
+	paged := *cachedResult
+	rankData := cachedResult.RankData
+
+	if offset > len(rankData) {
+		offset = len(rankData)
+	}
+
+	end := len(rankData)
+	if offset+length < end {
+		end = offset + length
+	}
+
+	paged.RankData = rankData[offset:end]
+
+	return &paged
+}
+
+// NewRankingCache returns a new RankingCache over leaderboard, keeping the top 100 scores of each snapshot for 5 minutes,
+// building up to 4 snapshots at the same time and serving up to 100 categories per GetCachedTopXRankings call
+func NewRankingCache(leaderboard *Leaderboard) *RankingCache {
+	cache := &RankingCache{
+		leaderboard:   leaderboard,
+		snapshots:     make(map[rankingSnapshotKey]*rankingSnapshot),
+		generations:   make(map[uint32]uint64),
+		building:      make(map[rankingSnapshotKey]chan struct{}),
+		buildSlots:    make(chan struct{}, 4),
+		clock:         time.Now,
+		MaxLength:     100,
+		Lifetime:      5 * time.Minute,
+		MaxCategories: 100,
+	}
+
+	leaderboard.onRotate(cache.Invalidate)
+
+	return cache
+}
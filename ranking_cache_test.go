@@ -0,0 +1,160 @@
+package nexproto
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestRankingCache returns a RankingCache over leaderboard reading the time from now
+func newTestRankingCache(leaderboard *Leaderboard, now *time.Time) *RankingCache {
+	cache := NewRankingCache(leaderboard)
+	cache.clock = func() time.Time {
+		return *now
+	}
+
+	return cache
+}
+
+// snapshotCount returns the number of snapshots held by cache
+func (cache *RankingCache) snapshotCount() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	return len(cache.snapshots)
+}
+
+func TestRankingCacheServesSnapshots(t *testing.T) {
+	leaderboard := NewLeaderboard()
+
+	for pid := uint32(1); pid <= 50; pid++ {
+		uploadTestScore(t, leaderboard, pid, pid)
+	}
+
+	now := time.Now()
+	cache := newTestRankingCache(leaderboard, &now)
+	cache.MaxLength = 10
+
+	orderParam := NewRankingOrderParam()
+	orderParam.Offset = 2
+	orderParam.Length = 5
+
+	result := cache.CachedTopX(1, orderParam)
+	if len(result.RankData) != 5 || result.RankData[0].PrincipalID != 48 || result.TotalCount != 50 || result.MaxLength != 10 {
+		t.Fatalf("first request returned %+v", result)
+	}
+
+	uploadTestScore(t, leaderboard, 100, 1000)
+
+	if result := cache.CachedTopX(1, orderParam); result.RankData[0].PrincipalID != 48 {
+		t.Fatal("a request before the snapshot expired saw a new score")
+	}
+
+	now = now.Add(cache.Lifetime)
+
+	if result := cache.CachedTopX(1, orderParam); result.RankData[0].PrincipalID != 48 {
+		t.Fatal("an expired snapshot was not served while being rebuilt")
+	}
+
+	key, _ := newRankingSnapshotKey(1, orderParam)
+	<-cache.build(key)
+
+	if result := cache.CachedTopX(1, orderParam); result.RankData[0].PrincipalID != 49 || result.TotalCount != 51 {
+		t.Fatalf("rebuilt snapshot holds %+v", result)
+	}
+}
+
+func TestRankingCacheSkipsEmptyCategories(t *testing.T) {
+	leaderboard := NewLeaderboard()
+	uploadTestScore(t, leaderboard, 1, 10)
+
+	now := time.Now()
+	cache := newTestRankingCache(leaderboard, &now)
+
+	orderParam := NewRankingOrderParam()
+	orderParam.Length = 10
+
+	results := cache.CachedTopXList([]uint32{2, 3, 4}, []*RankingOrderParam{orderParam, orderParam, orderParam})
+
+	for _, result := range results {
+		if len(result.RankData) != 0 || result.TotalCount != 0 {
+			t.Fatalf("category without scores returned %+v", result)
+		}
+	}
+
+	if count := cache.snapshotCount(); count != 0 {
+		t.Fatalf("%d snapshots built for categories without scores", count)
+	}
+}
+
+func TestRankingCacheKeyNormalization(t *testing.T) {
+	leaderboard := NewLeaderboard()
+	uploadTestScore(t, leaderboard, 1, 10)
+
+	now := time.Now()
+	cache := newTestRankingCache(leaderboard, &now)
+
+	tests := []struct {
+		name             string
+		orderCalculation uint8
+		groupIndex       uint8
+		groupNum         uint8
+		timeScope        uint8
+	}{
+		{"standard", RankingOrderCalculationStandard, RankingGroupIndexNone, 0, 0},
+		{"unknown calculation", 9, RankingGroupIndexNone, 0, 0},
+		{"group number without a group filter", RankingOrderCalculationStandard, RankingGroupIndexNone, 5, 0},
+		{"time scope", RankingOrderCalculationStandard, RankingGroupIndexNone, 0, 2},
+	}
+
+	for _, test := range tests {
+		orderParam := NewRankingOrderParam()
+		orderParam.OrderCalculation = test.orderCalculation
+		orderParam.GroupIndex = test.groupIndex
+		orderParam.GroupNum = test.groupNum
+		orderParam.TimeScope = test.timeScope
+		orderParam.Length = 10
+
+		if result := cache.CachedTopX(1, orderParam); len(result.RankData) != 1 {
+			t.Fatalf("%s: returned %+v", test.name, result)
+		}
+
+		if count := cache.snapshotCount(); count != 1 {
+			t.Fatalf("%s: %d snapshots held", test.name, count)
+		}
+	}
+
+	orderParam := NewRankingOrderParam()
+	orderParam.GroupIndex = rankingGroupCount
+	orderParam.Length = 10
+
+	if result := cache.CachedTopX(1, orderParam); len(result.RankData) != 0 || cache.snapshotCount() != 1 {
+		t.Fatalf("out of range group filter returned %+v with %d snapshots", result, cache.snapshotCount())
+	}
+}
+
+func TestRankingCacheInvalidatedOnRotation(t *testing.T) {
+	leaderboard := NewLeaderboard()
+	leaderboard.SetCategoryResetSchedule(1, RankingResetSchedule{Period: RankingResetDaily})
+
+	uploadTestScore(t, leaderboard, 1, 10)
+
+	now := time.Now()
+	cache := newTestRankingCache(leaderboard, &now)
+
+	orderParam := NewRankingOrderParam()
+	orderParam.Length = 10
+
+	if result := cache.CachedTopX(1, orderParam); len(result.RankData) != 1 {
+		t.Fatalf("first request returned %+v", result)
+	}
+
+	rotateTestPeriods(leaderboard, now.Add(24*time.Hour))
+
+	if count := cache.snapshotCount(); count != 0 {
+		t.Fatalf("%d snapshots kept after the period reset", count)
+	}
+
+	if result := cache.CachedTopX(1, orderParam); len(result.RankData) != 0 {
+		t.Fatalf("request after the period reset returned %+v", result)
+	}
+}
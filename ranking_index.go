@@ -0,0 +1,290 @@
+package nexproto
+
+import (
+	"math/rand"
+)
+
+// rankingSkipListMaxLevel bounds the height of a rankingSkipList, enough for billions of scores
+const rankingSkipListMaxLevel = 32
+
+// rankingKey orders the scores of a ranking index. Lower keys rank first, ties on sortKey
+// being broken by upload sequence so every score has a distinct position
+type rankingKey struct {
+	sortKey  uint32
+	sequence uint64
+}
+
+// less returns whether key ranks before other
+func (key rankingKey) less(other rankingKey) bool {
+	return key.sortKey < other.sortKey || (key.sortKey == other.sortKey && key.sequence < other.sequence)
+}
+
+// rankingSkipListLevel is a forward link of a rankingSkipListNode. span counts the nodes the link skips over
+type rankingSkipListLevel struct {
+	next *rankingSkipListNode
+	span int
+}
+
+// rankingSkipListNode is a node of a rankingSkipList
+type rankingSkipListNode struct {
+	key     rankingKey
+	entry   *rankingEntry
+	forward []rankingSkipListLevel
+}
+
+// rankingSkipList is a skip list with link spans, giving logarithmic insertion, removal,
+// position lookups and access by position
+type rankingSkipList struct {
+	head   *rankingSkipListNode
+	level  int
+	length int
+}
+
+// insert adds entry at key. key must not already be in the list
+func (list *rankingSkipList) insert(key rankingKey, entry *rankingEntry) {
+	var update [rankingSkipListMaxLevel]*rankingSkipListNode
+	var rank [rankingSkipListMaxLevel]int
+
+	node := list.head
+
+	for i := list.level - 1; i >= 0; i-- {
+		if i != list.level-1 {
+			rank[i] = rank[i+1]
+		}
+
+		for node.forward[i].next != nil && node.forward[i].next.key.less(key) {
+			rank[i] += node.forward[i].span
+			node = node.forward[i].next
+		}
+
+		update[i] = node
+	}
+
+	level := randomRankingSkipListLevel()
+
+	if level > list.level {
+		for i := list.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = list.head
+			update[i].forward[i].span = list.length
+		}
+
+		list.level = level
+	}
+
+	inserted := &rankingSkipListNode{
+		key:     key,
+		entry:   entry,
+		forward: make([]rankingSkipListLevel, level),
+	}
+
+	for i := 0; i < level; i++ {
+		inserted.forward[i].next = update[i].forward[i].next
+		update[i].forward[i].next = inserted
+
+		inserted.forward[i].span = update[i].forward[i].span - (rank[0] - rank[i])
+		update[i].forward[i].span = rank[0] - rank[i] + 1
+	}
+
+	for i := level; i < list.level; i++ {
+		update[i].forward[i].span++
+	}
+
+	list.length++
+}
+
+// remove deletes the node at key and returns whether it was found
+func (list *rankingSkipList) remove(key rankingKey) bool {
+	var update [rankingSkipListMaxLevel]*rankingSkipListNode
+
+	node := list.head
+
+	for i := list.level - 1; i >= 0; i-- {
+		for node.forward[i].next != nil && node.forward[i].next.key.less(key) {
+			node = node.forward[i].next
+		}
+
+		update[i] = node
+	}
+
+	removed := node.forward[0].next
+	if removed == nil || removed.key != key {
+		return false
+	}
+
+	for i := 0; i < list.level; i++ {
+		if update[i].forward[i].next == removed {
+			update[i].forward[i].span += removed.forward[i].span - 1
+			update[i].forward[i].next = removed.forward[i].next
+		} else {
+			update[i].forward[i].span--
+		}
+	}
+
+	for list.level > 1 && list.head.forward[list.level-1].next == nil {
+		list.level--
+	}
+
+	list.length--
+
+	return true
+}
+
+// countLess returns the number of nodes ranking before key
+func (list *rankingSkipList) countLess(key rankingKey) int {
+	count := 0
+	node := list.head
+
+	for i := list.level - 1; i >= 0; i-- {
+		for node.forward[i].next != nil && node.forward[i].next.key.less(key) {
+			count += node.forward[i].span
+			node = node.forward[i].next
+		}
+	}
+
+	return count
+}
+
+// at returns the node at the 0-based position, or nil if the list is shorter
+func (list *rankingSkipList) at(position int) *rankingSkipListNode {
+	if position < 0 || position >= list.length {
+		return nil
+	}
+
+	target := position + 1
+	traversed := 0
+	node := list.head
+
+	for i := list.level - 1; i >= 0; i-- {
+		for node.forward[i].next != nil && traversed+node.forward[i].span <= target {
+			traversed += node.forward[i].span
+			node = node.forward[i].next
+		}
+
+		if traversed == target {
+			return node
+		}
+	}
+
+	return nil
+}
+
+// newRankingSkipList returns a new empty rankingSkipList
+func newRankingSkipList() *rankingSkipList {
+	return &rankingSkipList{
+		head: &rankingSkipListNode{
+			forward: make([]rankingSkipListLevel, rankingSkipListMaxLevel),
+		},
+		level: 1,
+	}
+}
+
+// randomRankingSkipListLevel returns the height of a new node, each level being four times less likely than the previous
+func randomRankingSkipListLevel() int {
+	level := 1
+
+	for level < rankingSkipListMaxLevel && rand.Intn(4) == 0 {
+		level++
+	}
+
+	return level
+}
+
+// rankingIndex orders a set of scores, counting the distinct sort keys so the three order calculations
+// can all be answered in logarithmic time. The sum of the scores is kept for GetStats
+type rankingIndex struct {
+	entries  *rankingSkipList
+	distinct *rankingSkipList
+	counts   map[uint32]int
+	total    uint64
+}
+
+// add inserts entry into the index
+func (index *rankingIndex) add(entry *rankingEntry) {
+	index.entries.insert(entry.key, entry)
+
+	if index.counts[entry.key.sortKey] == 0 {
+		index.distinct.insert(rankingKey{sortKey: entry.key.sortKey}, nil)
+	}
+
+	index.counts[entry.key.sortKey]++
+	index.total += uint64(entry.scoreData.Score)
+}
+
+// remove deletes entry from the index
+func (index *rankingIndex) remove(entry *rankingEntry) {
+	if !index.entries.remove(entry.key) {
+		return
+	}
+
+	index.counts[entry.key.sortKey]--
+	index.total -= uint64(entry.scoreData.Score)
+
+	if index.counts[entry.key.sortKey] == 0 {
+		delete(index.counts, entry.key.sortKey)
+		index.distinct.remove(rankingKey{sortKey: entry.key.sortKey})
+	}
+}
+
+// length returns the number of scores in the index
+func (index *rankingIndex) length() int {
+	return index.entries.length
+}
+
+// position returns the 0-based position of key in the index
+func (index *rankingIndex) position(key rankingKey) int {
+	return index.entries.countLess(key)
+}
+
+// order returns the order of a score with key under orderCalculation. The key need not be in the index,
+// in which case the order is the one the score would get once uploaded
+func (index *rankingIndex) order(key rankingKey, orderCalculation uint8) uint32 {
+	switch orderCalculation {
+	case RankingOrderCalculationOrdinal:
+		return uint32(index.entries.countLess(key) + 1)
+	case RankingOrderCalculationDense:
+		return uint32(index.distinct.countLess(rankingKey{sortKey: key.sortKey}) + 1)
+	default:
+		return uint32(index.entries.countLess(rankingKey{sortKey: key.sortKey}) + 1)
+	}
+}
+
+// first returns the entry ranking first, or nil if the index is empty
+func (index *rankingIndex) first() *rankingEntry {
+	node := index.entries.head.forward[0].next
+	if node == nil {
+		return nil
+	}
+
+	return node.entry
+}
+
+// last returns the entry ranking last, or nil if the index is empty
+func (index *rankingIndex) last() *rankingEntry {
+	node := index.entries.at(index.entries.length - 1)
+	if node == nil {
+		return nil
+	}
+
+	return node.entry
+}
+
+// page returns up to length entries starting at the 0-based position offset, in ranking order
+func (index *rankingIndex) page(offset int, length int) []*rankingEntry {
+	entries := make([]*rankingEntry, 0)
+
+	for node := index.entries.at(offset); node != nil && len(entries) < length; node = node.forward[0].next {
+		entries = append(entries, node.entry)
+	}
+
+	return entries
+}
+
+// newRankingIndex returns a new empty rankingIndex
+func newRankingIndex() *rankingIndex {
+	return &rankingIndex{
+		entries:  newRankingSkipList(),
+		distinct: newRankingSkipList(),
+		counts:   make(map[uint32]int),
+	}
+}
@@ -0,0 +1,175 @@
+package nexproto
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// newTestRankingEntry returns a rankingEntry with the given sort key and upload sequence
+func newTestRankingEntry(sortKey uint32, sequence uint64) *rankingEntry {
+	scoreData := NewRankingScoreData()
+	scoreData.Score = sortKey
+
+	return &rankingEntry{
+		key:       rankingKey{sortKey, sequence},
+		scoreData: scoreData,
+	}
+}
+
+// checkRankingSkipList compares every position of list with the sorted keys of want
+func checkRankingSkipList(t *testing.T, list *rankingSkipList, want []rankingKey) {
+	t.Helper()
+
+	if list.length != len(want) {
+		t.Fatalf("length is %d, want %d", list.length, len(want))
+	}
+
+	for position, key := range want {
+		node := list.at(position)
+		if node == nil || node.key != key {
+			t.Fatalf("at(%d) is %v, want %v", position, node, key)
+		}
+
+		if count := list.countLess(key); count != position {
+			t.Fatalf("countLess(%v) is %d, want %d", key, count, position)
+		}
+	}
+
+	if list.at(-1) != nil || list.at(len(want)) != nil {
+		t.Fatal("at out of range returned a node")
+	}
+}
+
+func TestRankingSkipListInsertRemove(t *testing.T) {
+	list := newRankingSkipList()
+	random := rand.New(rand.NewSource(1))
+
+	keys := make([]rankingKey, 0)
+
+	for sequence := uint64(0); sequence < 2000; sequence++ {
+		key := rankingKey{uint32(random.Intn(100)), sequence}
+
+		list.insert(key, nil)
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].less(keys[j])
+	})
+
+	checkRankingSkipList(t, list, keys)
+
+	random.Shuffle(len(keys), func(i, j int) {
+		keys[i], keys[j] = keys[j], keys[i]
+	})
+
+	for _, key := range keys[:1200] {
+		if !list.remove(key) {
+			t.Fatalf("remove(%v) did not find the key", key)
+		}
+	}
+
+	if list.remove(keys[0]) {
+		t.Fatal("remove of a removed key succeeded")
+	}
+
+	keys = keys[1200:]
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].less(keys[j])
+	})
+
+	checkRankingSkipList(t, list, keys)
+}
+
+func TestRankingSkipListCountLessMissingKey(t *testing.T) {
+	list := newRankingSkipList()
+
+	for sequence, sortKey := range []uint32{10, 20, 20, 30} {
+		list.insert(rankingKey{sortKey, uint64(sequence)}, nil)
+	}
+
+	tests := []struct {
+		key  rankingKey
+		want int
+	}{
+		{rankingKey{5, 0}, 0},
+		{rankingKey{20, 0}, 1},
+		{rankingKey{25, 0}, 3},
+		{rankingKey{40, 0}, 4},
+	}
+
+	for _, test := range tests {
+		if count := list.countLess(test.key); count != test.want {
+			t.Errorf("countLess(%v) is %d, want %d", test.key, count, test.want)
+		}
+	}
+}
+
+func TestRankingIndexOrder(t *testing.T) {
+	index := newRankingIndex()
+
+	for sequence, sortKey := range []uint32{10, 20, 20, 30} {
+		index.add(newTestRankingEntry(sortKey, uint64(sequence)))
+	}
+
+	tests := []struct {
+		name             string
+		orderCalculation uint8
+		want             []uint32
+	}{
+		{"standard", RankingOrderCalculationStandard, []uint32{1, 2, 2, 4}},
+		{"ordinal", RankingOrderCalculationOrdinal, []uint32{1, 2, 3, 4}},
+		{"dense", RankingOrderCalculationDense, []uint32{1, 2, 2, 3}},
+	}
+
+	for _, test := range tests {
+		for position, entry := range index.page(0, index.length()) {
+			if order := index.order(entry.key, test.orderCalculation); order != test.want[position] {
+				t.Errorf("%s: order at %d is %d, want %d", test.name, position, order, test.want[position])
+			}
+		}
+
+		orders := rankingOrders(index.page(1, 3), test.orderCalculation, 1, test.want[1])
+		for i, order := range orders {
+			if order != test.want[i+1] {
+				t.Errorf("%s: rankingOrders at %d is %d, want %d", test.name, i+1, order, test.want[i+1])
+			}
+		}
+	}
+}
+
+func TestRankingIndexRemove(t *testing.T) {
+	index := newRankingIndex()
+
+	entries := make([]*rankingEntry, 0)
+
+	for sequence, sortKey := range []uint32{10, 20, 20, 30} {
+		entry := newTestRankingEntry(sortKey, uint64(sequence))
+
+		index.add(entry)
+		entries = append(entries, entry)
+	}
+
+	index.remove(entries[1])
+	index.remove(entries[1])
+
+	if index.length() != 3 || index.total != 60 || index.distinct.length != 3 {
+		t.Fatalf("length %d, total %d, distinct %d after removing one tied score", index.length(), index.total, index.distinct.length)
+	}
+
+	index.remove(entries[2])
+
+	if index.distinct.length != 2 {
+		t.Fatalf("distinct is %d after removing both tied scores, want 2", index.distinct.length)
+	}
+
+	if order := index.order(entries[3].key, RankingOrderCalculationDense); order != 2 {
+		t.Fatalf("dense order is %d, want 2", order)
+	}
+
+	if index.first() != entries[0] || index.last() != entries[3] {
+		t.Fatal("first or last entry is wrong")
+	}
+}
@@ -0,0 +1,319 @@
+package nexproto
+
+import (
+	"errors"
+	"time"
+)
+
+// Values of RankingResetSchedule.Period
+const (
+	// RankingResetNever keeps the scores of a category until they are deleted
+	RankingResetNever = 0
+
+	// RankingResetDaily starts a category over every day
+	RankingResetDaily = 1
+
+	// RankingResetWeekly starts a category over every week
+	RankingResetWeekly = 2
+
+	// RankingResetMonthly starts a category over every month
+	RankingResetMonthly = 3
+
+	// RankingResetCustom starts a category over every RankingResetSchedule.Interval
+	RankingResetCustom = 4
+)
+
+// ErrRankingPeriodNotFound is returned when a category has no archive for the requested period
+var ErrRankingPeriodNotFound = errors.New("ranking period not found")
+
+// rankingResetAnchor is the start of a period when a RankingResetSchedule has no anchor: Monday 1 January 2001, midnight UTC
+var rankingResetAnchor = time.Date(2001, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// RankingResetSchedule sets when a category starts over. Periods follow each other from Anchor, which defaults to
+// a Monday at midnight UTC so daily and weekly periods start at midnight and weeks on Mondays.
+// Monthly periods start on the day of the month of Anchor, which should not be after the 28th
+type RankingResetSchedule struct {
+	Period   uint8
+	Anchor   time.Time
+	Interval time.Duration // length of RankingResetCustom periods
+}
+
+// bounds returns the start and end of the period holding now. Both are zero if the schedule never resets
+func (schedule *RankingResetSchedule) bounds(now time.Time) (time.Time, time.Time) {
+	anchor := schedule.Anchor
+	if anchor.IsZero() {
+		anchor = rankingResetAnchor
+	}
+
+	var interval time.Duration
+
+	switch schedule.Period {
+	case RankingResetDaily:
+		interval = 24 * time.Hour
+	case RankingResetWeekly:
+		interval = 7 * 24 * time.Hour
+	case RankingResetCustom:
+		interval = schedule.Interval
+	case RankingResetMonthly:
+		months := (now.Year()-anchor.Year())*12 + int(now.Month()) - int(anchor.Month())
+
+		for anchor.AddDate(0, months, 0).After(now) {
+			months--
+		}
+
+		for !anchor.AddDate(0, months+1, 0).After(now) {
+			months++
+		}
+
+		return anchor.AddDate(0, months, 0), anchor.AddDate(0, months+1, 0)
+	}
+
+	if interval <= 0 {
+		return time.Time{}, time.Time{}
+	}
+
+	elapsed := now.Sub(anchor)
+	periods := elapsed / interval
+
+	if elapsed < 0 && elapsed%interval != 0 {
+		periods--
+	}
+
+	start := anchor.Add(periods * interval)
+
+	return start, start.Add(interval)
+}
+
+// RankingPeriod describes a past period of a category
+type RankingPeriod struct {
+	Category uint32
+	Start    time.Time
+	End      time.Time
+	Count    int // scores ranked when the period ended
+}
+
+// SetCategoryResetSchedule makes category start over as set by schedule. The scores already uploaded to category
+// belong to the period holding the current time
+func (leaderboard *Leaderboard) SetCategoryResetSchedule(category uint32, schedule RankingResetSchedule) {
+	leaderboard.rotateIfDue()
+
+	leaderboard.mutex.Lock()
+	defer leaderboard.mutex.Unlock()
+
+	leaderboard.schedules[category] = &schedule
+
+	if rankingCategory, ok := leaderboard.categories[category]; ok {
+		rankingCategory.endsAt = time.Time{}
+		leaderboard.schedulePeriod(category, rankingCategory, time.Now())
+	}
+}
+
+// RotatePeriods archives the categories whose period has ended and starts them over. Periods are also rotated
+// when the Leaderboard is used after the end of a period, so calling it is only needed to archive periods on time
+func (leaderboard *Leaderboard) RotatePeriods() {
+	now := time.Now()
+
+	leaderboard.mutex.Lock()
+	events := leaderboard.rotate(now)
+	leaderboard.mutex.Unlock()
+
+	fireEvents(events)
+}
+
+// OnPeriodReset sets the handler called when a category starts over, with the period which just ended
+func (leaderboard *Leaderboard) OnPeriodReset(handler func(category uint32, period *RankingPeriod)) {
+	leaderboard.PeriodResetHandler = handler
+}
+
+// Periods returns the archived periods of category, the most recent first. The period passed to PeriodRanking
+// and PeriodStats is the position in this list plus one, 0 standing for the current period
+func (leaderboard *Leaderboard) Periods(category uint32) []*RankingPeriod {
+	leaderboard.rotateIfDue()
+
+	leaderboard.mutex.RLock()
+	defer leaderboard.mutex.RUnlock()
+
+	periods := make([]*RankingPeriod, 0, len(leaderboard.archives[category]))
+
+	for _, archive := range leaderboard.archives[category] {
+		periods = append(periods, rankingCategoryPeriod(category, archive))
+	}
+
+	return periods
+}
+
+// PeriodCount returns the number of periods of category which have ended, including those no longer archived
+func (leaderboard *Leaderboard) PeriodCount(category uint32) int {
+	leaderboard.rotateIfDue()
+
+	leaderboard.mutex.RLock()
+	defer leaderboard.mutex.RUnlock()
+
+	return leaderboard.periodCounts[category]
+}
+
+// PeriodRanking returns the part of the ranking of a period of category selected by rankingMode and orderParam,
+// as Ranking does for the current period. period 0 is the current period, 1 the previous one and so on
+func (leaderboard *Leaderboard) PeriodRanking(category uint32, period int, rankingMode uint8, orderParam *RankingOrderParam, pid uint32, uniqueID uint64) (*RankingResult, error) {
+	leaderboard.rotateIfDue()
+
+	leaderboard.mutex.RLock()
+	defer leaderboard.mutex.RUnlock()
+
+	rankingCategory, err := leaderboard.period(category, period)
+	if err != nil {
+		return nil, err
+	}
+
+	return leaderboard.ranking(rankingCategory, rankingMode, orderParam, pid, uniqueID)
+}
+
+// PeriodRankingByPIDList returns the scores under uniqueID of the PIDs of pids in a period of category,
+// as RankingByPIDList does for the current period
+func (leaderboard *Leaderboard) PeriodRankingByPIDList(pids []uint32, category uint32, period int, orderParam *RankingOrderParam, uniqueID uint64) (*RankingResult, error) {
+	leaderboard.rotateIfDue()
+
+	leaderboard.mutex.RLock()
+	defer leaderboard.mutex.RUnlock()
+
+	rankingCategory, err := leaderboard.period(category, period)
+	if err != nil {
+		return nil, err
+	}
+
+	return leaderboard.rankingByPIDList(rankingCategory, pids, orderParam, uniqueID), nil
+}
+
+// PeriodStats returns the statistics of a period of category, as Stats does for the current period
+func (leaderboard *Leaderboard) PeriodStats(category uint32, period int, orderParam *RankingOrderParam, flags uint32) (*RankingStats, error) {
+	leaderboard.rotateIfDue()
+
+	leaderboard.mutex.RLock()
+	defer leaderboard.mutex.RUnlock()
+
+	rankingCategory, err := leaderboard.period(category, period)
+	if err != nil {
+		return nil, err
+	}
+
+	return rankingCategoryStats(rankingCategory, orderParam, flags), nil
+}
+
+// period returns the scores of a period of category, nil for a current period without scores. The caller must hold the lock
+func (leaderboard *Leaderboard) period(category uint32, period int) (*rankingCategory, error) {
+	if period == 0 {
+		return leaderboard.categories[category], nil
+	}
+
+	archives := leaderboard.archives[category]
+	if period < 0 || period > len(archives) {
+		return nil, ErrRankingPeriodNotFound
+	}
+
+	return archives[period-1], nil
+}
+
+// schedulePeriod sets the period of rankingCategory from the schedule of category, if any. The caller must hold the write lock
+func (leaderboard *Leaderboard) schedulePeriod(category uint32, rankingCategory *rankingCategory, now time.Time) {
+	schedule, ok := leaderboard.schedules[category]
+	if !ok {
+		return
+	}
+
+	start, end := schedule.bounds(now)
+	if end.IsZero() {
+		return
+	}
+
+	rankingCategory.since = start
+	rankingCategory.endsAt = end
+
+	if leaderboard.nextRotation.IsZero() || end.Before(leaderboard.nextRotation) {
+		leaderboard.nextRotation = end
+	}
+}
+
+// rotateIfDue rotates the periods if one of them has ended
+func (leaderboard *Leaderboard) rotateIfDue() {
+	leaderboard.mutex.RLock()
+	nextRotation := leaderboard.nextRotation
+	leaderboard.mutex.RUnlock()
+
+	if !nextRotation.IsZero() && !time.Now().Before(nextRotation) {
+		leaderboard.RotatePeriods()
+	}
+}
+
+// rotate archives the categories whose period ended at now and returns the events to fire once the lock is released.
+// The caller must hold the write lock
+func (leaderboard *Leaderboard) rotate(now time.Time) []func() {
+	events := make([]func(), 0)
+	handler := leaderboard.PeriodResetHandler
+	rotationHooks := leaderboard.rotationHooks
+
+	leaderboard.nextRotation = time.Time{}
+
+	for category, current := range leaderboard.categories {
+		if current.endsAt.IsZero() {
+			continue
+		}
+
+		if now.Before(current.endsAt) {
+			if leaderboard.nextRotation.IsZero() || current.endsAt.Before(leaderboard.nextRotation) {
+				leaderboard.nextRotation = current.endsAt
+			}
+
+			continue
+		}
+
+		archives := append([]*rankingCategory{current}, leaderboard.archives[category]...)
+		if leaderboard.MaxArchivedPeriods != 0 && len(archives) > leaderboard.MaxArchivedPeriods {
+			archives = archives[:leaderboard.MaxArchivedPeriods]
+		}
+
+		leaderboard.archives[category] = archives
+		leaderboard.periodCounts[category]++
+
+		next := newRankingCategory(current.orderBy)
+		leaderboard.schedulePeriod(category, next, now)
+		leaderboard.categories[category] = next
+
+		for _, hook := range rotationHooks {
+			hook := hook
+			category := category
+
+			events = append(events, func() {
+				hook(category)
+			})
+		}
+
+		if handler != nil {
+			category := category
+			period := rankingCategoryPeriod(category, current)
+
+			events = append(events, func() {
+				handler(category, period)
+			})
+		}
+	}
+
+	return events
+}
+
+// onRotate adds hook to the functions called with every category starting a new period, such as RankingCache.Invalidate
+func (leaderboard *Leaderboard) onRotate(hook func(category uint32)) {
+	leaderboard.mutex.Lock()
+	defer leaderboard.mutex.Unlock()
+
+	leaderboard.rotationHooks = append(leaderboard.rotationHooks, hook)
+}
+
+// rankingCategoryPeriod describes the period of rankingCategory
+func rankingCategoryPeriod(category uint32, rankingCategory *rankingCategory) *RankingPeriod {
+	return &RankingPeriod{
+		Category: category,
+		Start:    rankingCategory.since,
+		End:      rankingCategory.endsAt,
+		Count:    rankingCategory.all.length(),
+	}
+}
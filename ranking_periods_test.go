@@ -0,0 +1,143 @@
+package nexproto
+
+import (
+	"testing"
+	"time"
+)
+
+// rotateTestPeriods rotates the periods of leaderboard as if the time was now
+func rotateTestPeriods(leaderboard *Leaderboard, now time.Time) {
+	leaderboard.mutex.Lock()
+	events := leaderboard.rotate(now)
+	leaderboard.mutex.Unlock()
+
+	fireEvents(events)
+}
+
+func TestRankingResetScheduleBounds(t *testing.T) {
+	// Wednesday 13 March 2024
+	now := time.Date(2024, time.March, 13, 15, 0, 0, 0, time.UTC)
+	anchor := time.Date(2024, time.March, 20, 6, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule RankingResetSchedule
+		start    time.Time
+		end      time.Time
+	}{
+		{
+			"daily",
+			RankingResetSchedule{Period: RankingResetDaily},
+			time.Date(2024, time.March, 13, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, time.March, 14, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"weekly",
+			RankingResetSchedule{Period: RankingResetWeekly},
+			time.Date(2024, time.March, 11, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, time.March, 18, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"monthly",
+			RankingResetSchedule{Period: RankingResetMonthly},
+			time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"monthly from a later anchor",
+			RankingResetSchedule{Period: RankingResetMonthly, Anchor: anchor},
+			time.Date(2024, time.February, 20, 6, 0, 0, 0, time.UTC),
+			anchor,
+		},
+		{
+			"custom from a later anchor",
+			RankingResetSchedule{Period: RankingResetCustom, Interval: 48 * time.Hour, Anchor: anchor},
+			time.Date(2024, time.March, 12, 6, 0, 0, 0, time.UTC),
+			time.Date(2024, time.March, 14, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			"never",
+			RankingResetSchedule{Period: RankingResetNever},
+			time.Time{},
+			time.Time{},
+		},
+	}
+
+	for _, test := range tests {
+		start, end := test.schedule.bounds(now)
+		if !start.Equal(test.start) || !end.Equal(test.end) {
+			t.Errorf("%s: period is %v to %v, want %v to %v", test.name, start, end, test.start, test.end)
+		}
+	}
+}
+
+func TestLeaderboardPeriodRotation(t *testing.T) {
+	leaderboard := NewLeaderboard()
+	leaderboard.SetCategoryResetSchedule(1, RankingResetSchedule{Period: RankingResetDaily})
+
+	var resets []*RankingPeriod
+
+	leaderboard.OnPeriodReset(func(category uint32, period *RankingPeriod) {
+		resets = append(resets, period)
+	})
+
+	uploadTestScore(t, leaderboard, 1, 10)
+	uploadTestScore(t, leaderboard, 2, 20)
+
+	rotateTestPeriods(leaderboard, time.Now().Add(24*time.Hour))
+
+	if len(resets) != 1 || resets[0].Category != 1 || resets[0].Count != 2 || !resets[0].End.Equal(resets[0].Start.Add(24*time.Hour)) {
+		t.Fatalf("period reset handler got %v", resets)
+	}
+
+	orderParam := NewRankingOrderParam()
+	orderParam.Length = 10
+
+	current, err := leaderboard.Ranking(RankingModeGlobal, 1, orderParam, 0, 0)
+	if err != nil || len(current.RankData) != 0 {
+		t.Fatalf("current period holds %v, %v", current, err)
+	}
+
+	if periods := leaderboard.Periods(1); len(periods) != 1 || leaderboard.PeriodCount(1) != 1 {
+		t.Fatalf("%d periods archived, count %d", len(periods), leaderboard.PeriodCount(1))
+	}
+
+	archived, err := leaderboard.PeriodRanking(1, 1, RankingModeGlobal, orderParam, 0, 0)
+	if err != nil || len(archived.RankData) != 2 || archived.RankData[0].Score != 20 {
+		t.Fatalf("archived period holds %v, %v", archived, err)
+	}
+
+	if _, err := leaderboard.PeriodRanking(1, 2, RankingModeGlobal, orderParam, 0, 0); err != ErrRankingPeriodNotFound {
+		t.Fatalf("ranking of a missing period returned %v", err)
+	}
+
+	stats, err := leaderboard.PeriodStats(1, 1, orderParam, RankingStatsFlagTotal)
+	if err != nil || stats.StatsList[0] != 30 {
+		t.Fatalf("archived period stats are %v, %v", stats, err)
+	}
+}
+
+func TestLeaderboardMaxArchivedPeriods(t *testing.T) {
+	leaderboard := NewLeaderboard()
+	leaderboard.MaxArchivedPeriods = 2
+	leaderboard.SetCategoryResetSchedule(1, RankingResetSchedule{Period: RankingResetDaily})
+
+	now := time.Now()
+
+	for day := 1; day <= 3; day++ {
+		uploadTestScore(t, leaderboard, uint32(day), uint32(day))
+		rotateTestPeriods(leaderboard, now.Add(time.Duration(day)*24*time.Hour))
+	}
+
+	if periods := leaderboard.Periods(1); len(periods) != 2 || leaderboard.PeriodCount(1) != 3 {
+		t.Fatalf("%d periods kept out of %d", len(periods), leaderboard.PeriodCount(1))
+	}
+
+	orderParam := NewRankingOrderParam()
+	orderParam.Length = 10
+
+	latest, err := leaderboard.PeriodRanking(1, 1, RankingModeGlobal, orderParam, 0, 0)
+	if err != nil || len(latest.RankData) != 1 || latest.RankData[0].PrincipalID != 3 {
+		t.Fatalf("latest archived period holds %v, %v", latest, err)
+	}
+}
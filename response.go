@@ -0,0 +1,117 @@
+package nexproto
+
+import (
+	"sync"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+const (
+	// secureServerVPort is the PRUDP source used for clients which have not sent a request yet (secure server, stream type 3 port 1)
+	secureServerVPort = 0x31
+
+	// secureClientVPort is the PRUDP destination used for clients which have not sent a request yet (client, stream type 3 port 15)
+	secureClientVPort = 0x3F
+)
+
+// packetRoute is the PRUDP version and ports of the last request of a client, mirrored by the packets sent back to it
+type packetRoute struct {
+	version     uint8
+	source      uint8
+	destination uint8
+}
+
+var (
+	packetRoutesMutex  sync.RWMutex
+	packetRoutes       = make(map[*nex.Client]packetRoute)
+	packetRouteServers = make(map[*nex.Server]bool)
+)
+
+// trackPacketRoutes forgets the route of the clients leaving server. It only registers its handlers once per server
+func trackPacketRoutes(server *nex.Server) {
+	packetRoutesMutex.Lock()
+	defer packetRoutesMutex.Unlock()
+
+	if packetRouteServers[server] {
+		return
+	}
+
+	packetRouteServers[server] = true
+
+	forgetPacketRoute := func(packet nex.PacketInterface) {
+		packetRoutesMutex.Lock()
+		defer packetRoutesMutex.Unlock()
+
+		delete(packetRoutes, packet.Sender())
+	}
+
+	server.On("Disconnect", forgetPacketRoute)
+	server.On("Kick", forgetPacketRoute)
+}
+
+// rememberPacketRoute records the version and ports of a request, so the responses and pushes sent to its sender
+// outside of the request packet mirror them as respondNotImplemented does
+func rememberPacketRoute(packet nex.PacketInterface) {
+	route := packetRoute{
+		version:     packet.Version(),
+		source:      packet.Destination(),
+		destination: packet.Source(),
+	}
+
+	packetRoutesMutex.Lock()
+	defer packetRoutesMutex.Unlock()
+
+	packetRoutes[packet.Sender()] = route
+}
+
+// clientPacketRoute returns the route of the packets sent to client, falling back to the secure server ports
+// for clients which have not sent a request yet
+func clientPacketRoute(client *nex.Client) packetRoute {
+	packetRoutesMutex.RLock()
+	defer packetRoutesMutex.RUnlock()
+
+	if route, ok := packetRoutes[client]; ok {
+		return route
+	}
+
+	return packetRoute{
+		source:      secureServerVPort,
+		destination: secureClientVPort,
+	}
+}
+
+// respondSuccess sends a successful RMC response containing body to client
+func respondSuccess(client *nex.Client, protocolID uint8, methodID uint32, callID uint32, body []byte) {
+	rmcResponse := nex.NewRMCResponse(protocolID, callID)
+	rmcResponse.SetSuccess(methodID, body)
+
+	sendRMCPayload(client, rmcResponse.Bytes())
+}
+
+// respondError sends an RMC error response with the given Quazal result code to client
+func respondError(client *nex.Client, protocolID uint8, callID uint32, resultCode uint32) {
+	rmcResponse := nex.NewRMCResponse(protocolID, callID)
+	rmcResponse.SetError(resultCode)
+
+	sendRMCPayload(client, rmcResponse.Bytes())
+}
+
+// sendRMCPayload wraps an encoded RMC message in a reliable data packet and sends it to client over the route of its last request
+func sendRMCPayload(client *nex.Client, payload []byte) {
+	route := clientPacketRoute(client)
+
+	var responsePacket nex.PacketInterface
+
+	responsePacket, _ = nex.NewPacketV0(client, nil)
+
+	responsePacket.SetVersion(route.version)
+	responsePacket.SetSource(route.source)
+	responsePacket.SetDestination(route.destination)
+	responsePacket.SetType(nex.DataPacket)
+	responsePacket.SetPayload(payload)
+
+	responsePacket.AddFlag(nex.FlagNeedsAck)
+	responsePacket.AddFlag(nex.FlagReliable)
+
+	client.Server().Send(responsePacket)
+}
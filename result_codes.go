@@ -0,0 +1,40 @@
+package nexproto
+
+// Quazal result codes sent in RMC error responses
+const (
+	// ResultCoreNotImplemented is returned for methods the server does not implement
+	ResultCoreNotImplemented = 0x80010002
+
+	// ResultCoreAccessDenied is returned when the caller may not perform the operation
+	ResultCoreAccessDenied = 0x80010006
+
+	// ResultCoreInvalidArgument is returned when the request parameters could not be parsed or are out of range
+	ResultCoreInvalidArgument = 0x8001000A
+
+	// ResultRendezVousInvalidGID is returned when a gathering ID does not exist
+	ResultRendezVousInvalidGID = 0x8003006D
+
+	// ResultRendezVousSessionFull is returned when a gathering has reached its maximum participants
+	ResultRendezVousSessionFull = 0x800300C8
+
+	// ResultRendezVousInvalidOperation is returned when the operation is not valid in the current state
+	ResultRendezVousInvalidOperation = 0x800300D3
+
+	// ResultRendezVousNotParticipatedGathering is returned when the caller is not a participant of the gathering
+	ResultRendezVousNotParticipatedGathering = 0x800300D4
+
+	// ResultRendezVousAlreadyParticipatedGathering is returned when the caller already participates in the gathering
+	ResultRendezVousAlreadyParticipatedGathering = 0x800300D8
+
+	// ResultRendezVousPermissionDenied is returned when the caller does not own the gathering
+	ResultRendezVousPermissionDenied = 0x800300D9
+
+	// ResultRankingInvalidArgument is returned when a ranking parameter is out of range
+	ResultRankingInvalidArgument = 0x80670002
+
+	// ResultRankingNotFound is returned when the requested score or ranking does not exist
+	ResultRankingNotFound = 0x80670005
+
+	// ResultRankingInvalidDataSize is returned when uploaded ranking data exceeds its size limit
+	ResultRankingInvalidDataSize = 0x80670007
+)
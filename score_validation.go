@@ -0,0 +1,345 @@
+package nexproto
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	nex "github.com/jnackmclain/nex-go"
+)
+
+var (
+	// ErrScoreRateLimited is returned when a PID uploads scores faster than the rate limit allows
+	ErrScoreRateLimited = errors.New("score upload rate limited")
+
+	// ErrScoreOutOfBounds is the quarantine reason of a score outside the bounds of its category
+	ErrScoreOutOfBounds = errors.New("score out of category bounds")
+
+	// ErrQuarantinedScoreNotFound is returned when a quarantined score ID does not exist
+	ErrQuarantinedScoreNotFound = errors.New("quarantined score not found")
+)
+
+// ScoreCheck inspects a score uploaded by pid before it is ranked. Returning an error quarantines the score,
+// the error being kept as the reason
+type ScoreCheck func(pid uint32, uniqueID uint64, scoreData *RankingScoreData) error
+
+// QuarantinedScore is an uploaded score held for review instead of being ranked
+type QuarantinedScore struct {
+	ID          uint32
+	PID         uint32
+	UniqueID    uint64
+	ScoreData   *RankingScoreData
+	Reason      string
+	SubmittedAt time.Time
+}
+
+// rankingCategoryBounds holds the lowest and highest score accepted in a category
+type rankingCategoryBounds struct {
+	min uint32
+	max uint32
+}
+
+// scoreRateWindow counts the uploads of a PID during the current rate limit window
+type scoreRateWindow struct {
+	start time.Time
+	count int
+}
+
+// ScoreValidator checks scores before they reach a Leaderboard. Uploads over the rate limit are refused.
+// Scores outside the bounds of their category or failing a ScoreCheck are quarantined for review,
+// the client being answered as if the score had been ranked
+type ScoreValidator struct {
+	mutex                   sync.Mutex
+	leaderboard             *Leaderboard
+	bounds                  map[uint32]rankingCategoryBounds
+	checks                  []ScoreCheck
+	windows                 map[uint32]*scoreRateWindow
+	quarantine              []*QuarantinedScore
+	lastQuarantineID        uint32
+	stopReaper              chan struct{}
+	clock                   func() time.Time // current time of rate limit windows, replaced by tests
+	RateLimit               int              // uploads accepted per PID during RateWindow. 0 for no limit
+	RateWindow              time.Duration    // length of the rate limit window
+	MaxQuarantined          int              // quarantined scores kept, the oldest being dropped first. 0 for no limit
+	ScoreQuarantinedHandler func(score *QuarantinedScore)
+}
+
+// SetCategoryBounds quarantines the scores of category lower than min or higher than max
+func (validator *ScoreValidator) SetCategoryBounds(category uint32, min uint32, max uint32) {
+	validator.mutex.Lock()
+	defer validator.mutex.Unlock()
+
+	validator.bounds[category] = rankingCategoryBounds{min, max}
+}
+
+// AddCheck appends check to the checks run on every uploaded score, such as a song-specific maximum score
+func (validator *ScoreValidator) AddCheck(check ScoreCheck) {
+	validator.mutex.Lock()
+	defer validator.mutex.Unlock()
+
+	validator.checks = append(validator.checks, check)
+}
+
+// OnScoreQuarantined sets the handler called when a score is quarantined
+func (validator *ScoreValidator) OnScoreQuarantined(handler func(score *QuarantinedScore)) {
+	validator.ScoreQuarantinedHandler = handler
+}
+
+// Submit validates a score uploaded by pid under uniqueID and uploads it to the leaderboard if it passes.
+// It returns whether the score was quarantined instead, or ErrScoreRateLimited if the upload was refused
+func (validator *ScoreValidator) Submit(pid uint32, uniqueID uint64, scoreData *RankingScoreData) (bool, error) {
+	quarantined, err := validator.SubmitAll(pid, uniqueID, []*RankingScoreData{scoreData})
+
+	return quarantined == 1, err
+}
+
+// SubmitAll validates the scores of scoreDataList uploaded together by pid under uniqueID, counting them as a single
+// upload against the rate limit. Every score is checked before any is uploaded, the ones failing being quarantined.
+// It returns the number of scores quarantined, or ErrScoreRateLimited if the upload was refused
+func (validator *ScoreValidator) SubmitAll(pid uint32, uniqueID uint64, scoreDataList []*RankingScoreData) (int, error) {
+	now := validator.clock()
+
+	validator.mutex.Lock()
+
+	if !validator.allow(pid, now) {
+		validator.mutex.Unlock()
+		return 0, ErrScoreRateLimited
+	}
+
+	bounds := make(map[uint32]rankingCategoryBounds)
+
+	for _, scoreData := range scoreDataList {
+		if categoryBounds, ok := validator.bounds[scoreData.Category]; ok {
+			bounds[scoreData.Category] = categoryBounds
+		}
+	}
+
+	checks := append([]ScoreCheck(nil), validator.checks...)
+
+	validator.mutex.Unlock()
+
+	// Checks may call back into the validator, so they run without the lock
+	reasons := make([]error, len(scoreDataList))
+
+	for i, scoreData := range scoreDataList {
+		reasons[i] = checkScore(pid, uniqueID, scoreData, bounds, checks)
+	}
+
+	quarantinedCount := 0
+
+	for i, scoreData := range scoreDataList {
+		if reasons[i] != nil {
+			quarantined := validator.hold(pid, uniqueID, scoreData, reasons[i], now)
+			quarantinedCount++
+
+			if validator.ScoreQuarantinedHandler != nil {
+				validator.ScoreQuarantinedHandler(quarantined)
+			}
+
+			continue
+		}
+
+		err := validator.leaderboard.UploadScore(pid, uniqueID, scoreData)
+		if err != nil {
+			return quarantinedCount, err
+		}
+	}
+
+	return quarantinedCount, nil
+}
+
+// Quarantined returns the quarantined scores, oldest first
+func (validator *ScoreValidator) Quarantined() []*QuarantinedScore {
+	validator.mutex.Lock()
+	defer validator.mutex.Unlock()
+
+	return append([]*QuarantinedScore(nil), validator.quarantine...)
+}
+
+// Approve releases the quarantined score with the given ID and uploads it to the leaderboard
+func (validator *ScoreValidator) Approve(id uint32) error {
+	score, err := validator.release(id)
+	if err != nil {
+		return err
+	}
+
+	return validator.leaderboard.UploadScore(score.PID, score.UniqueID, score.ScoreData)
+}
+
+// Reject drops the quarantined score with the given ID
+func (validator *ScoreValidator) Reject(id uint32) error {
+	_, err := validator.release(id)
+
+	return err
+}
+
+// Reap forgets the rate limit windows which have ended
+func (validator *ScoreValidator) Reap() {
+	now := validator.clock()
+
+	validator.mutex.Lock()
+	defer validator.mutex.Unlock()
+
+	for pid, window := range validator.windows {
+		if now.Sub(window.start) >= validator.RateWindow {
+			delete(validator.windows, pid)
+		}
+	}
+}
+
+// StartReaper calls Reap every interval in the background until StopReaper is called
+func (validator *ScoreValidator) StartReaper(interval time.Duration) {
+	validator.StopReaper()
+
+	stop := make(chan struct{})
+
+	validator.mutex.Lock()
+	validator.stopReaper = stop
+	validator.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				validator.Reap()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopReaper stops the background reaper started by StartReaper
+func (validator *ScoreValidator) StopReaper() {
+	validator.mutex.Lock()
+	defer validator.mutex.Unlock()
+
+	if validator.stopReaper != nil {
+		close(validator.stopReaper)
+		validator.stopReaper = nil
+	}
+}
+
+// HandleUploadScore is the RankingProtocol::UploadScore handler validating scores before they are ranked
+func (validator *ScoreValidator) HandleUploadScore(err error, client *nex.Client, callID uint32, scoreData *RankingScoreData, uniqueID uint64) {
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, ResultRankingInvalidArgument)
+		return
+	}
+
+	_, err = validator.Submit(client.PID(), uniqueID, scoreData)
+	if err != nil {
+		respondError(client, RankingProtocolID, callID, scoreValidationResultCode(err))
+		return
+	}
+
+	respondSuccess(client, RankingProtocolID, RankingMethodUploadScore, callID, make([]byte, 0))
+}
+
+// allow counts an upload by pid and returns whether it is within the rate limit. The caller must hold the lock
+func (validator *ScoreValidator) allow(pid uint32, now time.Time) bool {
+	if validator.RateLimit == 0 {
+		return true
+	}
+
+	window, ok := validator.windows[pid]
+	if !ok || now.Sub(window.start) >= validator.RateWindow {
+		window = &scoreRateWindow{start: now}
+		validator.windows[pid] = window
+	}
+
+	if window.count >= validator.RateLimit {
+		return false
+	}
+
+	window.count++
+
+	return true
+}
+
+// hold quarantines a score which failed a check for reason and returns it
+func (validator *ScoreValidator) hold(pid uint32, uniqueID uint64, scoreData *RankingScoreData, reason error, now time.Time) *QuarantinedScore {
+	validator.mutex.Lock()
+	defer validator.mutex.Unlock()
+
+	validator.lastQuarantineID++
+
+	quarantined := &QuarantinedScore{
+		ID:          validator.lastQuarantineID,
+		PID:         pid,
+		UniqueID:    uniqueID,
+		ScoreData:   scoreData.Copy(),
+		Reason:      reason.Error(),
+		SubmittedAt: now,
+	}
+
+	validator.quarantine = append(validator.quarantine, quarantined)
+
+	if validator.MaxQuarantined != 0 && len(validator.quarantine) > validator.MaxQuarantined {
+		validator.quarantine = validator.quarantine[len(validator.quarantine)-validator.MaxQuarantined:]
+	}
+
+	return quarantined
+}
+
+// checkScore returns the reason to quarantine a score, or nil if it passes the bounds of its category and checks
+func checkScore(pid uint32, uniqueID uint64, scoreData *RankingScoreData, bounds map[uint32]rankingCategoryBounds, checks []ScoreCheck) error {
+	if categoryBounds, ok := bounds[scoreData.Category]; ok {
+		if scoreData.Score < categoryBounds.min || scoreData.Score > categoryBounds.max {
+			return ErrScoreOutOfBounds
+		}
+	}
+
+	for _, check := range checks {
+		err := check(pid, uniqueID, scoreData)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// release removes the quarantined score with the given ID and returns it
+func (validator *ScoreValidator) release(id uint32) (*QuarantinedScore, error) {
+	validator.mutex.Lock()
+	defer validator.mutex.Unlock()
+
+	for i, score := range validator.quarantine {
+		if score.ID == id {
+			validator.quarantine = append(validator.quarantine[:i], validator.quarantine[i+1:]...)
+			return score, nil
+		}
+	}
+
+	return nil, ErrQuarantinedScoreNotFound
+}
+
+// scoreValidationResultCode returns the result code sent for a ScoreValidator error
+func scoreValidationResultCode(err error) uint32 {
+	switch err {
+	case ErrScoreRateLimited:
+		return ResultCoreAccessDenied
+	default:
+		return rankingResultCode(err)
+	}
+}
+
+// NewScoreValidator returns a new ScoreValidator uploading to leaderboard, accepting 10 uploads per PID per minute
+// and keeping up to 1000 quarantined scores
+func NewScoreValidator(leaderboard *Leaderboard) *ScoreValidator {
+	return &ScoreValidator{
+		leaderboard:    leaderboard,
+		bounds:         make(map[uint32]rankingCategoryBounds),
+		checks:         make([]ScoreCheck, 0),
+		windows:        make(map[uint32]*scoreRateWindow),
+		quarantine:     make([]*QuarantinedScore, 0),
+		RateLimit:      10,
+		RateWindow:     time.Minute,
+		MaxQuarantined: 1000,
+		clock:          time.Now,
+	}
+}
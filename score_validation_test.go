@@ -0,0 +1,141 @@
+package nexproto
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// errTestSongMaximum is the reason a score is quarantined by the check of newTestScoreValidator
+var errTestSongMaximum = errors.New("score over the song maximum")
+
+// newTestScoreValidator returns a ScoreValidator allowing 3 uploads per minute, bounding category 1 to 0-100 and
+// quarantining the scores of category 2 over 10. Its clock is set to now
+func newTestScoreValidator(leaderboard *Leaderboard, now *time.Time) *ScoreValidator {
+	validator := NewScoreValidator(leaderboard)
+	validator.RateLimit = 3
+	validator.clock = func() time.Time {
+		return *now
+	}
+
+	validator.SetCategoryBounds(1, 0, 100)
+	validator.AddCheck(func(pid uint32, uniqueID uint64, scoreData *RankingScoreData) error {
+		if scoreData.Category == 2 && scoreData.Score > 10 {
+			return errTestSongMaximum
+		}
+
+		return nil
+	})
+
+	return validator
+}
+
+func TestScoreValidatorQuarantine(t *testing.T) {
+	leaderboard := NewLeaderboard()
+	now := time.Now()
+	validator := newTestScoreValidator(leaderboard, &now)
+
+	var quarantinedScores []*QuarantinedScore
+
+	validator.OnScoreQuarantined(func(score *QuarantinedScore) {
+		quarantinedScores = append(quarantinedScores, score)
+	})
+
+	tests := []struct {
+		category    uint32
+		score       uint32
+		quarantined bool
+	}{
+		{1, 50, false},
+		{1, 500, true},
+		{2, 11, true},
+	}
+
+	for _, test := range tests {
+		quarantined, err := validator.Submit(1, 0, &RankingScoreData{Category: test.category, Score: test.score})
+		if err != nil || quarantined != test.quarantined {
+			t.Fatalf("score %d in category %d quarantined %t, %v", test.score, test.category, quarantined, err)
+		}
+	}
+
+	held := validator.Quarantined()
+	if len(held) != 2 || len(quarantinedScores) != 2 || held[0].Reason != ErrScoreOutOfBounds.Error() || held[1].Reason != errTestSongMaximum.Error() {
+		t.Fatalf("quarantined %v", held)
+	}
+
+	if _, err := leaderboard.Score(1, 2, 0); err != ErrRankingScoreNotFound {
+		t.Fatalf("quarantined score was ranked, %v", err)
+	}
+
+	if err := validator.Approve(held[1].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if scoreData, err := leaderboard.Score(1, 2, 0); err != nil || scoreData.Score != 11 {
+		t.Fatalf("approved score is %v, %v", scoreData, err)
+	}
+
+	if err := validator.Reject(held[0].ID); err != nil || len(validator.Quarantined()) != 0 {
+		t.Fatalf("Reject returned %v, %d scores left", err, len(validator.Quarantined()))
+	}
+
+	if scoreData, _ := leaderboard.Score(1, 1, 0); scoreData.Score != 50 {
+		t.Fatalf("rejected score replaced the ranked one: %d", scoreData.Score)
+	}
+
+	if err := validator.Approve(held[0].ID); err != ErrQuarantinedScoreNotFound {
+		t.Fatalf("approving a rejected score returned %v", err)
+	}
+}
+
+func TestScoreValidatorRateLimit(t *testing.T) {
+	now := time.Now()
+	validator := newTestScoreValidator(NewLeaderboard(), &now)
+
+	for i := 0; i < 3; i++ {
+		if _, err := validator.Submit(1, 0, &RankingScoreData{Category: 1, Score: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := validator.Submit(1, 0, &RankingScoreData{Category: 1, Score: 1}); err != ErrScoreRateLimited {
+		t.Fatalf("upload over the rate limit returned %v", err)
+	}
+
+	if _, err := validator.Submit(2, 0, &RankingScoreData{Category: 1, Score: 1}); err != nil {
+		t.Fatalf("rate limit of another PID applied: %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	validator.Reap()
+
+	if _, err := validator.Submit(1, 0, &RankingScoreData{Category: 1, Score: 1}); err != nil {
+		t.Fatalf("upload in a new rate limit window returned %v", err)
+	}
+}
+
+func TestScoreValidatorCheckCallsValidator(t *testing.T) {
+	validator := NewScoreValidator(NewLeaderboard())
+
+	// A check reading the quarantine used to deadlock, being run with the lock held
+	validator.AddCheck(func(pid uint32, uniqueID uint64, scoreData *RankingScoreData) error {
+		if len(validator.Quarantined()) != 0 {
+			return errTestSongMaximum
+		}
+
+		return nil
+	})
+
+	done := make(chan struct{})
+
+	go func() {
+		validator.Submit(1, 0, &RankingScoreData{Category: 1, Score: 1})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Submit deadlocked on a check calling the validator")
+	}
+}
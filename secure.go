@@ -54,10 +54,14 @@ type SecureProtocol struct {
 func (secureProtocol *SecureProtocol) Setup() {
 	nexServer := secureProtocol.server
 
+	trackPacketRoutes(nexServer)
+
 	nexServer.On("Data", func(packet nex.PacketInterface) {
 		request := packet.RMCRequest()
 
 		if SecureProtocolID == request.ProtocolID() {
+			rememberPacketRoute(packet)
+
 			switch request.MethodID() {
 			case SecureMethodRegister:
 				go secureProtocol.handleRegister(packet)
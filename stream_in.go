@@ -1,6 +1,8 @@
 package nexproto
 
 import (
+	"errors"
+
 	nex "github.com/jnackmclain/nex-go"
 )
 
@@ -47,6 +49,93 @@ func (stream *StreamIn) ReadListStationURL() ([]*nex.StationURL, error) {
 	return stationUrls, nil
 }
 
+// ReadListMessageRecipient reads a list of MessageRecipient structures
+func (stream *StreamIn) ReadListMessageRecipient() ([]*MessageRecipient, error) {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 4 {
+		return nil, errors.New("[StreamIn::ReadListMessageRecipient] Data missing list length")
+	}
+
+	length := stream.ReadUInt32LE()
+	messageRecipients := make([]*MessageRecipient, 0)
+
+	for i := 0; i < int(length); i++ {
+		messageRecipientStructureInterface, err := stream.ReadStructure(NewMessageRecipient())
+		if err != nil {
+			return nil, err
+		}
+
+		messageRecipient := messageRecipientStructureInterface.(*MessageRecipient)
+		messageRecipients = append(messageRecipients, messageRecipient)
+	}
+
+	return messageRecipients, nil
+}
+
+// ReadListRankingOrderParam reads a list of RankingOrderParam structures
+func (stream *StreamIn) ReadListRankingOrderParam() ([]*RankingOrderParam, error) {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 4 {
+		return nil, errors.New("[StreamIn::ReadListRankingOrderParam] Data missing list length")
+	}
+
+	length := stream.ReadUInt32LE()
+	orderParams := make([]*RankingOrderParam, 0)
+
+	for i := 0; i < int(length); i++ {
+		orderParamStructureInterface, err := stream.ReadStructure(NewRankingOrderParam())
+		if err != nil {
+			return nil, err
+		}
+
+		orderParam := orderParamStructureInterface.(*RankingOrderParam)
+		orderParams = append(orderParams, orderParam)
+	}
+
+	return orderParams, nil
+}
+
+// ReadListRanking2ScoreData reads a list of Ranking2ScoreData structures
+func (stream *StreamIn) ReadListRanking2ScoreData() ([]*Ranking2ScoreData, error) {
+	if len(stream.Bytes()[stream.ByteOffset():]) < 4 {
+		return nil, errors.New("[StreamIn::ReadListRanking2ScoreData] Data missing list length")
+	}
+
+	length := stream.ReadUInt32LE()
+	scoreDataList := make([]*Ranking2ScoreData, 0)
+
+	for i := 0; i < int(length); i++ {
+		scoreDataStructureInterface, err := stream.ReadStructure(NewRanking2ScoreData())
+		if err != nil {
+			return nil, err
+		}
+
+		scoreData := scoreDataStructureInterface.(*Ranking2ScoreData)
+		scoreDataList = append(scoreDataList, scoreData)
+	}
+
+	return scoreDataList, nil
+}
+
+// ReadDataHolder reads a data holder, returning the class name and the encoded structure it wraps
+func (stream *StreamIn) ReadDataHolder() (string, []byte, error) {
+	className, err := stream.Read4ByteString()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(stream.Bytes()[stream.ByteOffset():]) < 4 {
+		return "", nil, errors.New("[StreamIn::ReadDataHolder] Data missing holder length")
+	}
+
+	stream.ReadUInt32LE()
+
+	content, err := stream.ReadBuffer()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return className, content, nil
+}
+
 // NewStreamIn returns a new nexproto output stream
 func NewStreamIn(data []byte, server *nex.Server) *StreamIn {
 	return &StreamIn{
@@ -0,0 +1,79 @@
+package nexproto
+
+import (
+	nex "github.com/jnackmclain/nex-go"
+)
+
+// StreamOut is an abstraction of StreamOut from github.com/jnackmclain/nex-go
+// Adds protocol-specific Structure list support
+type StreamOut struct {
+	*nex.StreamOut
+	server *nex.Server
+}
+
+// Write4ByteString writes a string prefixed with a 4 byte length, as read by Read4ByteString
+func (stream *StreamOut) Write4ByteString(str string) {
+	write4ByteString(stream.StreamOut, str)
+}
+
+// WriteBool writes a bool as a single byte
+func (stream *StreamOut) WriteBool(value bool) {
+	writeBool(stream.StreamOut, value)
+}
+
+// WriteDataHolder writes a Structure wrapped in a data holder of className
+func (stream *StreamOut) WriteDataHolder(className string, structure nex.StructureInterface) {
+	content := structure.Bytes(nex.NewStreamOut(stream.server))
+
+	write4ByteString(stream.StreamOut, className)
+	stream.WriteUInt32LE(uint32(len(content) + 4))
+	stream.WriteBuffer(content)
+}
+
+// WriteListGathering writes a list of Gathering structures, each wrapped in a data holder of the class it was
+// registered as, or of className if it was not registered with a GatheringManager
+func (stream *StreamOut) WriteListGathering(className string, gatherings []*Gathering) {
+	stream.WriteUInt32LE(uint32(len(gatherings)))
+
+	for _, gathering := range gatherings {
+		stream.WriteDataHolder(gathering.dataHolderClassName(className), gathering)
+	}
+}
+
+// WriteListInvitation writes a list of Invitation structures
+func (stream *StreamOut) WriteListInvitation(invitations []*Invitation) {
+	stream.WriteUInt32LE(uint32(len(invitations)))
+
+	for _, invitation := range invitations {
+		stream.WriteStructure(invitation)
+	}
+}
+
+// NewStreamOut returns a new nexproto output stream
+func NewStreamOut(server *nex.Server) *StreamOut {
+	return &StreamOut{
+		StreamOut: nex.NewStreamOut(server),
+		server:    server,
+	}
+}
+
+// write4ByteString writes a null terminated string prefixed with its 4 byte length
+func write4ByteString(stream *nex.StreamOut, str string) {
+	stream.WriteBuffer(append([]byte(str), 0x00))
+}
+
+// writeBool writes a bool as a single byte
+func writeBool(stream *nex.StreamOut, value bool) {
+	if value {
+		stream.WriteUInt8(1)
+	} else {
+		stream.WriteUInt8(0)
+	}
+}
+
+// writeBytes writes raw bytes with no length prefix
+func writeBytes(stream *nex.StreamOut, data []byte) {
+	for _, b := range data {
+		stream.WriteUInt8(b)
+	}
+}